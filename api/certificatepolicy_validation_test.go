@@ -0,0 +1,422 @@
+package api_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/schema/cel"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/schema/defaulting"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	celconfig "k8s.io/apiserver/pkg/apis/cel"
+	"sigs.k8s.io/yaml"
+)
+
+const crdPath = "../helm/envoy-extension-server/crds/generated/gateway.giantswarm.io_certificatepolicies.yaml"
+
+// crdSchema holds the compiled schema of the generated CertificatePolicy CRD.
+type crdSchema struct {
+	structural *structuralschema.Structural
+	validator  validation.SchemaValidator
+	cel        *cel.Validator
+}
+
+func loadCRDSchema(t *testing.T, version string) *crdSchema {
+	t.Helper()
+
+	data, err := os.ReadFile(crdPath)
+	if err != nil {
+		t.Fatalf("failed to read CRD: %v", err)
+	}
+	var crd apiextensionsv1.CustomResourceDefinition
+	if err := yaml.Unmarshal(data, &crd); err != nil {
+		t.Fatalf("failed to decode CRD: %v", err)
+	}
+
+	var versionSchema *apiextensionsv1.JSONSchemaProps
+	for _, v := range crd.Spec.Versions {
+		if v.Name == version {
+			versionSchema = v.Schema.OpenAPIV3Schema
+		}
+	}
+	if versionSchema == nil {
+		t.Fatalf("CRD has no %s schema", version)
+	}
+
+	var internal apiextensions.JSONSchemaProps
+	if err := apiextensionsv1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(versionSchema, &internal, nil); err != nil {
+		t.Fatalf("failed to convert schema: %v", err)
+	}
+	structural, err := structuralschema.NewStructural(&internal)
+	if err != nil {
+		t.Fatalf("failed to build structural schema: %v", err)
+	}
+	validator, _, err := validation.NewSchemaValidator(&internal)
+	if err != nil {
+		t.Fatalf("failed to build schema validator: %v", err)
+	}
+
+	return &crdSchema{
+		structural: structural,
+		validator:  validator,
+		cel:        cel.NewValidator(structural, true, celconfig.PerCallLimit),
+	}
+}
+
+// validate defaults obj in place and returns the OpenAPI and CEL errors.
+func (s *crdSchema) validate(t *testing.T, obj map[string]any) field.ErrorList {
+	t.Helper()
+
+	defaulting.Default(obj, s.structural)
+	errs := validation.ValidateCustomResource(nil, obj, s.validator)
+	celErrs, _ := s.cel.Validate(context.Background(), nil, s.structural, obj, nil, celconfig.RuntimeCELCostBudget)
+	return append(errs, celErrs...)
+}
+
+func newPolicyObject(version string, spec map[string]any) map[string]any {
+	return map[string]any{
+		"apiVersion": "gateway.giantswarm.io/" + version,
+		"kind":       "CertificatePolicy",
+		"metadata":   map[string]any{"name": "test-policy", "namespace": "default"},
+		"spec":       spec,
+	}
+}
+
+func gatewayRef(name string) map[string]any {
+	return map[string]any{"group": "gateway.networking.k8s.io", "kind": "Gateway", "name": name}
+}
+
+func TestCertificatePolicyV1alpha1CRDValidation(t *testing.T) {
+	schema := loadCRDSchema(t, "v1alpha1")
+
+	tests := []struct {
+		name    string
+		spec    map[string]any
+		wantErr string
+	}{
+		{
+			name: "valid policy",
+			spec: map[string]any{
+				"secretName": "tls-secret",
+				"targetRefs": []any{gatewayRef("gateway")},
+			},
+		},
+		{
+			name: "valid policy with section names",
+			spec: map[string]any{
+				"secretName": "tls-secret",
+				"targetRefs": []any{
+					gatewayRef("gateway"),
+					map[string]any{"group": "gateway.networking.k8s.io", "kind": "Gateway", "name": "gateway", "sectionName": "https"},
+				},
+			},
+		},
+		{
+			name:    "missing secret name",
+			spec:    map[string]any{"targetRefs": []any{gatewayRef("gateway")}},
+			wantErr: "spec.secretName: Required value",
+		},
+		{
+			name: "invalid secret name",
+			spec: map[string]any{
+				"secretName": "Not_A_Name",
+				"targetRefs": []any{gatewayRef("gateway")},
+			},
+			wantErr: "spec.secretName",
+		},
+		{
+			name: "invalid fallback secret name",
+			spec: map[string]any{
+				"secretName":         "tls-secret",
+				"fallbackSecretName": "-fallback",
+				"targetRefs":         []any{gatewayRef("gateway")},
+			},
+			wantErr: "spec.fallbackSecretName",
+		},
+		{
+			name:    "empty target refs",
+			spec:    map[string]any{"secretName": "tls-secret", "targetRefs": []any{}},
+			wantErr: "spec.targetRefs",
+		},
+		{
+			name: "target ref is not a gateway",
+			spec: map[string]any{
+				"secretName": "tls-secret",
+				"targetRefs": []any{map[string]any{"group": "gateway.networking.k8s.io", "kind": "HTTPRoute", "name": "route"}},
+			},
+			wantErr: "targetRefs must reference gateway.networking.k8s.io/Gateway resources",
+		},
+		{
+			name: "duplicate target refs",
+			spec: map[string]any{
+				"secretName": "tls-secret",
+				"targetRefs": []any{gatewayRef("gateway"), gatewayRef("gateway")},
+			},
+			wantErr: "targetRefs must be unique",
+		},
+		{
+			name: "cryptomb provider",
+			spec: map[string]any{
+				"secretName":         "tls-secret",
+				"targetRefs":         []any{gatewayRef("gateway")},
+				"privateKeyProvider": map[string]any{"providerName": "cryptomb", "cryptomb": map[string]any{}},
+			},
+		},
+		{
+			name: "cryptomb provider with type URL",
+			spec: map[string]any{
+				"secretName": "tls-secret",
+				"targetRefs": []any{gatewayRef("gateway")},
+				"privateKeyProvider": map[string]any{
+					"providerName": "cryptomb",
+					"typeURL":      "type.googleapis.com/example.Config",
+				},
+			},
+			wantErr: "the cryptomb provider is configured with cryptomb",
+		},
+		{
+			name: "custom provider without type URL",
+			spec: map[string]any{
+				"secretName":         "tls-secret",
+				"targetRefs":         []any{gatewayRef("gateway")},
+				"privateKeyProvider": map[string]any{"providerName": "pkcs11"},
+			},
+			wantErr: "other providers require typeURL",
+		},
+		{
+			name: "custom provider with config",
+			spec: map[string]any{
+				"secretName": "tls-secret",
+				"targetRefs": []any{gatewayRef("gateway")},
+				"privateKeyProvider": map[string]any{
+					"providerName": "pkcs11",
+					"typeURL":      "type.googleapis.com/example.Config",
+					"config":       map[string]any{"slot": int64(1)},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertValidation(t, schema.validate(t, newPolicyObject("v1alpha1", tt.spec)), tt.wantErr)
+		})
+	}
+}
+
+func TestCertificatePolicyCRDDefaults(t *testing.T) {
+	schema := loadCRDSchema(t, "v1beta1")
+
+	obj := newPolicyObject("v1beta1", map[string]any{
+		"secretRef":          map[string]any{"name": "tls-secret"},
+		"targetRefs":         []any{gatewayRef("gateway")},
+		"privateKeyProvider": map[string]any{"providerName": "cryptomb", "cryptomb": map[string]any{}},
+	})
+	if errs := schema.validate(t, obj); len(errs) > 0 {
+		t.Fatalf("unexpected validation errors: %v", errs.ToAggregate())
+	}
+
+	provider := obj["spec"].(map[string]any)["privateKeyProvider"].(map[string]any)
+	if got := provider["fallback"]; got != false {
+		t.Errorf("fallback = %v, want false", got)
+	}
+	if got := provider["cryptomb"].(map[string]any)["pollDelay"]; got != "20ms" {
+		t.Errorf("cryptomb.pollDelay = %v, want %q", got, "20ms")
+	}
+}
+
+func TestCertificatePolicyV1beta1CRDValidation(t *testing.T) {
+	schema := loadCRDSchema(t, "v1beta1")
+
+	tests := []struct {
+		name    string
+		spec    map[string]any
+		wantErr string
+	}{
+		{
+			name: "valid policy",
+			spec: map[string]any{
+				"secretRef":         map[string]any{"name": "tls-secret"},
+				"fallbackSecretRef": map[string]any{"name": "fallback"},
+				"targetRefs":        []any{gatewayRef("gateway")},
+				"hostnames":         []any{"*.example.com", "www.example.org"},
+				"tlsParams":         map[string]any{"minVersion": "1.2", "maxVersion": "1.3"},
+			},
+		},
+		{
+			name:    "missing secret ref",
+			spec:    map[string]any{"targetRefs": []any{gatewayRef("gateway")}},
+			wantErr: "spec.secretRef: Required value",
+		},
+		{
+			name: "invalid secret ref name",
+			spec: map[string]any{
+				"secretRef":  map[string]any{"name": "Not_A_Name"},
+				"targetRefs": []any{gatewayRef("gateway")},
+			},
+			wantErr: "spec.secretRef.name",
+		},
+		{
+			name: "invalid hostname",
+			spec: map[string]any{
+				"secretRef":  map[string]any{"name": "tls-secret"},
+				"targetRefs": []any{gatewayRef("gateway")},
+				"hostnames":  []any{"not a hostname"},
+			},
+			wantErr: "spec.hostnames[0]",
+		},
+		{
+			name: "unknown TLS version",
+			spec: map[string]any{
+				"secretRef":  map[string]any{"name": "tls-secret"},
+				"targetRefs": []any{gatewayRef("gateway")},
+				"tlsParams":  map[string]any{"minVersion": "1.4"},
+			},
+			wantErr: "spec.tlsParams.minVersion",
+		},
+		{
+			name: "inverted TLS version range",
+			spec: map[string]any{
+				"secretRef":  map[string]any{"name": "tls-secret"},
+				"targetRefs": []any{gatewayRef("gateway")},
+				"tlsParams":  map[string]any{"minVersion": "1.3", "maxVersion": "1.2"},
+			},
+			wantErr: "minVersion must not be greater than maxVersion",
+		},
+		{
+			name: "duplicate target refs",
+			spec: map[string]any{
+				"secretRef":  map[string]any{"name": "tls-secret"},
+				"targetRefs": []any{gatewayRef("gateway"), gatewayRef("gateway")},
+			},
+			wantErr: "targetRefs must be unique",
+		},
+		{
+			name: "Filename delivery with mount path",
+			spec: map[string]any{
+				"secretRef":  map[string]any{"name": "tls-secret"},
+				"targetRefs": []any{gatewayRef("gateway")},
+				"delivery":   map[string]any{"mode": "Filename", "mountPath": "/etc/envoy/certs"},
+			},
+		},
+		{
+			name: "Filename delivery without mount path",
+			spec: map[string]any{
+				"secretRef":  map[string]any{"name": "tls-secret"},
+				"targetRefs": []any{gatewayRef("gateway")},
+				"delivery":   map[string]any{"mode": "Filename"},
+			},
+			wantErr: "mountPath is required in Filename mode",
+		},
+		{
+			name: "private key provider with Inline delivery",
+			spec: map[string]any{
+				"secretRef":          map[string]any{"name": "tls-secret"},
+				"targetRefs":         []any{gatewayRef("gateway")},
+				"delivery":           map[string]any{"mode": "Inline"},
+				"privateKeyProvider": map[string]any{"providerName": "cryptomb"},
+			},
+		},
+		{
+			name: "private key provider with Reference delivery",
+			spec: map[string]any{
+				"secretRef":          map[string]any{"name": "tls-secret"},
+				"targetRefs":         []any{gatewayRef("gateway")},
+				"delivery":           map[string]any{"mode": "Reference"},
+				"privateKeyProvider": map[string]any{"providerName": "cryptomb"},
+			},
+			wantErr: "privateKeyProvider requires the Inline delivery mode",
+		},
+		{
+			name: "additional secret refs",
+			spec: map[string]any{
+				"secretRef":            map[string]any{"name": "tls-rsa"},
+				"additionalSecretRefs": []any{map[string]any{"name": "tls-ecdsa"}},
+				"targetRefs":           []any{gatewayRef("gateway")},
+			},
+		},
+		{
+			name: "duplicate additional secret refs",
+			spec: map[string]any{
+				"secretRef":            map[string]any{"name": "tls-rsa"},
+				"additionalSecretRefs": []any{map[string]any{"name": "tls-ecdsa"}, map[string]any{"name": "tls-ecdsa"}},
+				"targetRefs":           []any{gatewayRef("gateway")},
+			},
+			wantErr: "additionalSecretRefs must be unique",
+		},
+		{
+			name: "additional secret ref repeats secret ref",
+			spec: map[string]any{
+				"secretRef":            map[string]any{"name": "tls-rsa"},
+				"additionalSecretRefs": []any{map[string]any{"name": "tls-rsa"}},
+				"targetRefs":           []any{gatewayRef("gateway")},
+			},
+			wantErr: "additionalSecretRefs must not repeat secretRef",
+		},
+		{
+			name: "additional secret refs with Filename delivery",
+			spec: map[string]any{
+				"secretRef":            map[string]any{"name": "tls-rsa"},
+				"additionalSecretRefs": []any{map[string]any{"name": "tls-ecdsa"}},
+				"targetRefs":           []any{gatewayRef("gateway")},
+				"delivery":             map[string]any{"mode": "Filename", "mountPath": "/etc/envoy/certs"},
+			},
+			wantErr: "additionalSecretRefs require the Inline delivery mode",
+		},
+		{
+			name: "ALPN protocols",
+			spec: map[string]any{
+				"secretRef":     map[string]any{"name": "tls-secret"},
+				"targetRefs":    []any{gatewayRef("gateway")},
+				"alpnProtocols": []any{"h2", "http/1.1"},
+			},
+		},
+		{
+			name: "duplicate ALPN protocols",
+			spec: map[string]any{
+				"secretRef":     map[string]any{"name": "tls-secret"},
+				"targetRefs":    []any{gatewayRef("gateway")},
+				"alpnProtocols": []any{"h2", "h2"},
+			},
+			wantErr: "alpnProtocols must be unique",
+		},
+		{
+			name: "unknown ALPN protocol",
+			spec: map[string]any{
+				"secretRef":     map[string]any{"name": "tls-secret"},
+				"targetRefs":    []any{gatewayRef("gateway")},
+				"alpnProtocols": []any{"h3"},
+			},
+			wantErr: "spec.alpnProtocols[0]: Unsupported value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertValidation(t, schema.validate(t, newPolicyObject("v1beta1", tt.spec)), tt.wantErr)
+		})
+	}
+}
+
+// assertValidation checks that errs is empty when wantErr is empty and
+// contains wantErr otherwise.
+func assertValidation(t *testing.T, errs field.ErrorList, wantErr string) {
+	t.Helper()
+	if wantErr == "" {
+		if len(errs) > 0 {
+			t.Fatalf("unexpected validation errors: %v", errs.ToAggregate())
+		}
+		return
+	}
+	if len(errs) == 0 {
+		t.Fatalf("expected a validation error containing %q", wantErr)
+	}
+	if got := errs.ToAggregate().Error(); !strings.Contains(got, wantErr) {
+		t.Errorf("validation errors = %q, want them to contain %q", got, wantErr)
+	}
+}
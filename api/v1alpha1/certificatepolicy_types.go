@@ -1,32 +1,141 @@
-// Copyright Envoy Gateway Authors
-// SPDX-License-Identifier: Apache-2.0
-// The full text of the Apache license is available in the LICENSE file at
-// the root of the repo.
-
 package v1alpha1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
-// CertificatePolicy provides an example extension policy context resource.
+// CertificatePolicy attaches the TLS certificate of a Secret to the listeners
+// of one or more Gateways.
+//
+// Deprecated: use the v1beta1 CertificatePolicy. v1alpha1 objects are
+// converted to v1beta1 by the conversion webhook.
 //
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:deprecatedversion:warning="gateway.giantswarm.io/v1alpha1 CertificatePolicy is deprecated, use gateway.giantswarm.io/v1beta1"
 type CertificatePolicy struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
 	Spec CertificatePolicySpec `json:"spec"`
+
+	// +optional
+	Status CertificatePolicyStatus `json:"status,omitempty"`
 }
 
+// CertificatePolicySpec defines the desired state of a CertificatePolicy.
 type CertificatePolicySpec struct {
+	// TargetRefs are the Gateways, and optionally their listeners, the
+	// certificate is served on.
+	//
+	// +required
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=16
+	// +kubebuilder:validation:XValidation:rule="self.all(ref, ref.group == 'gateway.networking.k8s.io' && ref.kind == 'Gateway')",message="targetRefs must reference gateway.networking.k8s.io/Gateway resources"
+	// +kubebuilder:validation:XValidation:rule="self.all(r1, self.exists_one(r2, r1.name == r2.name && (has(r1.sectionName) ? (has(r2.sectionName) && r1.sectionName == r2.sectionName) : !has(r2.sectionName))))",message="targetRefs must be unique"
 	TargetRefs []gwapiv1.LocalPolicyTargetReferenceWithSectionName `json:"targetRefs"`
 
+	// SecretName is the name of the TLS Secret in the policy's namespace
+	// holding the certificate to serve.
+	//
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
 	SecretName string `json:"secretName"`
+
+	// FallbackSecretName is the name of a Secret in the same namespace that
+	// is served when the Secret referenced by SecretName is missing or
+	// invalid.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	FallbackSecretName string `json:"fallbackSecretName,omitempty"`
+
+	// PrivateKeyProvider offloads private key operations to an Envoy private
+	// key method provider instead of inlining the private key of the Secret.
+	//
+	// +optional
+	PrivateKeyProvider *PrivateKeyProvider `json:"privateKeyProvider,omitempty"`
 }
 
+// PrivateKeyProvider configures an Envoy private key method provider, for
+// example CryptoMB acceleration or an HSM backed provider.
+//
+// +kubebuilder:validation:XValidation:rule="self.providerName == 'cryptomb' ? !has(self.typeURL) && !has(self.config) : has(self.typeURL) && !has(self.cryptomb)",message="the cryptomb provider is configured with cryptomb, other providers require typeURL"
+type PrivateKeyProvider struct {
+	// ProviderName is the name of the Envoy private key method provider,
+	// e.g. "cryptomb".
+	//
+	// +kubebuilder:validation:MinLength=1
+	ProviderName string `json:"providerName"`
+
+	// CryptoMB configures the CryptoMB provider. It is only valid when
+	// ProviderName is "cryptomb". The private key is read from the Secret.
+	//
+	// +optional
+	CryptoMB *CryptoMBPrivateKeyProvider `json:"cryptomb,omitempty"`
+
+	// TypeURL is the protobuf type URL of the provider specific
+	// configuration. It is required for providers other than "cryptomb".
+	//
+	// +optional
+	TypeURL string `json:"typeURL,omitempty"`
+
+	// Config is the provider specific configuration in its JSON form.
+	//
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Config *runtime.RawExtension `json:"config,omitempty"`
+
+	// Fallback lets Envoy fall back to the BoringSSL default implementation
+	// when the provider is not available.
+	//
+	// +optional
+	// +kubebuilder:default=false
+	Fallback bool `json:"fallback,omitempty"`
+}
+
+// CryptoMBPrivateKeyProvider configures the CryptoMB private key provider.
+type CryptoMBPrivateKeyProvider struct {
+	// PollDelay is how long to wait before the per-thread processing queue is
+	// processed, even if it is not full. Defaults to 20ms.
+	//
+	// +optional
+	// +kubebuilder:default="20ms"
+	PollDelay *metav1.Duration `json:"pollDelay,omitempty"`
+}
+
+// CertificatePolicyStatus defines the observed state of a CertificatePolicy.
+type CertificatePolicyStatus struct {
+	// Conditions describe the current state of the policy.
+	//
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	// CertificateExpiringSoonConditionType is set to True when the certificate
+	// served for the policy expires within the configured threshold.
+	CertificateExpiringSoonConditionType = "CertificateExpiringSoon"
+
+	// CertificateExpiringSoonReason is used when the certificate expires
+	// within the configured threshold.
+	CertificateExpiringSoonReason = "ExpiringSoon"
+
+	// CertificateExpiredReason is used when the certificate already expired.
+	CertificateExpiredReason = "Expired"
+
+	// CertificateValidReason is used when the certificate does not expire
+	// within the configured threshold.
+	CertificateValidReason = "Valid"
+)
+
 // +kubebuilder:object:root=true
 //
 // CertificatePolicyList contains a list of CertificatePolicy resources.
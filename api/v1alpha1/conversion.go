@@ -0,0 +1,172 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+// ConversionDataAnnotation holds the v1beta1 fields that cannot be expressed
+// in v1alpha1, so that a round trip through v1alpha1 does not lose them.
+const ConversionDataAnnotation = "gateway.giantswarm.io/v1beta1-conversion-data"
+
+// conversionData is the content of the ConversionDataAnnotation.
+type conversionData struct {
+	Hostnames                  []gwapiv1.Hostname           `json:"hostnames,omitempty"`
+	TLSParams                  *v1beta1.TLSParameters       `json:"tlsParams,omitempty"`
+	Delivery                   *v1beta1.CertificateDelivery `json:"delivery,omitempty"`
+	AdditionalSecretRefs       []v1beta1.SecretReference    `json:"additionalSecretRefs,omitempty"`
+	SessionTicketKeysSecretRef *v1beta1.SecretReference     `json:"sessionTicketKeysSecretRef,omitempty"`
+	ALPNProtocols              []v1beta1.ALPNProtocol       `json:"alpnProtocols,omitempty"`
+}
+
+var _ conversion.Convertible = &CertificatePolicy{}
+
+// ConvertTo converts this CertificatePolicy to the v1beta1 hub version.
+func (src *CertificatePolicy) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1beta1.CertificatePolicy)
+	if !ok {
+		return fmt.Errorf("unsupported conversion hub %T", dstRaw)
+	}
+
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	dst.Spec = v1beta1.CertificatePolicySpec{
+		TargetRefs:         cloneTargetRefs(src.Spec.TargetRefs),
+		SecretRef:          v1beta1.SecretReference{Name: src.Spec.SecretName},
+		PrivateKeyProvider: convertPrivateKeyProviderTo(src.Spec.PrivateKeyProvider),
+	}
+	if src.Spec.FallbackSecretName != "" {
+		dst.Spec.FallbackSecretRef = &v1beta1.SecretReference{Name: src.Spec.FallbackSecretName}
+	}
+	dst.Status = v1beta1.CertificatePolicyStatus{Conditions: cloneConditions(src.Status.Conditions)}
+
+	raw, ok := dst.Annotations[ConversionDataAnnotation]
+	if !ok {
+		return nil
+	}
+	var data conversionData
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		return fmt.Errorf("failed to decode %s annotation: %w", ConversionDataAnnotation, err)
+	}
+	dst.Spec.Hostnames = data.Hostnames
+	dst.Spec.TLSParams = data.TLSParams
+	dst.Spec.Delivery = data.Delivery
+	dst.Spec.AdditionalSecretRefs = data.AdditionalSecretRefs
+	dst.Spec.SessionTicketKeysSecretRef = data.SessionTicketKeysSecretRef
+	dst.Spec.ALPNProtocols = data.ALPNProtocols
+	delete(dst.Annotations, ConversionDataAnnotation)
+	if len(dst.Annotations) == 0 {
+		dst.Annotations = nil
+	}
+	return nil
+}
+
+// ConvertFrom converts the v1beta1 hub version to this CertificatePolicy.
+func (dst *CertificatePolicy) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1beta1.CertificatePolicy)
+	if !ok {
+		return fmt.Errorf("unsupported conversion hub %T", srcRaw)
+	}
+
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	dst.Spec = CertificatePolicySpec{
+		TargetRefs:         cloneTargetRefs(src.Spec.TargetRefs),
+		SecretName:         src.Spec.SecretRef.Name,
+		PrivateKeyProvider: convertPrivateKeyProviderFrom(src.Spec.PrivateKeyProvider),
+	}
+	if src.Spec.FallbackSecretRef != nil {
+		dst.Spec.FallbackSecretName = src.Spec.FallbackSecretRef.Name
+	}
+	dst.Status = CertificatePolicyStatus{Conditions: cloneConditions(src.Status.Conditions)}
+
+	if len(src.Spec.Hostnames) == 0 && src.Spec.TLSParams == nil && src.Spec.Delivery == nil &&
+		len(src.Spec.AdditionalSecretRefs) == 0 && src.Spec.SessionTicketKeysSecretRef == nil &&
+		len(src.Spec.ALPNProtocols) == 0 {
+		return nil
+	}
+	raw, err := json.Marshal(conversionData{
+		Hostnames:                  src.Spec.Hostnames,
+		TLSParams:                  src.Spec.TLSParams,
+		Delivery:                   src.Spec.Delivery,
+		AdditionalSecretRefs:       src.Spec.AdditionalSecretRefs,
+		SessionTicketKeysSecretRef: src.Spec.SessionTicketKeysSecretRef,
+		ALPNProtocols:              src.Spec.ALPNProtocols,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s annotation: %w", ConversionDataAnnotation, err)
+	}
+	dst.Annotations = maps.Clone(dst.Annotations)
+	if dst.Annotations == nil {
+		dst.Annotations = map[string]string{}
+	}
+	dst.Annotations[ConversionDataAnnotation] = string(raw)
+	return nil
+}
+
+func convertPrivateKeyProviderTo(src *PrivateKeyProvider) *v1beta1.PrivateKeyProvider {
+	if src == nil {
+		return nil
+	}
+	dst := &v1beta1.PrivateKeyProvider{
+		ProviderName: src.ProviderName,
+		TypeURL:      src.TypeURL,
+		Config:       src.Config.DeepCopy(),
+		Fallback:     src.Fallback,
+	}
+	if src.CryptoMB != nil {
+		dst.CryptoMB = &v1beta1.CryptoMBPrivateKeyProvider{PollDelay: cloneDuration(src.CryptoMB.PollDelay)}
+	}
+	return dst
+}
+
+func convertPrivateKeyProviderFrom(src *v1beta1.PrivateKeyProvider) *PrivateKeyProvider {
+	if src == nil {
+		return nil
+	}
+	dst := &PrivateKeyProvider{
+		ProviderName: src.ProviderName,
+		TypeURL:      src.TypeURL,
+		Config:       src.Config.DeepCopy(),
+		Fallback:     src.Fallback,
+	}
+	if src.CryptoMB != nil {
+		dst.CryptoMB = &CryptoMBPrivateKeyProvider{PollDelay: cloneDuration(src.CryptoMB.PollDelay)}
+	}
+	return dst
+}
+
+func cloneTargetRefs(refs []gwapiv1.LocalPolicyTargetReferenceWithSectionName) []gwapiv1.LocalPolicyTargetReferenceWithSectionName {
+	if refs == nil {
+		return nil
+	}
+	clone := make([]gwapiv1.LocalPolicyTargetReferenceWithSectionName, len(refs))
+	for i := range refs {
+		refs[i].DeepCopyInto(&clone[i])
+	}
+	return clone
+}
+
+func cloneConditions(conditions []metav1.Condition) []metav1.Condition {
+	if conditions == nil {
+		return nil
+	}
+	clone := make([]metav1.Condition, len(conditions))
+	for i := range conditions {
+		conditions[i].DeepCopyInto(&clone[i])
+	}
+	return clone
+}
+
+func cloneDuration(d *metav1.Duration) *metav1.Duration {
+	if d == nil {
+		return nil
+	}
+	clone := *d
+	return &clone
+}
@@ -0,0 +1,171 @@
+package v1alpha1_test
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1alpha1"
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+func TestConvertToHub(t *testing.T) {
+	src := &v1alpha1.CertificatePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy", Namespace: "default", Labels: map[string]string{"app": "web"}},
+		Spec: v1alpha1.CertificatePolicySpec{
+			TargetRefs:         []gwapiv1.LocalPolicyTargetReferenceWithSectionName{gatewayTarget("gateway")},
+			SecretName:         "secret-1",
+			FallbackSecretName: "fallback",
+			PrivateKeyProvider: &v1alpha1.PrivateKeyProvider{
+				ProviderName: "cryptomb",
+				CryptoMB:     &v1alpha1.CryptoMBPrivateKeyProvider{PollDelay: &metav1.Duration{Duration: time.Millisecond}},
+			},
+		},
+		Status: v1alpha1.CertificatePolicyStatus{
+			Conditions: []metav1.Condition{{Type: v1alpha1.CertificateExpiringSoonConditionType, Status: metav1.ConditionFalse}},
+		},
+	}
+
+	var dst v1beta1.CertificatePolicy
+	if err := src.ConvertTo(&dst); err != nil {
+		t.Fatalf("ConvertTo() error = %v", err)
+	}
+
+	if dst.Name != "policy" || dst.Labels["app"] != "web" {
+		t.Errorf("metadata was not converted: %+v", dst.ObjectMeta)
+	}
+	if dst.Spec.SecretRef.Name != "secret-1" {
+		t.Errorf("secretRef.name = %q, want %q", dst.Spec.SecretRef.Name, "secret-1")
+	}
+	if dst.Spec.FallbackSecretRef == nil || dst.Spec.FallbackSecretRef.Name != "fallback" {
+		t.Errorf("fallbackSecretRef = %+v, want fallback", dst.Spec.FallbackSecretRef)
+	}
+	if got := dst.Spec.PrivateKeyProvider.CryptoMB.PollDelay.Duration; got != time.Millisecond {
+		t.Errorf("cryptomb.pollDelay = %v, want 1ms", got)
+	}
+	if len(dst.Status.Conditions) != 1 {
+		t.Errorf("got %d conditions, want 1", len(dst.Status.Conditions))
+	}
+
+	var back v1alpha1.CertificatePolicy
+	if err := back.ConvertFrom(&dst); err != nil {
+		t.Fatalf("ConvertFrom() error = %v", err)
+	}
+	if !equality.Semantic.DeepEqual(&back, src) {
+		t.Errorf("round trip changed the policy:\ngot  %+v\nwant %+v", back, *src)
+	}
+}
+
+func TestHubRoundTripPreservesV1beta1Fields(t *testing.T) {
+	src := &v1beta1.CertificatePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy", Namespace: "default", Annotations: map[string]string{"team": "gateway"}},
+		Spec: v1beta1.CertificatePolicySpec{
+			TargetRefs: []gwapiv1.LocalPolicyTargetReferenceWithSectionName{gatewayTarget("gateway")},
+			SecretRef:  v1beta1.SecretReference{Name: "secret-1"},
+			Hostnames:  []gwapiv1.Hostname{"*.example.com"},
+			TLSParams: &v1beta1.TLSParameters{
+				MinVersion:   ptr.To(v1beta1.TLSv12),
+				CipherSuites: []string{"ECDHE-ECDSA-AES128-GCM-SHA256"},
+			},
+			PrivateKeyProvider: &v1beta1.PrivateKeyProvider{
+				ProviderName: "pkcs11",
+				TypeURL:      "type.googleapis.com/example.Config",
+				Config:       &runtime.RawExtension{Raw: []byte(`{"slot":1}`)},
+			},
+		},
+	}
+
+	var spoke v1alpha1.CertificatePolicy
+	if err := spoke.ConvertFrom(src); err != nil {
+		t.Fatalf("ConvertFrom() error = %v", err)
+	}
+	if _, ok := spoke.Annotations[v1alpha1.ConversionDataAnnotation]; !ok {
+		t.Fatalf("annotation %s is missing", v1alpha1.ConversionDataAnnotation)
+	}
+	if _, ok := src.Annotations[v1alpha1.ConversionDataAnnotation]; ok {
+		t.Error("ConvertFrom() modified the annotations of the source")
+	}
+
+	var dst v1beta1.CertificatePolicy
+	if err := spoke.ConvertTo(&dst); err != nil {
+		t.Fatalf("ConvertTo() error = %v", err)
+	}
+	if !equality.Semantic.DeepEqual(&dst, src) {
+		t.Errorf("round trip changed the policy:\ngot  %+v\nwant %+v", dst, *src)
+	}
+}
+
+func TestHubRoundTripPreservesDelivery(t *testing.T) {
+	src := &v1beta1.CertificatePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy", Namespace: "default"},
+		Spec: v1beta1.CertificatePolicySpec{
+			TargetRefs: []gwapiv1.LocalPolicyTargetReferenceWithSectionName{gatewayTarget("gateway")},
+			SecretRef:  v1beta1.SecretReference{Name: "secret-1"},
+			Delivery:   &v1beta1.CertificateDelivery{Mode: v1beta1.DeliveryFilename, MountPath: "/etc/envoy/certs/secret-1"},
+		},
+	}
+
+	var spoke v1alpha1.CertificatePolicy
+	if err := spoke.ConvertFrom(src); err != nil {
+		t.Fatalf("ConvertFrom() error = %v", err)
+	}
+	var dst v1beta1.CertificatePolicy
+	if err := spoke.ConvertTo(&dst); err != nil {
+		t.Fatalf("ConvertTo() error = %v", err)
+	}
+	if !equality.Semantic.DeepEqual(&dst, src) {
+		t.Errorf("round trip changed the policy:\ngot  %+v\nwant %+v", dst, *src)
+	}
+}
+
+func TestHubRoundTripPreservesAdditionalSecrets(t *testing.T) {
+	src := &v1beta1.CertificatePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy", Namespace: "default"},
+		Spec: v1beta1.CertificatePolicySpec{
+			TargetRefs:                 []gwapiv1.LocalPolicyTargetReferenceWithSectionName{gatewayTarget("gateway")},
+			SecretRef:                  v1beta1.SecretReference{Name: "secret-rsa"},
+			AdditionalSecretRefs:       []v1beta1.SecretReference{{Name: "secret-ecdsa"}},
+			SessionTicketKeysSecretRef: &v1beta1.SecretReference{Name: "ticket-keys"},
+			ALPNProtocols:              []v1beta1.ALPNProtocol{v1beta1.ALPNHTTP11},
+		},
+	}
+
+	var spoke v1alpha1.CertificatePolicy
+	if err := spoke.ConvertFrom(src); err != nil {
+		t.Fatalf("ConvertFrom() error = %v", err)
+	}
+	var dst v1beta1.CertificatePolicy
+	if err := spoke.ConvertTo(&dst); err != nil {
+		t.Fatalf("ConvertTo() error = %v", err)
+	}
+	if !equality.Semantic.DeepEqual(&dst, src) {
+		t.Errorf("round trip changed the policy:\ngot  %+v\nwant %+v", dst, *src)
+	}
+}
+
+func TestConvertToInvalidConversionData(t *testing.T) {
+	src := &v1alpha1.CertificatePolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "policy",
+			Annotations: map[string]string{v1alpha1.ConversionDataAnnotation: "{"},
+		},
+	}
+	if err := src.ConvertTo(&v1beta1.CertificatePolicy{}); err == nil {
+		t.Error("ConvertTo() expected an error for an invalid annotation")
+	}
+}
+
+func gatewayTarget(name string) gwapiv1.LocalPolicyTargetReferenceWithSectionName {
+	return gwapiv1.LocalPolicyTargetReferenceWithSectionName{
+		LocalPolicyTargetReference: gwapiv1.LocalPolicyTargetReference{
+			Group: gwapiv1.GroupName,
+			Kind:  "Gateway",
+			Name:  gwapiv1.ObjectName(name),
+		},
+	}
+}
@@ -1,8 +1,7 @@
-// Copyright Envoy Gateway Authors
-// SPDX-License-Identifier: Apache-2.0
-// The full text of the Apache license is available in the LICENSE file at
-// the root of the repo.
-
+// Package v1alpha1 contains the deprecated v1alpha1 API of the
+// gateway.giantswarm.io group. Its objects are converted to v1beta1 by the
+// conversion webhook.
+//
 // +kubebuilder:object:generate=true
 // +groupName=gateway.giantswarm.io
 package v1alpha1
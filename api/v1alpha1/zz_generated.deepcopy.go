@@ -5,8 +5,9 @@
 package v1alpha1
 
 import (
-	runtime "k8s.io/apimachinery/pkg/runtime"
-	v1 "sigs.k8s.io/gateway-api/apis/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/gateway-api/apis/v1"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -15,6 +16,7 @@ func (in *CertificatePolicy) DeepCopyInto(out *CertificatePolicy) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificatePolicy.
@@ -77,6 +79,11 @@ func (in *CertificatePolicySpec) DeepCopyInto(out *CertificatePolicySpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.PrivateKeyProvider != nil {
+		in, out := &in.PrivateKeyProvider, &out.PrivateKeyProvider
+		*out = new(PrivateKeyProvider)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificatePolicySpec.
@@ -88,3 +95,70 @@ func (in *CertificatePolicySpec) DeepCopy() *CertificatePolicySpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificatePolicyStatus) DeepCopyInto(out *CertificatePolicyStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificatePolicyStatus.
+func (in *CertificatePolicyStatus) DeepCopy() *CertificatePolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificatePolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CryptoMBPrivateKeyProvider) DeepCopyInto(out *CryptoMBPrivateKeyProvider) {
+	*out = *in
+	if in.PollDelay != nil {
+		in, out := &in.PollDelay, &out.PollDelay
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CryptoMBPrivateKeyProvider.
+func (in *CryptoMBPrivateKeyProvider) DeepCopy() *CryptoMBPrivateKeyProvider {
+	if in == nil {
+		return nil
+	}
+	out := new(CryptoMBPrivateKeyProvider)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrivateKeyProvider) DeepCopyInto(out *PrivateKeyProvider) {
+	*out = *in
+	if in.CryptoMB != nil {
+		in, out := &in.CryptoMB, &out.CryptoMB
+		*out = new(CryptoMBPrivateKeyProvider)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrivateKeyProvider.
+func (in *PrivateKeyProvider) DeepCopy() *PrivateKeyProvider {
+	if in == nil {
+		return nil
+	}
+	out := new(PrivateKeyProvider)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,328 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// CertificatePolicy attaches the TLS certificate of a Secret to the listeners
+// of one or more Gateways.
+//
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+type CertificatePolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec CertificatePolicySpec `json:"spec"`
+
+	// +optional
+	Status CertificatePolicyStatus `json:"status,omitempty"`
+}
+
+// CertificatePolicySpec defines the desired state of a CertificatePolicy.
+//
+// +kubebuilder:validation:XValidation:rule="!has(self.privateKeyProvider) || !has(self.delivery) || self.delivery.mode == 'Inline'",message="privateKeyProvider requires the Inline delivery mode"
+// +kubebuilder:validation:XValidation:rule="!has(self.additionalSecretRefs) || !has(self.delivery) || self.delivery.mode == 'Inline'",message="additionalSecretRefs require the Inline delivery mode"
+// +kubebuilder:validation:XValidation:rule="!has(self.additionalSecretRefs) || self.additionalSecretRefs.all(ref, ref.name != self.secretRef.name)",message="additionalSecretRefs must not repeat secretRef"
+type CertificatePolicySpec struct {
+	// TargetRefs are the Gateways, and optionally their listeners, the
+	// certificate is served on.
+	//
+	// +required
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=16
+	// +kubebuilder:validation:XValidation:rule="self.all(ref, ref.group == 'gateway.networking.k8s.io' && ref.kind == 'Gateway')",message="targetRefs must reference gateway.networking.k8s.io/Gateway resources"
+	// +kubebuilder:validation:XValidation:rule="self.all(r1, self.exists_one(r2, r1.name == r2.name && (has(r1.sectionName) ? (has(r2.sectionName) && r1.sectionName == r2.sectionName) : !has(r2.sectionName))))",message="targetRefs must be unique"
+	TargetRefs []gwapiv1.LocalPolicyTargetReferenceWithSectionName `json:"targetRefs"`
+
+	// SecretRef references the TLS Secret in the policy's namespace holding
+	// the certificate to serve.
+	//
+	// +required
+	SecretRef SecretReference `json:"secretRef"`
+
+	// AdditionalSecretRefs references further TLS Secrets in the policy's
+	// namespace that are served next to SecretRef, e.g. an ECDSA certificate
+	// next to an RSA one. Envoy picks the certificate the client supports.
+	// At most one certificate per key algorithm is served on a filter chain;
+	// later ones are left out and reported in the KeyAlgorithmConflict
+	// condition.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxItems=3
+	// +kubebuilder:validation:XValidation:rule="self.all(r1, self.exists_one(r2, r1.name == r2.name))",message="additionalSecretRefs must be unique"
+	AdditionalSecretRefs []SecretReference `json:"additionalSecretRefs,omitempty"`
+
+	// FallbackSecretRef references a Secret in the policy's namespace that is
+	// served when the Secret referenced by SecretRef is missing or invalid.
+	//
+	// +optional
+	FallbackSecretRef *SecretReference `json:"fallbackSecretRef,omitempty"`
+
+	// Hostnames restricts the certificate to the filter chains of the
+	// targeted listeners matching one of these server names. When empty the
+	// certificate is added to every TLS filter chain of the targets.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxItems=16
+	Hostnames []gwapiv1.Hostname `json:"hostnames,omitempty"`
+
+	// TLSParams overrides the TLS parameters of the filter chains the
+	// certificate is added to.
+	//
+	// +optional
+	TLSParams *TLSParameters `json:"tlsParams,omitempty"`
+
+	// ALPNProtocols overrides the application protocols offered on the
+	// filter chains the certificate is added to, in order of preference.
+	// Leaving out h2 makes clients negotiate HTTP/1.1, e.g. for legacy
+	// clients with broken HTTP/2 support. HTTP/3 filter chains always
+	// negotiate h3 and are not affected.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxItems=2
+	// +kubebuilder:validation:XValidation:rule="self.all(p1, self.exists_one(p2, p1 == p2))",message="alpnProtocols must be unique"
+	ALPNProtocols []ALPNProtocol `json:"alpnProtocols,omitempty"`
+
+	// PrivateKeyProvider offloads private key operations to an Envoy private
+	// key method provider instead of inlining the private key of the Secret.
+	//
+	// +optional
+	PrivateKeyProvider *PrivateKeyProvider `json:"privateKeyProvider,omitempty"`
+
+	// Delivery configures how the certificate reaches Envoy. By default the
+	// certificate and private key are inlined in the xDS secret.
+	//
+	// +optional
+	Delivery *CertificateDelivery `json:"delivery,omitempty"`
+
+	// SessionTicketKeysSecretRef references a Secret in the policy's
+	// namespace holding the TLS session ticket keys of the filter chains the
+	// certificate is served on. Sharing the keys lets every Envoy replica
+	// resume the TLS sessions of the others. The Secret holds one or more
+	// 80 byte keys concatenated under its ticket.keys key; the first one
+	// encrypts new tickets, all of them decrypt.
+	//
+	// +optional
+	SessionTicketKeysSecretRef *SecretReference `json:"sessionTicketKeysSecretRef,omitempty"`
+}
+
+// SecretReference references a Secret in the namespace of the policy.
+type SecretReference struct {
+	// Name is the name of the Secret.
+	//
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	Name string `json:"name"`
+}
+
+// CertificateDeliveryMode selects how a certificate reaches Envoy.
+//
+// +kubebuilder:validation:Enum=Inline;Reference;Filename
+type CertificateDeliveryMode string
+
+const (
+	// DeliveryInline sends the certificate and private key in the xDS
+	// secret.
+	DeliveryInline CertificateDeliveryMode = "Inline"
+
+	// DeliveryReference references the xDS secret Envoy Gateway generates
+	// for a Gateway listener serving the same Secret. When no listener does,
+	// the Filename mode is used if a mount path is set.
+	DeliveryReference CertificateDeliveryMode = "Reference"
+
+	// DeliveryFilename points Envoy at the files of the Secret mounted into
+	// the proxy. The Secret is not read.
+	DeliveryFilename CertificateDeliveryMode = "Filename"
+)
+
+// CertificateDelivery configures how the certificate of a policy reaches
+// Envoy, so that private keys do not have to travel through xDS.
+//
+// +kubebuilder:validation:XValidation:rule="self.mode != 'Filename' || has(self.mountPath)",message="mountPath is required in Filename mode"
+type CertificateDelivery struct {
+	// Mode selects how the certificate reaches Envoy.
+	//
+	// +optional
+	// +kubebuilder:default=Inline
+	Mode CertificateDeliveryMode `json:"mode,omitempty"`
+
+	// MountPath is the directory the Secret is mounted at in the Envoy proxy
+	// containers. Envoy reads tls.crt and tls.key from it and reloads them
+	// when the directory changes.
+	//
+	// +optional
+	// +kubebuilder:validation:Pattern=`^/`
+	// +kubebuilder:validation:MaxLength=1024
+	MountPath string `json:"mountPath,omitempty"`
+}
+
+// ALPNProtocol is an application protocol negotiated through TLS ALPN.
+//
+// +kubebuilder:validation:Enum=h2;"http/1.1"
+type ALPNProtocol string
+
+const (
+	// ALPNHTTP2 is HTTP/2.
+	ALPNHTTP2 ALPNProtocol = "h2"
+	// ALPNHTTP11 is HTTP/1.1.
+	ALPNHTTP11 ALPNProtocol = "http/1.1"
+)
+
+// TLSVersion is a TLS protocol version.
+//
+// +kubebuilder:validation:Enum=Auto;"1.0";"1.1";"1.2";"1.3"
+type TLSVersion string
+
+const (
+	// TLSAuto lets Envoy pick its default version.
+	TLSAuto TLSVersion = "Auto"
+	// TLSv10 is TLS 1.0.
+	TLSv10 TLSVersion = "1.0"
+	// TLSv11 is TLS 1.1.
+	TLSv11 TLSVersion = "1.1"
+	// TLSv12 is TLS 1.2.
+	TLSv12 TLSVersion = "1.2"
+	// TLSv13 is TLS 1.3.
+	TLSv13 TLSVersion = "1.3"
+)
+
+// TLSParameters configures the TLS handshake of a listener.
+//
+// +kubebuilder:validation:XValidation:rule="!has(self.minVersion) || !has(self.maxVersion) || self.minVersion == 'Auto' || self.maxVersion == 'Auto' || self.minVersion <= self.maxVersion",message="minVersion must not be greater than maxVersion"
+type TLSParameters struct {
+	// MinVersion is the minimum TLS version accepted.
+	//
+	// +optional
+	MinVersion *TLSVersion `json:"minVersion,omitempty"`
+
+	// MaxVersion is the maximum TLS version accepted.
+	//
+	// +optional
+	MaxVersion *TLSVersion `json:"maxVersion,omitempty"`
+
+	// CipherSuites are the cipher suites offered for TLS 1.2 and older, in
+	// Envoy's cipher suite syntax.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxItems=32
+	CipherSuites []string `json:"cipherSuites,omitempty"`
+
+	// ECDHCurves are the elliptic curves offered for key exchange.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxItems=16
+	ECDHCurves []string `json:"ecdhCurves,omitempty"`
+
+	// SignatureAlgorithms are the signature algorithms offered for the
+	// handshake.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxItems=32
+	SignatureAlgorithms []string `json:"signatureAlgorithms,omitempty"`
+}
+
+// PrivateKeyProvider configures an Envoy private key method provider, for
+// example CryptoMB acceleration or an HSM backed provider.
+//
+// +kubebuilder:validation:XValidation:rule="self.providerName == 'cryptomb' ? !has(self.typeURL) && !has(self.config) : has(self.typeURL) && !has(self.cryptomb)",message="the cryptomb provider is configured with cryptomb, other providers require typeURL"
+type PrivateKeyProvider struct {
+	// ProviderName is the name of the Envoy private key method provider,
+	// e.g. "cryptomb".
+	//
+	// +kubebuilder:validation:MinLength=1
+	ProviderName string `json:"providerName"`
+
+	// CryptoMB configures the CryptoMB provider. It is only valid when
+	// ProviderName is "cryptomb". The private key is read from the Secret.
+	//
+	// +optional
+	CryptoMB *CryptoMBPrivateKeyProvider `json:"cryptomb,omitempty"`
+
+	// TypeURL is the protobuf type URL of the provider specific
+	// configuration. It is required for providers other than "cryptomb".
+	//
+	// +optional
+	TypeURL string `json:"typeURL,omitempty"`
+
+	// Config is the provider specific configuration in its JSON form.
+	//
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Config *runtime.RawExtension `json:"config,omitempty"`
+
+	// Fallback lets Envoy fall back to the BoringSSL default implementation
+	// when the provider is not available.
+	//
+	// +optional
+	// +kubebuilder:default=false
+	Fallback bool `json:"fallback,omitempty"`
+}
+
+// CryptoMBPrivateKeyProvider configures the CryptoMB private key provider.
+type CryptoMBPrivateKeyProvider struct {
+	// PollDelay is how long to wait before the per-thread processing queue is
+	// processed, even if it is not full. Defaults to 20ms.
+	//
+	// +optional
+	// +kubebuilder:default="20ms"
+	PollDelay *metav1.Duration `json:"pollDelay,omitempty"`
+}
+
+// CertificatePolicyStatus defines the observed state of a CertificatePolicy.
+type CertificatePolicyStatus struct {
+	// Conditions describe the current state of the policy.
+	//
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	// CertificateExpiringSoonConditionType is set to True when the certificate
+	// served for the policy expires within the configured threshold.
+	CertificateExpiringSoonConditionType = "CertificateExpiringSoon"
+
+	// CertificateExpiringSoonReason is used when the certificate expires
+	// within the configured threshold.
+	CertificateExpiringSoonReason = "ExpiringSoon"
+
+	// CertificateExpiredReason is used when the certificate already expired.
+	CertificateExpiredReason = "Expired"
+
+	// CertificateValidReason is used when the certificate does not expire
+	// within the configured threshold.
+	CertificateValidReason = "Valid"
+
+	// KeyAlgorithmConflictConditionType is set to True when several Secrets
+	// of the policy hold certificates with the same key algorithm and only
+	// the first of them is served.
+	KeyAlgorithmConflictConditionType = "KeyAlgorithmConflict"
+
+	// DuplicateKeyAlgorithmReason is used when certificates of the policy
+	// are left out because an earlier one has the same key algorithm.
+	DuplicateKeyAlgorithmReason = "DuplicateKeyAlgorithm"
+
+	// UniqueKeyAlgorithmsReason is used when every certificate of the policy
+	// has a different key algorithm.
+	UniqueKeyAlgorithmsReason = "UniqueKeyAlgorithms"
+)
+
+// +kubebuilder:object:root=true
+//
+// CertificatePolicyList contains a list of CertificatePolicy resources.
+type CertificatePolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CertificatePolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CertificatePolicy{}, &CertificatePolicyList{})
+}
@@ -0,0 +1,5 @@
+package v1beta1
+
+// Hub marks CertificatePolicy as the conversion hub. Older API versions
+// convert to and from this version.
+func (*CertificatePolicy) Hub() {}
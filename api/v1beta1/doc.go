@@ -0,0 +1,6 @@
+// Package v1beta1 contains the v1beta1 API of the gateway.giantswarm.io
+// group. It is the storage version and the conversion hub of the group.
+//
+// +kubebuilder:object:generate=true
+// +groupName=gateway.giantswarm.io
+package v1beta1
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/giantswarm/envoy-extension-server-app/internal/config"
+	"github.com/giantswarm/envoy-extension-server-app/internal/extensionserver"
+	"github.com/giantswarm/envoy-extension-server-app/internal/logging"
+)
+
+// applyFlags overrides the settings of the flags that are set.
+func applyFlags(cCtx *cli.Context, cfg *config.Config) error {
+	if cCtx.IsSet("host") {
+		cfg.Server.Host = cCtx.String("host")
+	}
+	if cCtx.IsSet("port") {
+		cfg.Server.Port = cCtx.Int("port")
+	}
+	if cCtx.IsSet("log-level") {
+		cfg.Logging.Level = cCtx.String("log-level")
+	}
+	if cCtx.IsSet("log-format") {
+		cfg.Logging.Format = cCtx.String("log-format")
+	}
+	if cCtx.IsSet("fallback-secret") {
+		cfg.Fallback.Secret = cCtx.String("fallback-secret")
+	}
+	if cCtx.IsSet("strict") {
+		cfg.Fallback.Strict = cCtx.Bool("strict")
+	}
+	if cCtx.IsSet("session-ticket-key-rotation") {
+		cfg.SessionTicketKeys.Rotate = cCtx.Bool("session-ticket-key-rotation")
+	}
+	if cCtx.IsSet("session-ticket-key-rotation-interval") {
+		cfg.SessionTicketKeys.RotationInterval = metav1.Duration{Duration: cCtx.Duration("session-ticket-key-rotation-interval")}
+	}
+	if cCtx.IsSet("validation-mode") {
+		cfg.Hooks.ValidationMode = cCtx.String("validation-mode")
+	}
+	if cCtx.IsSet("hook-timeout") {
+		cfg.Hooks.Timeout = metav1.Duration{Duration: cCtx.Duration("hook-timeout")}
+	}
+	if cCtx.IsSet("hook-timeouts") {
+		timeouts, err := extensionserver.ParseHookTimeouts(cCtx.StringSlice("hook-timeouts"))
+		if err != nil {
+			return fmt.Errorf("invalid --hook-timeouts: %w", err)
+		}
+		cfg.Hooks.Timeouts = make(map[string]metav1.Duration, len(timeouts))
+		for hook, timeout := range timeouts {
+			cfg.Hooks.Timeouts[hook] = metav1.Duration{Duration: timeout}
+		}
+	}
+	if cCtx.IsSet("fetch-concurrency") {
+		cfg.Hooks.FetchConcurrency = cCtx.Int("fetch-concurrency")
+	}
+	if cCtx.IsSet("partial-results") {
+		cfg.Hooks.PartialResults = cCtx.Bool("partial-results")
+	}
+	if cCtx.IsSet("mutators") {
+		cfg.Hooks.Mutators = cCtx.StringSlice("mutators")
+	}
+	if cCtx.IsSet("otlp-endpoint") {
+		cfg.Tracing.Endpoint = cCtx.String("otlp-endpoint")
+	}
+	if cCtx.IsSet("otlp-insecure") {
+		cfg.Tracing.Insecure = cCtx.Bool("otlp-insecure")
+	}
+	if cCtx.IsSet("trace-sample-ratio") {
+		cfg.Tracing.SampleRatio = cCtx.Float64("trace-sample-ratio")
+	}
+	if cCtx.IsSet("metrics-port") {
+		cfg.Metrics.Port = cCtx.Int("metrics-port")
+	}
+	if cCtx.IsSet("certificate-expiry-threshold") {
+		cfg.Metrics.CertificateExpiry.Threshold = metav1.Duration{Duration: cCtx.Duration("certificate-expiry-threshold")}
+	}
+	if cCtx.IsSet("certificate-expiry-scan-interval") {
+		cfg.Metrics.CertificateExpiry.ScanInterval = metav1.Duration{Duration: cCtx.Duration("certificate-expiry-scan-interval")}
+	}
+	if cCtx.IsSet("cache") {
+		cfg.Cache.Enabled = cCtx.Bool("cache")
+	}
+	if cCtx.IsSet("cache-namespaces") {
+		cfg.Cache.Namespaces = cCtx.StringSlice("cache-namespaces")
+	}
+	if cCtx.IsSet("cache-secret-selector") {
+		cfg.Cache.SecretSelector = cCtx.String("cache-secret-selector")
+	}
+	if cCtx.IsSet("cache-policy-selector") {
+		cfg.Cache.PolicySelector = cCtx.String("cache-policy-selector")
+	}
+	if cCtx.IsSet("leader-elect") {
+		cfg.LeaderElection.Enabled = cCtx.Bool("leader-elect")
+	}
+	if cCtx.IsSet("leader-election-namespace") {
+		cfg.LeaderElection.LeaseNamespace = cCtx.String("leader-election-namespace")
+	}
+	if cCtx.IsSet("webhook-port") {
+		cfg.Webhook.Port = cCtx.Int("webhook-port")
+	}
+	if cCtx.IsSet("webhook-cert-dir") {
+		cfg.Webhook.CertDir = cCtx.String("webhook-cert-dir")
+	}
+	if cCtx.IsSet("webhook-cert-name") {
+		cfg.Webhook.CertName = cCtx.String("webhook-cert-name")
+	}
+	if cCtx.IsSet("webhook-key-name") {
+		cfg.Webhook.KeyName = cCtx.String("webhook-key-name")
+	}
+	if cCtx.IsSet("conversion-service") {
+		cfg.Webhook.ConversionService = cCtx.String("conversion-service")
+	}
+	if cCtx.IsSet("conversion-service-port") {
+		cfg.Webhook.ConversionServicePort = cCtx.Int("conversion-service-port")
+	}
+	if cCtx.IsSet("conversion-ca-name") {
+		cfg.Webhook.ConversionCAName = cCtx.String("conversion-ca-name")
+	}
+	if cCtx.IsSet("record-dir") {
+		cfg.Recording.Dir = cCtx.String("record-dir")
+	}
+	if cCtx.IsSet("record-max-files") {
+		cfg.Recording.MaxFiles = cCtx.Int("record-max-files")
+	}
+	return nil
+}
+
+// loadConfig reads the configuration from the defaults, the configuration
+// file, the environment and the flags set, each overriding the previous
+// ones, and validates it.
+func loadConfig(cCtx *cli.Context) (*config.Config, error) {
+	var cfg *config.Config
+	if path := cCtx.String("config"); path != "" {
+		var err error
+		if cfg, err = config.Load(path); err != nil {
+			return nil, err
+		}
+	} else {
+		cfg = config.Default()
+		if err := config.ApplyEnv(cfg, os.LookupEnv); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyFlags(cCtx, cfg); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// labelSelector parses a validated label selector. It returns nil, which
+// selects everything, for an empty selector.
+func labelSelector(selector string) labels.Selector {
+	if selector == "" {
+		return nil
+	}
+	parsed, _ := labels.Parse(selector)
+	return parsed
+}
+
+// hookOptions returns the Server options of the settings that can be changed
+// at runtime.
+func hookOptions(cfg *config.Config) []extensionserver.Option {
+	// Validated by loadConfig.
+	validationMode, _ := extensionserver.ParseValidationMode(cfg.Hooks.ValidationMode)
+	mutators, _ := extensionserver.DefaultRegistry().Select(cfg.Hooks.Mutators)
+	opts := []extensionserver.Option{
+		extensionserver.WithStrictMode(cfg.Fallback.Strict),
+		extensionserver.WithValidationMode(validationMode),
+		extensionserver.WithFetchConcurrency(cfg.Hooks.FetchConcurrency),
+		extensionserver.WithPartialResults(cfg.Hooks.PartialResults),
+		extensionserver.WithEnabledHooks(cfg.Hooks.Enabled),
+		extensionserver.WithPolicyKinds(cfg.Policies.Kinds),
+		extensionserver.WithMutators(mutators...),
+	}
+	if cfg.Fallback.Secret != "" {
+		secretKey, _ := parseNamespacedName(cfg.Fallback.Secret)
+		opts = append(opts, extensionserver.WithFallbackSecret(secretKey))
+	}
+	return opts
+}
+
+// watchConfig applies the settings of the configuration file that can be
+// changed at runtime whenever it changes, until ctx is done. newServer
+// returns a Server for the settings.
+func watchConfig(ctx context.Context, cCtx *cli.Context, logger *slog.Logger, current *config.Config, level *slog.LevelVar,
+	reloadable *extensionserver.Reloadable, newServer func(*config.Config) *extensionserver.Server,
+) {
+	path := cCtx.String("config")
+	err := config.Watch(ctx, logger, path, func() {
+		next, err := loadConfig(cCtx)
+		if err != nil {
+			logger.Error("ignoring invalid configuration change", slog.String("error", err.Error()))
+			return
+		}
+		if sections := config.RestartRequired(current, next); len(sections) > 0 {
+			logger.Warn("configuration changes require a restart", slog.String("sections", strings.Join(sections, ",")))
+		}
+
+		current = config.Reloadable(current, next)
+		parsed, _ := logging.ParseLevel(current.Logging.Level)
+		level.Set(parsed)
+		reloadable.Store(newServer(current))
+		logger.Info("Reloaded the configuration", slog.String("path", path))
+	})
+	if err != nil {
+		logger.Error("failed to watch the configuration file", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/giantswarm/envoy-extension-server-app/internal/certexpiry"
+	"github.com/giantswarm/envoy-extension-server-app/internal/extensionserver"
+	"github.com/giantswarm/envoy-extension-server-app/internal/logging"
+	"github.com/giantswarm/envoy-extension-server-app/internal/recorder"
+	"github.com/giantswarm/envoy-extension-server-app/internal/ticketkeys"
+	"github.com/giantswarm/envoy-extension-server-app/internal/tracing"
+)
+
+// serverFlags are the flags of the server command. Flags that are set
+// override the configuration file and the environment.
+var serverFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:    "config",
+		Usage:   "the path of a configuration file, see the README for its format; it is watched and settings that are safe to change at runtime are applied without a restart",
+		EnvVars: []string{"EXTENSION_SERVER_CONFIG"},
+	},
+	&cli.StringFlag{
+		Name:        "host",
+		Usage:       "the host on which to listen",
+		DefaultText: "0.0.0.0",
+		Value:       "0.0.0.0",
+	},
+	&cli.IntFlag{
+		Name:        "port",
+		Usage:       "the port on which to listen",
+		DefaultText: "5005",
+		Value:       5005,
+	},
+	&cli.StringFlag{
+		Name:        "log-level",
+		Usage:       "the log level, should be one of Debug/Info/Warn/Error",
+		DefaultText: "Info",
+		Value:       "Info",
+	},
+	&cli.StringFlag{
+		Name:        "log-format",
+		Usage:       "the log format, should be one of text/json",
+		DefaultText: string(logging.FormatText),
+		Value:       string(logging.FormatText),
+	},
+	&cli.StringFlag{
+		Name:  "fallback-secret",
+		Usage: "the namespace/name of a TLS Secret served when the Secret referenced by a CertificatePolicy is missing or invalid",
+	},
+	&cli.BoolFlag{
+		Name:  "strict",
+		Usage: "omit the SDS reference of CertificatePolicies whose Secret is missing or invalid instead of serving a fallback certificate",
+	},
+	&cli.BoolFlag{
+		Name:  "session-ticket-key-rotation",
+		Usage: "create the session ticket keys Secrets referenced by CertificatePolicies and rotate their keys",
+	},
+	&cli.DurationFlag{
+		Name:        "session-ticket-key-rotation-interval",
+		Usage:       "the interval at which the encryption key of a session ticket keys Secret is replaced",
+		DefaultText: ticketkeys.DefaultInterval.String(),
+		Value:       ticketkeys.DefaultInterval,
+	},
+	&cli.StringFlag{
+		Name:        "validation-mode",
+		Usage:       "what the hooks return when the xDS they produced is invalid, fail-open returns the resources received from Envoy Gateway unmodified, fail-closed fails the hook",
+		DefaultText: string(extensionserver.ValidationFailOpen),
+		Value:       string(extensionserver.ValidationFailOpen),
+	},
+	&cli.DurationFlag{
+		Name:        "hook-timeout",
+		Usage:       "the time budget of each hook, should be below the extension timeout of Envoy Gateway; 0 disables the budget",
+		DefaultText: extensionserver.DefaultHookTimeout.String(),
+		Value:       extensionserver.DefaultHookTimeout,
+	},
+	&cli.StringSliceFlag{
+		Name:  "hook-timeouts",
+		Usage: "the time budgets of individual hooks as <hook>=<duration>, e.g. PostTranslateModify=10s, overriding --hook-timeout",
+	},
+	&cli.IntFlag{
+		Name:        "fetch-concurrency",
+		Usage:       "the number of Secrets a hook reads from the Kubernetes API concurrently",
+		DefaultText: strconv.Itoa(extensionserver.DefaultFetchConcurrency),
+		Value:       extensionserver.DefaultFetchConcurrency,
+	},
+	&cli.StringSliceFlag{
+		Name:        "mutators",
+		Usage:       "the mutators the hooks run",
+		DefaultText: strings.Join(extensionserver.DefaultRegistry().Names(), ","),
+	},
+	&cli.BoolFlag{
+		Name:  "partial-results",
+		Usage: "leave CertificatePolicies whose Secret was not read within the hook's time budget out of the response instead of failing the hook",
+	},
+	&cli.StringFlag{
+		Name:  "otlp-endpoint",
+		Usage: "the host:port of an OTLP gRPC collector spans of the hooks are exported to; tracing is disabled when empty",
+	},
+	&cli.BoolFlag{
+		Name:  "otlp-insecure",
+		Usage: "connect to the OTLP collector without TLS",
+	},
+	&cli.Float64Flag{
+		Name:        "trace-sample-ratio",
+		Usage:       "the fraction of hook calls traced when Envoy Gateway did not propagate a sampling decision",
+		DefaultText: strconv.FormatFloat(tracing.DefaultSampleRatio, 'g', -1, 64),
+		Value:       tracing.DefaultSampleRatio,
+	},
+	&cli.IntFlag{
+		Name:        "metrics-port",
+		Usage:       "the port on which to expose Prometheus metrics, 0 disables the metrics endpoint",
+		DefaultText: "8080",
+		Value:       8080,
+	},
+	&cli.DurationFlag{
+		Name:        "certificate-expiry-threshold",
+		Usage:       "the remaining validity below which a certificate is reported as expiring soon",
+		DefaultText: certexpiry.DefaultThreshold.String(),
+		Value:       certexpiry.DefaultThreshold,
+	},
+	&cli.DurationFlag{
+		Name:        "certificate-expiry-scan-interval",
+		Usage:       "the interval at which certificates referenced by CertificatePolicies are checked for expiry",
+		DefaultText: certexpiry.DefaultInterval.String(),
+		Value:       certexpiry.DefaultInterval,
+	},
+	&cli.BoolFlag{
+		Name:  "cache",
+		Usage: "read Secrets, CertificatePolicies and Gateways from an informer cache instead of the Kubernetes API",
+	},
+	&cli.StringSliceFlag{
+		Name:  "cache-namespaces",
+		Usage: "the namespaces cached with --cache, Secrets of other namespaces cannot be served; all namespaces are cached when empty",
+	},
+	&cli.StringFlag{
+		Name:  "cache-secret-selector",
+		Usage: "a label selector restricting the Secrets cached with --cache, e.g. gateway.giantswarm.io/managed=true; other Secrets are treated as missing",
+	},
+	&cli.StringFlag{
+		Name:  "cache-policy-selector",
+		Usage: "a label selector restricting the CertificatePolicies cached with --cache",
+	},
+	&cli.BoolFlag{
+		Name:        "leader-elect",
+		Usage:       "elect the replica that writes status updates and Events through a Lease, every replica writes when disabled",
+		DefaultText: "true",
+		Value:       true,
+	},
+	&cli.StringFlag{
+		Name:  "leader-election-namespace",
+		Usage: "the namespace of the leader election Lease, defaults to the namespace of the Pod",
+	},
+	&cli.IntFlag{
+		Name:        "webhook-port",
+		Usage:       "the port on which to serve the CertificatePolicy admission webhooks, 0 disables the webhooks",
+		DefaultText: "0",
+	},
+	&cli.StringFlag{
+		Name:        "webhook-cert-dir",
+		Usage:       "the directory containing the webhook serving certificate and key",
+		DefaultText: "/tmp/k8s-webhook-server/serving-certs",
+		Value:       "/tmp/k8s-webhook-server/serving-certs",
+	},
+	&cli.StringFlag{
+		Name:        "webhook-cert-name",
+		Usage:       "the file name of the webhook serving certificate",
+		DefaultText: "tls.crt",
+		Value:       "tls.crt",
+	},
+	&cli.StringFlag{
+		Name:        "webhook-key-name",
+		Usage:       "the file name of the webhook serving key",
+		DefaultText: "tls.key",
+		Value:       "tls.key",
+	},
+	&cli.StringFlag{
+		Name:  "conversion-service",
+		Usage: "the namespace/name of the Service in front of the webhook server; when set the CertificatePolicy CRD is configured to convert API versions through it",
+	},
+	&cli.IntFlag{
+		Name:        "conversion-service-port",
+		Usage:       "the port of the conversion Service",
+		DefaultText: "443",
+		Value:       443,
+	},
+	&cli.StringFlag{
+		Name:        "conversion-ca-name",
+		Usage:       "the file name in the webhook certificate directory of the CA bundle the API server uses to verify the conversion webhook",
+		DefaultText: "ca.crt",
+		Value:       "ca.crt",
+	},
+	&cli.StringFlag{
+		Name:  "record-dir",
+		Usage: "a directory every hook request and response is recorded to with private keys redacted, for replaying them with the render command; recording is disabled when empty",
+	},
+	&cli.IntFlag{
+		Name:        "record-max-files",
+		Usage:       "the number of recordings kept in --record-dir, older ones are deleted",
+		DefaultText: strconv.Itoa(recorder.DefaultMaxFiles),
+		Value:       recorder.DefaultMaxFiles,
+	},
+}
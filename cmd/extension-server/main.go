@@ -1,31 +1,73 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	pb "github.com/envoyproxy/gateway/proto/extension"
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/urfave/cli/v2"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	"github.com/giantswarm/envoy-extension-server-app/api/v1alpha1"
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+	"github.com/giantswarm/envoy-extension-server-app/internal/certexpiry"
+	"github.com/giantswarm/envoy-extension-server-app/internal/config"
+	"github.com/giantswarm/envoy-extension-server-app/internal/events"
 	"github.com/giantswarm/envoy-extension-server-app/internal/extensionserver"
+	"github.com/giantswarm/envoy-extension-server-app/internal/kube"
+	"github.com/giantswarm/envoy-extension-server-app/internal/leader"
+	"github.com/giantswarm/envoy-extension-server-app/internal/logging"
+	"github.com/giantswarm/envoy-extension-server-app/internal/recorder"
+	"github.com/giantswarm/envoy-extension-server-app/internal/servertls"
+	"github.com/giantswarm/envoy-extension-server-app/internal/ticketkeys"
+	"github.com/giantswarm/envoy-extension-server-app/internal/tracing"
+	"github.com/giantswarm/envoy-extension-server-app/internal/webhook"
 
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	ctrlconfig "sigs.k8s.io/controller-runtime/pkg/client/config"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
+// eventSourceComponent is the component reported on Kubernetes Events.
+const eventSourceComponent = "envoy-extension-server"
+
 var scheme = runtime.NewScheme()
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(gwapiv1.AddToScheme(scheme))
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
 	utilruntime.Must(v1alpha1.AddToScheme(scheme))
+	utilruntime.Must(v1beta1.AddToScheme(scheme))
 }
 
 func main() {
@@ -38,80 +80,334 @@ func main() {
 			{
 				Name:   "server",
 				Usage:  "runs the Extension Server",
-				Before: handleSignals,
 				Action: startExtensionServer,
-				Flags: []cli.Flag{
-					&cli.StringFlag{
-						Name:        "host",
-						Usage:       "the host on which to listen",
-						DefaultText: "0.0.0.0",
-						Value:       "0.0.0.0",
-					},
-					&cli.IntFlag{
-						Name:        "port",
-						Usage:       "the port on which to listen",
-						DefaultText: "5005",
-						Value:       5005,
-					},
-					&cli.StringFlag{
-						Name:        "log-level",
-						Usage:       "the log level, should be one of Debug/Info/Warn/Error",
-						DefaultText: "Debug",
-						Value:       "Debug",
-					},
-				},
+				Flags:  serverFlags,
 			},
+			renderCommand,
 		},
 	}
-	app.Run(os.Args)
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
 
-var grpcServer *grpc.Server
-
-func handleSignals(cCtx *cli.Context) error {
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGQUIT)
-	go func() {
-		for range c {
-			if grpcServer != nil {
-				grpcServer.Stop()
-				os.Exit(0)
-			}
-		}
-	}()
-	return nil
-}
+// gracefulStopTimeout bounds the time in-flight hooks get to finish on
+// shutdown before their connections are closed.
+const gracefulStopTimeout = 10 * time.Second
 
+// startExtensionServer serves the hooks until SIGTERM or SIGINT is received.
+// On shutdown it lets in-flight hooks finish, waits for the background
+// goroutines, among them the leader election releasing its Lease, and
+// flushes the spans before returning.
 func startExtensionServer(cCtx *cli.Context) error {
-	var level slog.Level
-	if err := level.UnmarshalText([]byte(cCtx.String("log-level"))); err != nil {
-		level = slog.LevelInfo
+	cfg, err := loadConfig(cCtx)
+	if err != nil {
+		return err
 	}
-	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
-		Level: level,
-	}))
+
+	// Deferred first, so that the spans of the background goroutines are
+	// flushed after they stopped.
+	var tracerProvider *sdktrace.TracerProvider
+	defer func() { shutdownTracing(tracerProvider) }()
+
+	// Background goroutines stop when ctx is cancelled. The deferred calls
+	// cancel it before waiting for them.
+	var background sync.WaitGroup
+	defer background.Wait()
+	ctx, stop := signal.NotifyContext(cCtx.Context, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	// Validated by loadConfig.
+	level, _ := logging.ParseLevel(cfg.Logging.Level)
+	logFormat, _ := logging.ParseFormat(cfg.Logging.Format)
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(level)
+	logger := logging.New(os.Stderr, logFormat, logLevel)
+	ctrllog.SetLogger(logr.FromSlogHandler(logger.Handler()))
 
 	// Create Kubernetes client
-	cfg, err := config.GetConfig()
+	restConfig, err := ctrlconfig.GetConfig()
 	if err != nil {
 		logger.Error("failed to get Kubernetes config", slog.String("error", err.Error()))
 		return err
 	}
 
-	k8sClient, err := client.New(cfg, client.Options{Scheme: scheme})
+	k8sClient, err := kube.NewClient(ctx, restConfig, scheme, kube.Options{
+		Cache:          cfg.Cache.Enabled,
+		Namespaces:     cfg.Cache.Namespaces,
+		SecretSelector: labelSelector(cfg.Cache.SecretSelector),
+		PolicySelector: labelSelector(cfg.Cache.PolicySelector),
+	})
 	if err != nil {
 		logger.Error("failed to create Kubernetes client", slog.String("error", err.Error()))
 		return err
 	}
 
-	address := net.JoinHostPort(cCtx.String("host"), cCtx.String("port"))
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		logger.Error("failed to create Kubernetes clientset", slog.String("error", err.Error()))
+		return err
+	}
+
+	// elected receives a value whenever this replica starts leading.
+	elected := make(chan struct{}, 1)
+	var gate leader.Gate = leader.Always
+	if election := cfg.LeaderElection; election.Enabled {
+		elector, err := leader.New(logger, clientset, leader.Options{
+			Name:          election.LeaseName,
+			Namespace:     election.LeaseNamespace,
+			LeaseDuration: election.LeaseDuration.Duration,
+			RenewDeadline: election.RenewDeadline.Duration,
+			RetryPeriod:   election.RetryPeriod.Duration,
+			OnChange: func(leading bool) {
+				if leading {
+					notify(elected)
+				}
+			},
+		})
+		if err != nil {
+			logger.Error("failed to set up leader election", slog.String("error", err.Error()))
+			return err
+		}
+		background.Go(func() { elector.Run(ctx) })
+		gate = elector
+	} else {
+		notify(elected)
+	}
+
+	eventRecorder := leader.Recorder(newEventRecorder(clientset), gate)
+	baseOpts := []extensionserver.Option{
+		extensionserver.WithEventRecorder(eventRecorder),
+		extensionserver.WithLeader(gate),
+	}
+
+	var grpcOpts []grpc.ServerOption
+	if cfg.Tracing.Endpoint != "" {
+		tracerProvider, err = tracing.NewTracerProvider(ctx, tracing.Options{
+			Endpoint:    cfg.Tracing.Endpoint,
+			Insecure:    cfg.Tracing.Insecure,
+			SampleRatio: cfg.Tracing.SampleRatio,
+			Version:     cCtx.App.Version,
+		})
+		if err != nil {
+			logger.Error("failed to set up tracing", slog.String("error", err.Error()))
+			return err
+		}
+		logger.Info("Exporting traces", slog.String("endpoint", cfg.Tracing.Endpoint))
+		baseOpts = append(baseOpts, extensionserver.WithTracerProvider(tracerProvider))
+		grpcOpts = append(grpcOpts, grpc.StatsHandler(tracing.ServerHandler(tracerProvider)))
+	}
+	if tlsConfig := cfg.Server.TLS; tlsConfig.CertFile != "" {
+		serverTLS, err := servertls.NewConfig(tlsConfig.CertFile, tlsConfig.KeyFile, tlsConfig.ClientCAFile)
+		if err != nil {
+			logger.Error("failed to set up TLS", slog.String("error", err.Error()))
+			return err
+		}
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(serverTLS)))
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	scanner, err := certexpiry.New(logger, k8sClient, eventRecorder, certexpiry.Options{
+		Threshold:  cfg.Metrics.CertificateExpiry.Threshold.Duration,
+		Interval:   cfg.Metrics.CertificateExpiry.ScanInterval.Duration,
+		Registerer: registry,
+		Leader:     gate,
+	})
+	if err != nil {
+		logger.Error("failed to create certificate expiry scanner", slog.String("error", err.Error()))
+		return err
+	}
+	background.Go(func() { scanner.Run(ctx) })
+
+	if cfg.SessionTicketKeys.Rotate {
+		// The rotated Secrets must match the Secret cache the hooks read them from.
+		secretLabels, err := kube.MatchingLabels(labelSelector(cfg.Cache.SecretSelector))
+		if err != nil {
+			logger.Error("failed to set up session ticket key rotation", slog.String("error", err.Error()))
+			return err
+		}
+		rotator := ticketkeys.New(logger, k8sClient, ticketkeys.Options{
+			Interval: cfg.SessionTicketKeys.RotationInterval.Duration,
+			Keys:     cfg.SessionTicketKeys.Keys,
+			Leader:   gate,
+			Reader:   kube.APIReader(k8sClient),
+			Labels:   secretLabels,
+		})
+		background.Go(func() { rotator.Run(ctx) })
+	}
+
+	if cfg.Metrics.Port != 0 {
+		background.Go(func() {
+			serveMetrics(ctx, logger, net.JoinHostPort(cfg.Server.Host, strconv.Itoa(cfg.Metrics.Port)), registry)
+		})
+	}
+
+	if cfg.Webhook.Port != 0 {
+		webhookServer := webhook.NewServer(webhook.Options{
+			Port:     cfg.Webhook.Port,
+			CertDir:  cfg.Webhook.CertDir,
+			CertName: cfg.Webhook.CertName,
+			KeyName:  cfg.Webhook.KeyName,
+		}, scheme, kube.APIReader(k8sClient))
+		background.Go(func() {
+			if err := webhookServer.Start(ctx); err != nil {
+				logger.Error("webhook server failed", slog.String("error", err.Error()))
+			}
+		})
+
+		if cfg.Webhook.ConversionService != "" {
+			// Only the leader writes the CRD, again whenever it is elected.
+			background.Go(func() {
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case <-elected:
+					}
+					if err := configureConversion(ctx, k8sClient, cfg.Webhook); err != nil {
+						logger.Error("failed to configure CertificatePolicy conversion", slog.String("error", err.Error()))
+					}
+				}
+			})
+		}
+	}
+
+	address := net.JoinHostPort(cfg.Server.Host, strconv.Itoa(cfg.Server.Port))
 	logger.Info("Starting the extension server", slog.String("host", address))
 	lis, err := net.Listen("tcp", address)
 	if err != nil {
 		return err
 	}
-	var opts []grpc.ServerOption
-	grpcServer = grpc.NewServer(opts...)
-	pb.RegisterEnvoyGatewayExtensionServer(grpcServer, extensionserver.New(logger, k8sClient))
+	interceptors := []grpc.UnaryServerInterceptor{
+		logging.UnaryServerInterceptor(logger),
+		extensionserver.TimeoutInterceptor(cfg.Hooks.Timeout.Duration, cfg.HookTimeouts()),
+	}
+	if cfg.Recording.Dir != "" {
+		hookRecorder, err := recorder.New(logger, recorder.Options{
+			Dir:      cfg.Recording.Dir,
+			MaxFiles: cfg.Recording.MaxFiles,
+		})
+		if err != nil {
+			logger.Error("failed to create hook recorder", slog.String("error", err.Error()))
+			return err
+		}
+		logger.Warn("Recording hook requests and responses", slog.String("dir", cfg.Recording.Dir))
+		interceptors = append(interceptors, hookRecorder.UnaryServerInterceptor())
+	}
+
+	newServer := func(cfg *config.Config) *extensionserver.Server {
+		return extensionserver.New(logger, k8sClient, append(slices.Clone(baseOpts), hookOptions(cfg)...)...)
+	}
+	reloadable := extensionserver.NewReloadable(newServer(cfg))
+	if cCtx.String("config") != "" {
+		background.Go(func() { watchConfig(ctx, cCtx, logger, cfg, logLevel, reloadable, newServer) })
+	}
+
+	grpcServer := grpc.NewServer(append(grpcOpts, grpc.ChainUnaryInterceptor(interceptors...))...)
+	pb.RegisterEnvoyGatewayExtensionServer(grpcServer, reloadable)
+	background.Go(func() {
+		<-ctx.Done()
+		logger.Info("Stopping the extension server")
+		stopGRPCServer(logger, grpcServer, gracefulStopTimeout)
+	})
 	return grpcServer.Serve(lis)
 }
+
+// notify sends to ch unless a value is pending already.
+func notify(ch chan<- struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// stopGRPCServer lets in-flight calls finish and closes their connections
+// when they did not finish within timeout.
+func stopGRPCServer(logger *slog.Logger, server *grpc.Server, timeout time.Duration) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-stopped:
+	case <-timer.C:
+		logger.Warn("in-flight hooks did not finish in time, closing their connections", slog.Duration("timeout", timeout))
+		server.Stop()
+		<-stopped
+	}
+}
+
+// shutdownTracing flushes the spans not exported yet. tracerProvider is nil
+// when tracing is disabled.
+func shutdownTracing(tracerProvider *sdktrace.TracerProvider) {
+	if tracerProvider == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = tracerProvider.Shutdown(ctx)
+}
+
+// parseNamespacedName parses a namespace/name reference.
+func parseNamespacedName(value string) (types.NamespacedName, error) {
+	namespace, name, ok := strings.Cut(value, "/")
+	if !ok || namespace == "" || name == "" {
+		return types.NamespacedName{}, fmt.Errorf("%q is not in namespace/name format", value)
+	}
+	return types.NamespacedName{Namespace: namespace, Name: name}, nil
+}
+
+// configureConversion points the CertificatePolicy CRD at the conversion
+// webhook behind the configured Service.
+func configureConversion(ctx context.Context, k8sClient client.Client, cfg config.Webhook) error {
+	service, err := parseNamespacedName(cfg.ConversionService)
+	if err != nil {
+		return fmt.Errorf("invalid conversion service: %w", err)
+	}
+	caBundle, err := os.ReadFile(filepath.Join(cfg.CertDir, cfg.ConversionCAName))
+	if err != nil {
+		return fmt.Errorf("failed to read conversion CA bundle: %w", err)
+	}
+	return webhook.ConfigureConversion(ctx, k8sClient, service, int32(cfg.ConversionServicePort), caBundle)
+}
+
+// newEventRecorder creates an event recorder that publishes deduplicated and
+// rate limited Kubernetes Events through the API server.
+func newEventRecorder(clientset kubernetes.Interface) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
+	recorder := broadcaster.NewRecorder(scheme, corev1.EventSource{Component: eventSourceComponent})
+	return events.NewRecorder(recorder, events.Options{})
+}
+
+// serveMetrics exposes the metrics of the given registry on /metrics until
+// ctx is done.
+func serveMetrics(ctx context.Context, logger *slog.Logger, address string, registry *prometheus.Registry) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	server := &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+
+	logger.Info("Starting the metrics server", slog.String("host", address))
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("metrics server failed", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/giantswarm/envoy-extension-server-app/internal/extensionserver"
+	"github.com/giantswarm/envoy-extension-server-app/internal/logging"
+	"github.com/giantswarm/envoy-extension-server-app/internal/recorder"
+	"github.com/giantswarm/envoy-extension-server-app/internal/render"
+)
+
+// renderCommand runs a hook against a request read from a file and prints
+// the resulting xDS without a cluster.
+var renderCommand = &cli.Command{
+	Name:      "render",
+	Usage:     "runs a hook against a recorded request and prints the resulting xDS",
+	UsageText: "extension-server render --hook PostTranslateModify --request request.yaml --manifest policies.yaml",
+	Action:    renderHook,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "hook",
+			Usage: fmt.Sprintf("the hook to run, one of %v", render.Hooks),
+		},
+		&cli.StringFlag{
+			Name:  "request",
+			Usage: "the file holding the hook request as protojson encoded JSON or YAML, - reads from stdin",
+		},
+		&cli.StringFlag{
+			Name:  "recording",
+			Usage: "a file written by --record-dir, replayed in place of --hook and --request, - reads from stdin",
+		},
+		&cli.StringSliceFlag{
+			Name:  "manifest",
+			Usage: "a YAML manifest with CertificatePolicies, Secrets and Gateways used in place of a cluster, may be repeated",
+		},
+		&cli.StringFlag{
+			Name:        "output",
+			Usage:       "the output format, json or yaml",
+			DefaultText: string(render.FormatJSON),
+			Value:       string(render.FormatJSON),
+		},
+		&cli.StringFlag{
+			Name:  "fallback-secret",
+			Usage: "the namespace/name of a TLS Secret served when the Secret referenced by a CertificatePolicy is missing or invalid",
+		},
+		&cli.BoolFlag{
+			Name:  "strict",
+			Usage: "omit the SDS reference of CertificatePolicies whose Secret is missing or invalid instead of serving a fallback certificate",
+		},
+		&cli.StringFlag{
+			Name:        "log-level",
+			Usage:       "the level of the hook logs written to stderr, should be one of Debug/Info/Warn/Error",
+			DefaultText: "Warn",
+			Value:       "Warn",
+		},
+	},
+}
+
+func renderHook(cCtx *cli.Context) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cCtx.String("log-level"))); err != nil {
+		level = slog.LevelWarn
+	}
+	logger := logging.New(cCtx.App.ErrWriter, logging.FormatText, level)
+
+	hook, request, err := readRequest(cCtx)
+	if err != nil {
+		return err
+	}
+	var manifests [][]byte
+	for _, path := range cCtx.StringSlice("manifest") {
+		manifest, err := readInput(cCtx.App.Reader, path)
+		if err != nil {
+			return err
+		}
+		manifests = append(manifests, manifest)
+	}
+
+	serverOpts := []extensionserver.Option{
+		extensionserver.WithStrictMode(cCtx.Bool("strict")),
+	}
+	if fallbackSecret := cCtx.String("fallback-secret"); fallbackSecret != "" {
+		secretKey, err := parseNamespacedName(fallbackSecret)
+		if err != nil {
+			return fmt.Errorf("invalid fallback secret: %w", err)
+		}
+		serverOpts = append(serverOpts, extensionserver.WithFallbackSecret(secretKey))
+	}
+
+	out, err := render.Render(cCtx.Context, render.Options{
+		Hook:          hook,
+		Request:       request,
+		Manifests:     manifests,
+		Format:        render.Format(cCtx.String("output")),
+		Logger:        logger,
+		ServerOptions: serverOpts,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = cCtx.App.Writer.Write(out)
+	return err
+}
+
+// readRequest returns the hook and request to render, either from a
+// recording or from the --hook and --request flags.
+func readRequest(cCtx *cli.Context) (render.Hook, []byte, error) {
+	recordingPath := cCtx.String("recording")
+	if recordingPath == "" {
+		if cCtx.String("hook") == "" || cCtx.String("request") == "" {
+			return "", nil, errors.New("either --recording or both --hook and --request must be set")
+		}
+		request, err := readInput(cCtx.App.Reader, cCtx.String("request"))
+		if err != nil {
+			return "", nil, err
+		}
+		return render.Hook(cCtx.String("hook")), request, nil
+	}
+
+	if cCtx.IsSet("hook") || cCtx.IsSet("request") {
+		return "", nil, errors.New("--recording can not be combined with --hook or --request")
+	}
+	data, err := readInput(cCtx.App.Reader, recordingPath)
+	if err != nil {
+		return "", nil, err
+	}
+	recording, err := recorder.ReadRecording(data)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid recording %s: %w", recordingPath, err)
+	}
+	return render.Hook(recording.Hook()), recording.Request, nil
+}
+
+// readInput reads a file, or stdin when path is "-".
+func readInput(stdin io.Reader, path string) ([]byte, error) {
+	if path == "-" {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stdin: %w", err)
+		}
+		return data, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return data, nil
+}
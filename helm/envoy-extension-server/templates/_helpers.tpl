@@ -61,3 +61,53 @@ Create the name of the service account to use
 {{- default "default" .Values.serviceAccount.name }}
 {{- end }}
 {{- end }}
+
+{{/*
+Rules of the namespaced resources the server reads and writes. They are
+granted cluster-wide, or only in cache.namespaces when the cache is scoped.
+*/}}
+{{- define "extension-server.namespacedRules" -}}
+- apiGroups:
+  - ""
+  resources:
+  - secrets
+  verbs:
+  - get
+  - list
+  - watch
+  {{- if .Values.sessionTicketKeys.rotate }}
+  - create
+  - update
+  {{- end }}
+- apiGroups:
+  - gateway.giantswarm.io
+  resources:
+  - certificatepolicies
+  verbs:
+  - get
+  - list
+  - watch
+- apiGroups:
+  - gateway.giantswarm.io
+  resources:
+  - certificatepolicies/status
+  verbs:
+  - update
+- apiGroups:
+  - ""
+  resources:
+  - events
+  verbs:
+  - create
+  - patch
+- apiGroups:
+  - gateway.networking.k8s.io
+  resources:
+  - gateways
+  verbs:
+  - get
+  {{- if .Values.cache.enabled }}
+  - list
+  - watch
+  {{- end }}
+{{- end }}
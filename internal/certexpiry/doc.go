@@ -0,0 +1,5 @@
+// Package certexpiry periodically scans the Secrets referenced by
+// CertificatePolicies and reports certificates that are about to expire via
+// Prometheus metrics, Kubernetes Events and a CertificateExpiringSoon status
+// condition on the policy.
+package certexpiry
@@ -0,0 +1,19 @@
+package certexpiry
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// expiryLabels are the labels of the certificate expiry gauge.
+var expiryLabels = []string{"namespace", "policy", "secret"}
+
+// newExpiryGauge creates the gauge exposing the NotAfter timestamp of the
+// certificates referenced by each CertificatePolicy.
+func newExpiryGauge() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "envoy_extension_server",
+		Subsystem: "certificate",
+		Name:      "expiry_timestamp_seconds",
+		Help:      "NotAfter timestamp of a certificate referenced by a CertificatePolicy, in seconds since the Unix epoch.",
+	}, expiryLabels)
+}
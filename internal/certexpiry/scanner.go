@@ -0,0 +1,278 @@
+package certexpiry
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+	"github.com/giantswarm/envoy-extension-server-app/internal/leader"
+)
+
+const (
+	// DefaultThreshold is the remaining validity below which a certificate is
+	// reported as expiring soon.
+	DefaultThreshold = 14 * 24 * time.Hour
+
+	// DefaultInterval is the default time between two scans.
+	DefaultInterval = time.Hour
+)
+
+// Options configures a Scanner.
+type Options struct {
+	// Threshold is the remaining validity below which a certificate is
+	// reported as expiring soon. Defaults to DefaultThreshold.
+	Threshold time.Duration
+
+	// Interval is the time between two scans. Defaults to DefaultInterval.
+	Interval time.Duration
+
+	// Clock is used to determine the current time. Defaults to the real clock.
+	Clock clock.WithTicker
+
+	// Registerer is used to register the expiry gauge. When nil the gauge
+	// is not registered.
+	Registerer prometheus.Registerer
+
+	// Leader gates the status updates, so that only one replica writes
+	// them. The metrics are reported by every replica. Defaults to
+	// leader.Always.
+	Leader leader.Gate
+}
+
+// Scanner periodically checks the certificates referenced by all
+// CertificatePolicies in the cluster.
+type Scanner struct {
+	log       *slog.Logger
+	client    client.Client
+	recorder  record.EventRecorder
+	clock     clock.WithTicker
+	threshold time.Duration
+	interval  time.Duration
+	leader    leader.Gate
+
+	expiry   *prometheus.GaugeVec
+	reported map[reportKey]prometheus.Labels
+}
+
+// reportKey identifies the gauge series of a Secret referenced by a policy.
+type reportKey struct {
+	policy types.NamespacedName
+	secret string
+}
+
+// New creates a Scanner and registers its metrics.
+func New(logger *slog.Logger, client client.Client, recorder record.EventRecorder, opts Options) (*Scanner, error) {
+	s := &Scanner{
+		log:       logger,
+		client:    client,
+		recorder:  recorder,
+		clock:     opts.Clock,
+		threshold: opts.Threshold,
+		interval:  opts.Interval,
+		leader:    opts.Leader,
+		expiry:    newExpiryGauge(),
+		reported:  map[reportKey]prometheus.Labels{},
+	}
+	if s.clock == nil {
+		s.clock = clock.RealClock{}
+	}
+	if s.leader == nil {
+		s.leader = leader.Always
+	}
+	if s.threshold <= 0 {
+		s.threshold = DefaultThreshold
+	}
+	if s.interval <= 0 {
+		s.interval = DefaultInterval
+	}
+	if opts.Registerer != nil {
+		if err := opts.Registerer.Register(s.expiry); err != nil {
+			return nil, fmt.Errorf("failed to register certificate expiry gauge: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// Run scans immediately and then on every interval until ctx is cancelled.
+func (s *Scanner) Run(ctx context.Context) {
+	ticker := s.clock.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Scan(ctx); err != nil {
+			s.log.Error("certificate expiry scan failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// Scan checks the certificates of every CertificatePolicy once. Errors for
+// individual policies do not stop the scan and are returned joined.
+func (s *Scanner) Scan(ctx context.Context) error {
+	var policies v1beta1.CertificatePolicyList
+	if err := s.client.List(ctx, &policies); err != nil {
+		return fmt.Errorf("failed to list CertificatePolicies: %w", err)
+	}
+
+	var errs []error
+	seen := map[reportKey]prometheus.Labels{}
+	for i := range policies.Items {
+		policy := &policies.Items[i]
+		reported, err := s.checkPolicy(ctx, policy)
+		for _, labels := range reported {
+			seen[reportKey{policy: client.ObjectKeyFromObject(policy), secret: labels["secret"]}] = labels
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("policy %s/%s: %w", policy.Namespace, policy.Name, err))
+		}
+	}
+
+	// Drop series of policies that were deleted, of Secrets no longer
+	// referenced and of Secrets that could not be read.
+	for key, labels := range s.reported {
+		if _, ok := seen[key]; !ok {
+			s.expiry.Delete(labels)
+		}
+	}
+	s.reported = seen
+
+	return errors.Join(errs...)
+}
+
+// checkPolicy records the expiry of every certificate referenced by the
+// policy, sets the condition from the one expiring first and returns the
+// labels of the reported gauge series. Secrets that cannot be read do not
+// stop the others from being checked.
+func (s *Scanner) checkPolicy(ctx context.Context, policy *v1beta1.CertificatePolicy) ([]prometheus.Labels, error) {
+	var (
+		reported []prometheus.Labels
+		errs     []error
+		soonest  string
+		notAfter time.Time
+	)
+	for _, name := range policySecrets(policy) {
+		expiry, err := s.secretNotAfter(ctx, types.NamespacedName{Namespace: policy.Namespace, Name: name})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		labels := prometheus.Labels{
+			"namespace": policy.Namespace,
+			"policy":    policy.Name,
+			"secret":    name,
+		}
+		s.expiry.With(labels).Set(float64(expiry.Unix()))
+		reported = append(reported, labels)
+
+		if soonest == "" || expiry.Before(notAfter) {
+			soonest, notAfter = name, expiry
+		}
+	}
+	if soonest == "" {
+		return reported, errors.Join(errs...)
+	}
+
+	condition := s.expiryCondition(policy, soonest, notAfter)
+	if condition.Status == metav1.ConditionTrue {
+		s.recorder.Event(policy, corev1.EventTypeWarning, v1beta1.CertificateExpiringSoonConditionType, condition.Message)
+	}
+
+	if s.leader.IsLeader() && meta.SetStatusCondition(&policy.Status.Conditions, condition) {
+		if err := s.client.Status().Update(ctx, policy); err != nil {
+			errs = append(errs, fmt.Errorf("failed to update status: %w", err))
+		}
+	}
+
+	return reported, errors.Join(errs...)
+}
+
+// policySecrets returns the names of the Secrets whose certificates the
+// policy serves: SecretRef, AdditionalSecretRefs and FallbackSecretRef.
+func policySecrets(policy *v1beta1.CertificatePolicy) []string {
+	names := []string{policy.Spec.SecretRef.Name}
+	for _, ref := range policy.Spec.AdditionalSecretRefs {
+		names = append(names, ref.Name)
+	}
+	if ref := policy.Spec.FallbackSecretRef; ref != nil {
+		names = append(names, ref.Name)
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// secretNotAfter returns the NotAfter time of the certificate in a Secret.
+func (s *Scanner) secretNotAfter(ctx context.Context, key types.NamespacedName) (time.Time, error) {
+	var secret corev1.Secret
+	if err := s.client.Get(ctx, key, &secret); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get secret %s: %w", key, err)
+	}
+	notAfter, err := certificateNotAfter(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("secret %s: %w", key, err)
+	}
+	return notAfter, nil
+}
+
+// expiryCondition returns the CertificateExpiringSoon condition for the
+// certificate in secretName, valid until notAfter.
+func (s *Scanner) expiryCondition(policy *v1beta1.CertificatePolicy, secretName string, notAfter time.Time) metav1.Condition {
+	now := s.clock.Now()
+	condition := metav1.Condition{
+		Type:               v1beta1.CertificateExpiringSoonConditionType,
+		ObservedGeneration: policy.Generation,
+		LastTransitionTime: metav1.NewTime(now),
+	}
+
+	switch remaining := notAfter.Sub(now); {
+	case remaining <= 0:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = v1beta1.CertificateExpiredReason
+		condition.Message = fmt.Sprintf("Certificate in Secret %s expired at %s", secretName, notAfter.UTC().Format(time.RFC3339))
+	case remaining < s.threshold:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = v1beta1.CertificateExpiringSoonReason
+		condition.Message = fmt.Sprintf("Certificate in Secret %s expires at %s", secretName, notAfter.UTC().Format(time.RFC3339))
+	default:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = v1beta1.CertificateValidReason
+		condition.Message = fmt.Sprintf("Certificate in Secret %s is valid until %s", secretName, notAfter.UTC().Format(time.RFC3339))
+	}
+	return condition
+}
+
+// certificateNotAfter returns the NotAfter time of the leaf certificate, which
+// is the first certificate of a PEM encoded chain.
+func certificateNotAfter(certChain []byte) (time.Time, error) {
+	for block, rest := pem.Decode(certChain); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		return cert.NotAfter, nil
+	}
+	return time.Time{}, fmt.Errorf("no PEM encoded certificate found in %s", corev1.TLSCertKey)
+}
@@ -0,0 +1,392 @@
+package certexpiry
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"log/slog"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+var now = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name       string
+		notAfter   time.Time
+		wantStatus metav1.ConditionStatus
+		wantReason string
+		wantEvent  bool
+	}{
+		{
+			name:       "valid certificate",
+			notAfter:   now.Add(30 * 24 * time.Hour),
+			wantStatus: metav1.ConditionFalse,
+			wantReason: v1beta1.CertificateValidReason,
+		},
+		{
+			name:       "certificate expiring soon",
+			notAfter:   now.Add(24 * time.Hour),
+			wantStatus: metav1.ConditionTrue,
+			wantReason: v1beta1.CertificateExpiringSoonReason,
+			wantEvent:  true,
+		},
+		{
+			name:       "expired certificate",
+			notAfter:   now.Add(-time.Hour),
+			wantStatus: metav1.ConditionTrue,
+			wantReason: v1beta1.CertificateExpiredReason,
+			wantEvent:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := createPolicy("policy-1", "secret-1")
+			k8sClient := newFakeClient(t, policy, createSecret(t, "secret-1", tt.notAfter))
+			recorder := record.NewFakeRecorder(10)
+			scanner := newTestScanner(t, k8sClient, recorder, nil)
+
+			if err := scanner.Scan(context.Background()); err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+
+			got := getPolicy(t, k8sClient, "policy-1")
+			condition := meta.FindStatusCondition(got.Status.Conditions, v1beta1.CertificateExpiringSoonConditionType)
+			if condition == nil {
+				t.Fatal("CertificateExpiringSoon condition not set")
+			}
+			if condition.Status != tt.wantStatus {
+				t.Errorf("condition status = %q, want %q", condition.Status, tt.wantStatus)
+			}
+			if condition.Reason != tt.wantReason {
+				t.Errorf("condition reason = %q, want %q", condition.Reason, tt.wantReason)
+			}
+
+			gotEvent := len(recorder.Events) > 0
+			if gotEvent != tt.wantEvent {
+				t.Errorf("event emitted = %v, want %v", gotEvent, tt.wantEvent)
+			}
+
+			gotExpiry := testutil.ToFloat64(scanner.expiry.WithLabelValues("default", "policy-1", "secret-1"))
+			if gotExpiry != float64(tt.notAfter.Unix()) {
+				t.Errorf("expiry gauge = %v, want %v", gotExpiry, tt.notAfter.Unix())
+			}
+		})
+	}
+}
+
+func TestScanTransitionsAsTimePasses(t *testing.T) {
+	policy := createPolicy("policy-1", "secret-1")
+	k8sClient := newFakeClient(t, policy, createSecret(t, "secret-1", now.Add(15*24*time.Hour)))
+	fakeClock := clocktesting.NewFakeClock(now)
+	scanner := newTestScanner(t, k8sClient, record.NewFakeRecorder(10), fakeClock)
+
+	if err := scanner.Scan(context.Background()); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if meta.IsStatusConditionTrue(getPolicy(t, k8sClient, "policy-1").Status.Conditions, v1beta1.CertificateExpiringSoonConditionType) {
+		t.Fatal("certificate reported as expiring soon before the threshold")
+	}
+
+	fakeClock.Step(2 * 24 * time.Hour)
+	if err := scanner.Scan(context.Background()); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	condition := meta.FindStatusCondition(getPolicy(t, k8sClient, "policy-1").Status.Conditions, v1beta1.CertificateExpiringSoonConditionType)
+	if condition.Status != metav1.ConditionTrue {
+		t.Fatal("certificate not reported as expiring soon after the threshold")
+	}
+	if !condition.LastTransitionTime.Time.Equal(fakeClock.Now()) {
+		t.Errorf("LastTransitionTime = %v, want %v", condition.LastTransitionTime.Time, fakeClock.Now())
+	}
+}
+
+func TestScanLeavesStatusToTheLeader(t *testing.T) {
+	policy := createPolicy("policy-1", "secret-1")
+	notAfter := now.Add(24 * time.Hour)
+	k8sClient := newFakeClient(t, policy, createSecret(t, "secret-1", notAfter))
+	scanner, err := New(slog.New(slog.NewTextHandler(os.Stdout, nil)), k8sClient, record.NewFakeRecorder(10), Options{
+		Clock:  clocktesting.NewFakeClock(now),
+		Leader: follower{},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err := scanner.Scan(context.Background()); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if conditions := getPolicy(t, k8sClient, "policy-1").Status.Conditions; len(conditions) != 0 {
+		t.Errorf("conditions = %v, want none on a follower", conditions)
+	}
+	if got := testutil.ToFloat64(scanner.expiry.WithLabelValues("default", "policy-1", "secret-1")); got != float64(notAfter.Unix()) {
+		t.Errorf("expiry gauge = %v, want %v", got, notAfter.Unix())
+	}
+}
+
+// follower is a leader.Gate of a replica that is not the leader.
+type follower struct{}
+
+func (follower) IsLeader() bool { return false }
+
+func TestScanErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret *corev1.Secret
+	}{
+		{
+			name:   "missing secret",
+			secret: nil,
+		},
+		{
+			name: "invalid certificate",
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "secret-1", Namespace: "default"},
+				Data:       map[string][]byte{corev1.TLSCertKey: []byte("not a certificate")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := []client.Object{
+				createPolicy("policy-1", "secret-1"),
+				createPolicy("policy-2", "secret-2"),
+				createSecret(t, "secret-2", now.Add(time.Hour)),
+			}
+			if tt.secret != nil {
+				objs = append(objs, tt.secret)
+			}
+			k8sClient := newFakeClient(t, objs...)
+			scanner := newTestScanner(t, k8sClient, record.NewFakeRecorder(10), nil)
+
+			err := scanner.Scan(context.Background())
+			if err == nil || !strings.Contains(err.Error(), "policy-1") {
+				t.Fatalf("Scan() error = %v, want an error for policy-1", err)
+			}
+
+			// The failure of one policy must not prevent the others from being checked.
+			if !meta.IsStatusConditionTrue(getPolicy(t, k8sClient, "policy-2").Status.Conditions, v1beta1.CertificateExpiringSoonConditionType) {
+				t.Error("policy-2 was not checked")
+			}
+		})
+	}
+}
+
+func TestScanChecksEverySecret(t *testing.T) {
+	policy := createPolicy("policy-1", "secret-1")
+	policy.Spec.AdditionalSecretRefs = []v1beta1.SecretReference{{Name: "secret-2"}}
+	policy.Spec.FallbackSecretRef = &v1beta1.SecretReference{Name: "fallback"}
+	expiries := map[string]time.Time{
+		"secret-1": now.Add(30 * 24 * time.Hour),
+		"secret-2": now.Add(24 * time.Hour),
+		"fallback": now.Add(60 * 24 * time.Hour),
+	}
+	objs := []client.Object{policy}
+	for name, notAfter := range expiries {
+		objs = append(objs, createSecret(t, name, notAfter))
+	}
+	k8sClient := newFakeClient(t, objs...)
+	scanner := newTestScanner(t, k8sClient, record.NewFakeRecorder(10), nil)
+
+	if err := scanner.Scan(context.Background()); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	for name, notAfter := range expiries {
+		if got := testutil.ToFloat64(scanner.expiry.WithLabelValues("default", "policy-1", name)); got != float64(notAfter.Unix()) {
+			t.Errorf("expiry gauge of %s = %v, want %v", name, got, notAfter.Unix())
+		}
+	}
+	condition := meta.FindStatusCondition(getPolicy(t, k8sClient, "policy-1").Status.Conditions, v1beta1.CertificateExpiringSoonConditionType)
+	if condition == nil || condition.Status != metav1.ConditionTrue {
+		t.Fatalf("condition = %v, want the soonest expiry reported", condition)
+	}
+	if !strings.Contains(condition.Message, "secret-2") {
+		t.Errorf("condition message = %q, want it to name secret-2", condition.Message)
+	}
+}
+
+func TestScanRemovesStaleSeries(t *testing.T) {
+	policy := createPolicy("policy-1", "secret-1")
+	policy.Spec.AdditionalSecretRefs = []v1beta1.SecretReference{{Name: "secret-2"}}
+	k8sClient := newFakeClient(t, policy, createSecret(t, "secret-1", now.Add(time.Hour)), createSecret(t, "secret-2", now.Add(time.Hour)))
+	scanner := newTestScanner(t, k8sClient, record.NewFakeRecorder(10), nil)
+
+	if err := scanner.Scan(context.Background()); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if got := testutil.CollectAndCount(scanner.expiry); got != 2 {
+		t.Fatalf("got %d series, want 2", got)
+	}
+
+	policy = getPolicy(t, k8sClient, "policy-1")
+	policy.Spec.AdditionalSecretRefs = nil
+	if err := k8sClient.Update(context.Background(), policy); err != nil {
+		t.Fatalf("failed to update policy: %v", err)
+	}
+	if err := scanner.Scan(context.Background()); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if got := testutil.CollectAndCount(scanner.expiry); got != 1 {
+		t.Errorf("got %d series after secret-2 was removed from the policy, want 1", got)
+	}
+
+	if err := k8sClient.Delete(context.Background(), getPolicy(t, k8sClient, "policy-1")); err != nil {
+		t.Fatalf("failed to delete policy: %v", err)
+	}
+	if err := scanner.Scan(context.Background()); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if got := testutil.CollectAndCount(scanner.expiry); got != 0 {
+		t.Errorf("got %d series after the policy was deleted, want 0", got)
+	}
+}
+
+func TestRunScansUntilCancelled(t *testing.T) {
+	policy := createPolicy("policy-1", "secret-1")
+	k8sClient := newFakeClient(t, policy, createSecret(t, "secret-1", now.Add(time.Hour)))
+	scanner := newTestScanner(t, k8sClient, record.NewFakeRecorder(10), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	scanner.Run(ctx)
+
+	if len(getPolicy(t, k8sClient, "policy-1").Status.Conditions) == 0 {
+		t.Error("Run() returned without scanning")
+	}
+}
+
+func TestNewRegistersGauge(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	if _, err := New(slog.New(slog.NewTextHandler(os.Stdout, nil)), nil, nil, Options{Registerer: registry}); err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := New(slog.New(slog.NewTextHandler(os.Stdout, nil)), nil, nil, Options{Registerer: registry}); err == nil {
+		t.Error("New() expected an error when registering the gauge twice")
+	}
+}
+
+func TestCertificateNotAfter(t *testing.T) {
+	want := now.Add(time.Hour)
+	secret := createSecret(t, "secret-1", want)
+
+	got, err := certificateNotAfter(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		t.Fatalf("certificateNotAfter() error = %v", err)
+	}
+	if !got.Equal(want) {
+		t.Errorf("certificateNotAfter() = %v, want %v", got, want)
+	}
+
+	if _, err := certificateNotAfter([]byte("garbage")); err == nil {
+		t.Error("certificateNotAfter() expected an error for non-PEM input")
+	}
+	invalid := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("garbage")})
+	if _, err := certificateNotAfter(invalid); err == nil {
+		t.Error("certificateNotAfter() expected an error for an invalid certificate")
+	}
+}
+
+func newTestScanner(t *testing.T, k8sClient client.Client, recorder record.EventRecorder, fakeClock *clocktesting.FakeClock) *Scanner {
+	t.Helper()
+	if fakeClock == nil {
+		fakeClock = clocktesting.NewFakeClock(now)
+	}
+	scanner, err := New(slog.New(slog.NewTextHandler(os.Stdout, nil)), k8sClient, recorder, Options{
+		Threshold: 14 * 24 * time.Hour,
+		Clock:     fakeClock,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return scanner
+}
+
+func newFakeClient(t *testing.T, objs ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add client-go scheme: %v", err)
+	}
+	if err := v1beta1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add v1alpha1 scheme: %v", err)
+	}
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(&v1beta1.CertificatePolicy{}).
+		Build()
+}
+
+func getPolicy(t *testing.T, k8sClient client.Client, name string) *v1beta1.CertificatePolicy {
+	t.Helper()
+	var policy v1beta1.CertificatePolicy
+	if err := k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: name}, &policy); err != nil {
+		t.Fatalf("failed to get policy: %v", err)
+	}
+	return &policy
+}
+
+func createPolicy(name, secretName string) *v1beta1.CertificatePolicy {
+	return &v1beta1.CertificatePolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: v1beta1.CertificatePolicySpec{
+			SecretRef: v1beta1.SecretReference{Name: secretName},
+		},
+	}
+}
+
+func createSecret(t *testing.T, name string, notAfter time.Time) *corev1.Secret {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		},
+	}
+}
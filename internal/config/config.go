@@ -0,0 +1,364 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/envoy-extension-server-app/internal/certexpiry"
+	"github.com/giantswarm/envoy-extension-server-app/internal/extensionserver"
+	"github.com/giantswarm/envoy-extension-server-app/internal/leader"
+	"github.com/giantswarm/envoy-extension-server-app/internal/logging"
+	"github.com/giantswarm/envoy-extension-server-app/internal/recorder"
+	"github.com/giantswarm/envoy-extension-server-app/internal/ticketkeys"
+	"github.com/giantswarm/envoy-extension-server-app/internal/tracing"
+)
+
+const (
+	// APIVersion is the version of the configuration file format.
+	APIVersion = "extensionserver.giantswarm.io/v1alpha1"
+
+	// Kind is the kind of the configuration file.
+	Kind = "ExtensionServerConfig"
+)
+
+// Config is the configuration of the extension server.
+type Config struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+
+	Server            Server            `json:"server"`
+	Logging           Logging           `json:"logging"`
+	Hooks             Hooks             `json:"hooks"`
+	Policies          Policies          `json:"policies"`
+	Fallback          Fallback          `json:"fallback"`
+	SessionTicketKeys SessionTicketKeys `json:"sessionTicketKeys"`
+	Cache             Cache             `json:"cache"`
+	LeaderElection    LeaderElection    `json:"leaderElection"`
+	Metrics           Metrics           `json:"metrics"`
+	Tracing           Tracing           `json:"tracing"`
+	Recording         Recording         `json:"recording"`
+	Webhook           Webhook           `json:"webhook"`
+}
+
+// Server configures the gRPC listener the hooks are served on.
+type Server struct {
+	// Host is the address to listen on.
+	Host string `json:"host"`
+
+	// Port is the port to listen on.
+	Port int `json:"port"`
+
+	// TLS enables TLS on the listener.
+	TLS TLS `json:"tls"`
+}
+
+// TLS configures the serving certificate of the gRPC listener. The
+// certificate is re-read when its files change.
+type TLS struct {
+	// CertFile is the path of the PEM encoded serving certificate. TLS is
+	// disabled when empty.
+	CertFile string `json:"certFile"`
+
+	// KeyFile is the path of the PEM encoded private key.
+	KeyFile string `json:"keyFile"`
+
+	// ClientCAFile is the path of a PEM encoded CA bundle. When set, Envoy
+	// Gateway must present a client certificate issued by one of the CAs.
+	ClientCAFile string `json:"clientCAFile"`
+}
+
+// Logging configures the logs.
+type Logging struct {
+	// Level is one of debug, info, warn or error. It can be changed at
+	// runtime.
+	Level string `json:"level"`
+
+	// Format is one of text or json.
+	Format string `json:"format"`
+}
+
+// Hooks configures the hooks.
+type Hooks struct {
+	// Enabled lists the hooks that modify the resources they receive. The
+	// other hooks return them unchanged. It can be changed at runtime.
+	Enabled []string `json:"enabled"`
+
+	// Timeout is the time budget of each hook. Zero disables it.
+	Timeout metav1.Duration `json:"timeout"`
+
+	// Timeouts overrides the time budget of individual hooks.
+	Timeouts map[string]metav1.Duration `json:"timeouts,omitempty"`
+
+	// FetchConcurrency is the number of Secrets a hook reads concurrently.
+	// It can be changed at runtime.
+	FetchConcurrency int `json:"fetchConcurrency"`
+
+	// PartialResults leaves policies whose Secret was not read within the
+	// time budget out of the response instead of failing the hook. It can be
+	// changed at runtime.
+	PartialResults bool `json:"partialResults"`
+
+	// ValidationMode is what the hooks return when the xDS they produced is
+	// invalid. It can be changed at runtime.
+	ValidationMode string `json:"validationMode"`
+
+	// Mutators lists the mutators the hooks run, e.g. certificates,
+	// tls-params, session-ticket-keys or alpn. It can be changed at runtime.
+	Mutators []string `json:"mutators"`
+}
+
+// Policies configures which extension resources the hooks process.
+type Policies struct {
+	// Kinds lists the kinds of extension resources processed. It can be
+	// changed at runtime.
+	Kinds []string `json:"kinds"`
+}
+
+// Fallback configures what is served when the Secret of a policy is missing
+// or invalid. It can be changed at runtime.
+type Fallback struct {
+	// Secret is the namespace/name of a TLS Secret served in place of the
+	// Secret of a policy without fallback of its own.
+	Secret string `json:"secret"`
+
+	// Strict omits the SDS reference instead of serving a fallback.
+	Strict bool `json:"strict"`
+}
+
+// SessionTicketKeys configures the rotation of the session ticket keys
+// Secrets CertificatePolicies refer to.
+type SessionTicketKeys struct {
+	// Rotate creates the missing Secrets and periodically replaces their
+	// encryption key. Only Secrets created by the server or annotated as
+	// rotated are rotated.
+	Rotate bool `json:"rotate"`
+
+	// RotationInterval is the time between two rotations of a Secret.
+	RotationInterval metav1.Duration `json:"rotationInterval"`
+
+	// Keys is the number of keys kept in a Secret. Tickets encrypted with
+	// one of them can be resumed.
+	Keys int `json:"keys"`
+}
+
+// Cache configures an informer cache for Secrets, CertificatePolicies and
+// Gateways.
+type Cache struct {
+	// Enabled makes the server read Secrets, CertificatePolicies and the
+	// Gateways they target from an informer cache instead of the API server.
+	Enabled bool `json:"enabled"`
+
+	// Namespaces restricts the cache to these namespaces. Secrets of other
+	// namespaces cannot be served. All namespaces are cached when empty.
+	Namespaces []string `json:"namespaces"`
+
+	// SecretSelector is a label selector restricting the cached Secrets,
+	// e.g. gateway.giantswarm.io/managed=true. Other Secrets are treated as
+	// missing.
+	SecretSelector string `json:"secretSelector"`
+
+	// PolicySelector is a label selector restricting the CertificatePolicies
+	// whose certificates are scanned for expiry.
+	PolicySelector string `json:"policySelector"`
+}
+
+// LeaderElection configures the election of the replica that writes status
+// updates and Events. Every replica serves the hooks.
+type LeaderElection struct {
+	// Enabled elects a leader through a Lease. When disabled, every replica
+	// writes.
+	Enabled bool `json:"enabled"`
+
+	// LeaseName is the name of the Lease.
+	LeaseName string `json:"leaseName"`
+
+	// LeaseNamespace is the namespace of the Lease. Defaults to the
+	// namespace of the Pod.
+	LeaseNamespace string `json:"leaseNamespace"`
+
+	// LeaseDuration is the time other replicas wait before taking over a
+	// Lease that was not renewed.
+	LeaseDuration metav1.Duration `json:"leaseDuration"`
+
+	// RenewDeadline is the time the leader retries renewing the Lease
+	// before giving up leadership.
+	RenewDeadline metav1.Duration `json:"renewDeadline"`
+
+	// RetryPeriod is the time between two attempts to acquire or renew the
+	// Lease.
+	RetryPeriod metav1.Duration `json:"retryPeriod"`
+}
+
+// Metrics configures the metrics endpoint and certificate expiry scan.
+type Metrics struct {
+	// Port is the port of the Prometheus metrics endpoint. Zero disables it.
+	Port int `json:"port"`
+
+	// CertificateExpiry configures the certificate expiry scan.
+	CertificateExpiry CertificateExpiry `json:"certificateExpiry"`
+}
+
+// CertificateExpiry configures the certificate expiry scan.
+type CertificateExpiry struct {
+	// Threshold is the remaining validity below which a certificate is
+	// reported as expiring soon.
+	Threshold metav1.Duration `json:"threshold"`
+
+	// ScanInterval is the interval of the scan.
+	ScanInterval metav1.Duration `json:"scanInterval"`
+}
+
+// Tracing configures the export of spans.
+type Tracing struct {
+	// Endpoint is the host:port of an OTLP gRPC collector. Tracing is
+	// disabled when empty.
+	Endpoint string `json:"endpoint"`
+
+	// Insecure disables TLS towards the collector.
+	Insecure bool `json:"insecure"`
+
+	// SampleRatio is the fraction of hook calls traced when Envoy Gateway did
+	// not propagate a sampling decision.
+	SampleRatio float64 `json:"sampleRatio"`
+}
+
+// Recording configures the recording of hook requests and responses.
+type Recording struct {
+	// Dir is the directory recordings are written to. Recording is disabled
+	// when empty.
+	Dir string `json:"dir"`
+
+	// MaxFiles is the number of recordings kept.
+	MaxFiles int `json:"maxFiles"`
+}
+
+// Webhook configures the admission and conversion webhooks.
+type Webhook struct {
+	// Port is the port of the webhook server. Zero disables the webhooks.
+	Port int `json:"port"`
+
+	// CertDir is the directory containing the serving certificate and key.
+	CertDir string `json:"certDir"`
+
+	// CertName is the file name of the serving certificate.
+	CertName string `json:"certName"`
+
+	// KeyName is the file name of the serving key.
+	KeyName string `json:"keyName"`
+
+	// ConversionService is the namespace/name of the Service in front of the
+	// webhook server. When set, the CertificatePolicy CRD is configured to
+	// convert API versions through it.
+	ConversionService string `json:"conversionService"`
+
+	// ConversionServicePort is the port of the conversion Service.
+	ConversionServicePort int `json:"conversionServicePort"`
+
+	// ConversionCAName is the file name in CertDir of the CA bundle the API
+	// server uses to verify the conversion webhook.
+	ConversionCAName string `json:"conversionCAName"`
+}
+
+// Default returns the configuration used when no configuration file is
+// given.
+func Default() *Config {
+	return &Config{
+		APIVersion: APIVersion,
+		Kind:       Kind,
+		Server: Server{
+			Host: "0.0.0.0",
+			Port: 5005,
+		},
+		Logging: Logging{
+			Level:  "info",
+			Format: string(logging.FormatText),
+		},
+		Hooks: Hooks{
+			Enabled:          slices.Clone(extensionserver.Hooks),
+			Timeout:          metav1.Duration{Duration: extensionserver.DefaultHookTimeout},
+			FetchConcurrency: extensionserver.DefaultFetchConcurrency,
+			ValidationMode:   string(extensionserver.ValidationFailOpen),
+			Mutators:         extensionserver.DefaultRegistry().Names(),
+		},
+		Policies: Policies{
+			Kinds: slices.Clone(extensionserver.PolicyKinds),
+		},
+		LeaderElection: LeaderElection{
+			Enabled:       true,
+			LeaseName:     leader.DefaultLeaseName,
+			LeaseDuration: metav1.Duration{Duration: leader.DefaultLeaseDuration},
+			RenewDeadline: metav1.Duration{Duration: leader.DefaultRenewDeadline},
+			RetryPeriod:   metav1.Duration{Duration: leader.DefaultRetryPeriod},
+		},
+		SessionTicketKeys: SessionTicketKeys{
+			RotationInterval: metav1.Duration{Duration: ticketkeys.DefaultInterval},
+			Keys:             ticketkeys.DefaultKeys,
+		},
+		Metrics: Metrics{
+			Port: 8080,
+			CertificateExpiry: CertificateExpiry{
+				Threshold:    metav1.Duration{Duration: certexpiry.DefaultThreshold},
+				ScanInterval: metav1.Duration{Duration: certexpiry.DefaultInterval},
+			},
+		},
+		Tracing: Tracing{
+			SampleRatio: tracing.DefaultSampleRatio,
+		},
+		Recording: Recording{
+			MaxFiles: recorder.DefaultMaxFiles,
+		},
+		Webhook: Webhook{
+			CertDir:               "/tmp/k8s-webhook-server/serving-certs",
+			CertName:              "tls.crt",
+			KeyName:               "tls.key",
+			ConversionServicePort: 443,
+			ConversionCAName:      "ca.crt",
+		},
+	}
+}
+
+// Load reads the configuration file at path over the defaults and applies
+// the environment overrides. The result is not validated yet.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Parse decodes a configuration file over the defaults and applies the
+// environment overrides. Unknown fields are rejected.
+func Parse(data []byte) (*Config, error) {
+	cfg := Default()
+	// The file must state its version.
+	cfg.APIVersion, cfg.Kind = "", ""
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+			return nil, err
+		}
+	}
+	if err := ApplyEnv(cfg, os.LookupEnv); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// HookTimeouts returns the time budgets of individual hooks.
+func (c *Config) HookTimeouts() map[string]time.Duration {
+	timeouts := make(map[string]time.Duration, len(c.Hooks.Timeouts))
+	for hook, timeout := range c.Hooks.Timeouts {
+		timeouts[hook] = timeout.Duration
+	}
+	return timeouts
+}
@@ -0,0 +1,280 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLoad(t *testing.T) {
+	cfg, err := Load("testdata/config.yaml")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	if cfg.Server.Port != 5006 || cfg.Server.TLS.CertFile != "/etc/extension-server/tls/tls.crt" {
+		t.Errorf("server = %+v", cfg.Server)
+	}
+	if cfg.Logging.Level != "debug" || cfg.Logging.Format != "json" {
+		t.Errorf("logging = %+v", cfg.Logging)
+	}
+	if !reflect.DeepEqual(cfg.Hooks.Enabled, []string{"PostTranslateModify"}) || cfg.Hooks.Timeout.Duration != 3*time.Second {
+		t.Errorf("hooks = %+v", cfg.Hooks)
+	}
+	if !reflect.DeepEqual(cfg.Hooks.Mutators, []string{"certificates"}) {
+		t.Errorf("hooks.mutators = %v", cfg.Hooks.Mutators)
+	}
+	if got := cfg.HookTimeouts(); !reflect.DeepEqual(got, map[string]time.Duration{"PostTranslateModify": 10 * time.Second}) {
+		t.Errorf("HookTimeouts() = %v", got)
+	}
+	if !cfg.Cache.Enabled || len(cfg.Cache.Namespaces) != 2 || cfg.Cache.SecretSelector != "gateway.giantswarm.io/managed=true" {
+		t.Errorf("cache = %+v", cfg.Cache)
+	}
+	// Settings the file leaves out keep their defaults.
+	if cfg.Server.Host != "0.0.0.0" || cfg.Hooks.ValidationMode != "fail-open" || cfg.Recording.MaxFiles != Default().Recording.MaxFiles {
+		t.Errorf("defaults were not kept: %+v", cfg)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "config.yaml")); err == nil {
+		t.Error("Load() expected an error for a missing file")
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name:    "unknown field",
+			data:    "apiVersion: extensionserver.giantswarm.io/v1alpha1\nkind: ExtensionServerConfig\nhooks:\n  timout: 3s\n",
+			wantErr: `unknown field "timout"`,
+		},
+		{
+			name:    "wrong type",
+			data:    "server:\n  port: https\n",
+			wantErr: "port",
+		},
+		{
+			name:    "invalid duration",
+			data:    "hooks:\n  timeout: soon\n",
+			wantErr: "soon",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseRequiresVersion(t *testing.T) {
+	cfg, err := Parse([]byte("server:\n  port: 5006\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "apiVersion: must be "+APIVersion) || !strings.Contains(err.Error(), "kind: must be "+Kind) {
+		t.Errorf("Validate() error = %v, want apiVersion and kind errors", err)
+	}
+}
+
+func TestParseAppliesEnv(t *testing.T) {
+	t.Setenv("EXTENSION_SERVER_LOGGING_LEVEL", "warn")
+
+	cfg, err := Parse([]byte("logging:\n  level: debug\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if cfg.Logging.Level != "warn" {
+		t.Errorf("logging.level = %q, want the environment override warn", cfg.Logging.Level)
+	}
+}
+
+func TestDefaultIsValid(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Errorf("Default().Validate() error = %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr []string
+	}{
+		{
+			name:    "port out of range",
+			modify:  func(c *Config) { c.Server.Port = 70000 },
+			wantErr: []string{"server.port: must be between 1 and 65535, got 70000"},
+		},
+		{
+			name:    "certificate without key",
+			modify:  func(c *Config) { c.Server.TLS.CertFile = "tls.crt" },
+			wantErr: []string{"server.tls: certFile and keyFile must be set together"},
+		},
+		{
+			name:    "client CA without certificate",
+			modify:  func(c *Config) { c.Server.TLS.ClientCAFile = "ca.crt" },
+			wantErr: []string{"server.tls.clientCAFile: requires certFile and keyFile"},
+		},
+		{
+			name:    "unknown level and format",
+			modify:  func(c *Config) { c.Logging.Level, c.Logging.Format = "verbose", "xml" },
+			wantErr: []string{`logging.level: must be one of debug, info, warn or error, got "verbose"`, `logging.format: must be one of [text json], got "xml"`},
+		},
+		{
+			name:    "unknown hook",
+			modify:  func(c *Config) { c.Hooks.Enabled = []string{"PostTranslateModify", "PostGatewayModify"} },
+			wantErr: []string{`hooks.enabled[1]: unknown hook "PostGatewayModify"`},
+		},
+		{
+			name: "invalid timeouts",
+			modify: func(c *Config) {
+				c.Hooks.Timeout.Duration = -time.Second
+				c.Hooks.Timeouts = map[string]metav1.Duration{"PostGatewayModify": {Duration: time.Second}}
+			},
+			wantErr: []string{"hooks.timeout: must not be negative", "hooks.timeouts.PostGatewayModify: unknown hook"},
+		},
+		{
+			name:    "no fetch concurrency",
+			modify:  func(c *Config) { c.Hooks.FetchConcurrency = 0 },
+			wantErr: []string{"hooks.fetchConcurrency: must be at least 1, got 0"},
+		},
+		{
+			name:    "unknown validation mode",
+			modify:  func(c *Config) { c.Hooks.ValidationMode = "ignore" },
+			wantErr: []string{`hooks.validationMode: must be one of`},
+		},
+		{
+			name:    "unknown mutator",
+			modify:  func(c *Config) { c.Hooks.Mutators = []string{"certificates", "headers"} },
+			wantErr: []string{`hooks.mutators[1]: unknown mutator "headers", must be one of [certificates tls-params session-ticket-keys alpn]`},
+		},
+		{
+			name:    "unknown policy kind",
+			modify:  func(c *Config) { c.Policies.Kinds = []string{"BackendTLSPolicy"} },
+			wantErr: []string{`policies.kinds[0]: unknown kind "BackendTLSPolicy"`},
+		},
+		{
+			name:    "fallback in strict mode",
+			modify:  func(c *Config) { c.Fallback = Fallback{Secret: "default/fallback", Strict: true} },
+			wantErr: []string{"fallback: secret has no effect in strict mode"},
+		},
+		{
+			name:    "fallback without namespace",
+			modify:  func(c *Config) { c.Fallback.Secret = "fallback" },
+			wantErr: []string{`fallback.secret: must be in namespace/name format, got "fallback"`},
+		},
+		{
+			name: "invalid session ticket key rotation",
+			modify: func(c *Config) {
+				c.SessionTicketKeys.RotationInterval.Duration = 0
+				c.SessionTicketKeys.Keys = 0
+			},
+			wantErr: []string{"sessionTicketKeys.rotationInterval: must be positive", "sessionTicketKeys.keys: must be at least 1, got 0"},
+		},
+		{
+			name:    "cache namespaces without cache",
+			modify:  func(c *Config) { c.Cache.Namespaces = []string{"default", ""} },
+			wantErr: []string{"cache.namespaces[1]: must not be empty", "cache.namespaces: requires cache.enabled"},
+		},
+		{
+			name: "invalid cache selectors",
+			modify: func(c *Config) {
+				c.Cache.SecretSelector = "gateway.giantswarm.io/managed=true"
+				c.Cache.PolicySelector = "team in (a"
+			},
+			wantErr: []string{"cache.secretSelector: requires cache.enabled", "cache.policySelector: invalid label selector"},
+		},
+		{
+			name: "leader election durations out of order",
+			modify: func(c *Config) {
+				c.LeaderElection.LeaseName = ""
+				c.LeaderElection.LeaseDuration.Duration = c.LeaderElection.RenewDeadline.Duration
+			},
+			wantErr: []string{"leaderElection.leaseName: must not be empty", "leaderElection.leaseDuration: must be longer than renewDeadline"},
+		},
+		{
+			name:    "invalid metrics",
+			modify:  func(c *Config) { c.Metrics.Port, c.Metrics.CertificateExpiry.ScanInterval.Duration = -1, 0 },
+			wantErr: []string{"metrics.port: must be between 0 and 65535, got -1", "metrics.certificateExpiry.scanInterval: must be positive"},
+		},
+		{
+			name:    "sample ratio out of range",
+			modify:  func(c *Config) { c.Tracing.SampleRatio = 1.5 },
+			wantErr: []string{"tracing.sampleRatio: must be between 0 and 1, got 1.5"},
+		},
+		{
+			name:    "conversion without webhook",
+			modify:  func(c *Config) { c.Webhook.ConversionService = "giantswarm/extension-server" },
+			wantErr: []string{"webhook.conversionService: requires webhook.port"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.modify(cfg)
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatal("Validate() expected an error")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRestartRequired(t *testing.T) {
+	current := Default()
+
+	next := Default()
+	next.Logging.Level = "debug"
+	next.Hooks.Enabled = nil
+	next.Hooks.PartialResults = true
+	next.Hooks.Mutators = []string{"tls-params"}
+	next.Fallback.Strict = true
+	if got := RestartRequired(current, next); len(got) != 0 {
+		t.Errorf("RestartRequired() = %v, want none for runtime settings", got)
+	}
+	if got := Reloadable(current, next); !reflect.DeepEqual(got, next) {
+		t.Errorf("Reloadable() = %+v, want %+v", got, next)
+	}
+
+	next.Server.Port = 5006
+	next.Hooks.Timeout.Duration = time.Second
+	next.Logging.Format = "json"
+	if got := RestartRequired(current, next); !reflect.DeepEqual(got, []string{"server", "logging", "hooks"}) {
+		t.Errorf("RestartRequired() = %v, want [server logging hooks]", got)
+	}
+	applied := Reloadable(current, next)
+	if applied.Server.Port != current.Server.Port || applied.Logging.Level != "debug" || applied.Logging.Format != current.Logging.Format {
+		t.Errorf("Reloadable() = %+v, want only the runtime settings of next", applied)
+	}
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
@@ -0,0 +1,6 @@
+// Package config loads the versioned configuration file of the extension
+// server. Values are read from the file, overridden by EXTENSION_SERVER_*
+// environment variables and validated strictly. The file can be watched for
+// changes, so that settings that are safe to change at runtime are applied
+// without a restart.
+package config
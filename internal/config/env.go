@@ -0,0 +1,139 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EnvPrefix prefixes the environment variables overriding settings. The
+// variable of a setting is its path in upper snake case, e.g.
+// EXTENSION_SERVER_HOOKS_FETCH_CONCURRENCY for hooks.fetchConcurrency.
+const EnvPrefix = "EXTENSION_SERVER"
+
+var durationType = reflect.TypeFor[metav1.Duration]()
+
+// ApplyEnv overrides settings with the environment variables returned by
+// lookup. Lists are comma separated, hook timeouts are given as
+// <hook>=<duration> pairs.
+func ApplyEnv(cfg *Config, lookup func(string) (string, bool)) error {
+	return applyEnv(reflect.ValueOf(cfg).Elem(), EnvPrefix, lookup)
+}
+
+// EnvNames returns the environment variables overriding settings.
+func EnvNames() []string {
+	var names []string
+	_ = applyEnv(reflect.ValueOf(Default()).Elem(), EnvPrefix, func(name string) (string, bool) {
+		names = append(names, name)
+		return "", false
+	})
+	return names
+}
+
+func applyEnv(value reflect.Value, prefix string, lookup func(string) (string, bool)) error {
+	for i := range value.NumField() {
+		field := value.Type().Field(i)
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if tag == "apiVersion" || tag == "kind" {
+			continue
+		}
+		name := prefix + "_" + upperSnakeCase(tag)
+
+		if field.Type.Kind() == reflect.Struct && field.Type != durationType {
+			if err := applyEnv(value.Field(i), name, lookup); err != nil {
+				return err
+			}
+			continue
+		}
+
+		raw, ok := lookup(name)
+		if !ok {
+			continue
+		}
+		if err := setValue(value.Field(i), raw); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func setValue(value reflect.Value, raw string) error {
+	switch {
+	case value.Type() == durationType:
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		value.Set(reflect.ValueOf(metav1.Duration{Duration: duration}))
+	case value.Kind() == reflect.String:
+		value.SetString(raw)
+	case value.Kind() == reflect.Bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		value.SetBool(parsed)
+	case value.Kind() == reflect.Int:
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(parsed))
+	case value.Kind() == reflect.Float64:
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		value.SetFloat(parsed)
+	case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.String:
+		value.Set(reflect.ValueOf(splitList(raw)))
+	case value.Kind() == reflect.Map && value.Type().Elem() == durationType:
+		durations := map[string]metav1.Duration{}
+		for _, pair := range splitList(raw) {
+			key, duration, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("%q is not a <key>=<duration> pair", pair)
+			}
+			parsed, err := time.ParseDuration(duration)
+			if err != nil {
+				return err
+			}
+			durations[key] = metav1.Duration{Duration: parsed}
+		}
+		value.Set(reflect.ValueOf(durations))
+	default:
+		return fmt.Errorf("unsupported setting type %s", value.Type())
+	}
+	return nil
+}
+
+// splitList splits a comma separated list, dropping empty elements.
+func splitList(raw string) []string {
+	list := []string{}
+	for _, element := range strings.Split(raw, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			list = append(list, element)
+		}
+	}
+	return list
+}
+
+// upperSnakeCase converts a camel case field name such as clientCAFile to
+// CLIENT_CA_FILE.
+func upperSnakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) &&
+			(unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"EXTENSION_SERVER_SERVER_PORT":                              "5006",
+		"EXTENSION_SERVER_SERVER_TLS_CLIENT_CA_FILE":                "/etc/ca.crt",
+		"EXTENSION_SERVER_HOOKS_ENABLED":                            "PostTranslateModify, ",
+		"EXTENSION_SERVER_HOOKS_TIMEOUT":                            "2s",
+		"EXTENSION_SERVER_HOOKS_TIMEOUTS":                           "PostTranslateModify=10s,PostHTTPListenerModify=0s",
+		"EXTENSION_SERVER_HOOKS_PARTIAL_RESULTS":                    "true",
+		"EXTENSION_SERVER_TRACING_SAMPLE_RATIO":                     "0.25",
+		"EXTENSION_SERVER_METRICS_CERTIFICATE_EXPIRY_SCAN_INTERVAL": "1m",
+		"EXTENSION_SERVER_KIND":                                     "Ignored",
+	}
+	cfg := Default()
+	if err := ApplyEnv(cfg, lookup(env)); err != nil {
+		t.Fatalf("ApplyEnv() error = %v", err)
+	}
+
+	if cfg.Server.Port != 5006 || cfg.Server.TLS.ClientCAFile != "/etc/ca.crt" {
+		t.Errorf("server = %+v", cfg.Server)
+	}
+	if !reflect.DeepEqual(cfg.Hooks.Enabled, []string{"PostTranslateModify"}) || cfg.Hooks.Timeout.Duration != 2*time.Second || !cfg.Hooks.PartialResults {
+		t.Errorf("hooks = %+v", cfg.Hooks)
+	}
+	if got := cfg.HookTimeouts(); !reflect.DeepEqual(got, map[string]time.Duration{"PostTranslateModify": 10 * time.Second, "PostHTTPListenerModify": 0}) {
+		t.Errorf("HookTimeouts() = %v", got)
+	}
+	if cfg.Tracing.SampleRatio != 0.25 || cfg.Metrics.CertificateExpiry.ScanInterval.Duration != time.Minute {
+		t.Errorf("tracing = %+v, metrics = %+v", cfg.Tracing, cfg.Metrics)
+	}
+	if cfg.Kind != Kind {
+		t.Errorf("kind = %q, want it not to be overridable", cfg.Kind)
+	}
+}
+
+func TestApplyEnvErrors(t *testing.T) {
+	tests := map[string]string{
+		"EXTENSION_SERVER_SERVER_PORT":           "https",
+		"EXTENSION_SERVER_HOOKS_PARTIAL_RESULTS": "sometimes",
+		"EXTENSION_SERVER_HOOKS_TIMEOUT":         "soon",
+		"EXTENSION_SERVER_HOOKS_TIMEOUTS":        "PostTranslateModify",
+		"EXTENSION_SERVER_TRACING_SAMPLE_RATIO":  "half",
+	}
+
+	for name, value := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := ApplyEnv(Default(), lookup(map[string]string{name: value}))
+			if err == nil || !strings.Contains(err.Error(), "invalid "+name) {
+				t.Errorf("ApplyEnv() error = %v, want it to name %s", err, name)
+			}
+		})
+	}
+}
+
+func TestEnvNames(t *testing.T) {
+	names := EnvNames()
+	for _, want := range []string{"EXTENSION_SERVER_LOGGING_LEVEL", "EXTENSION_SERVER_CACHE_NAMESPACES", "EXTENSION_SERVER_WEBHOOK_CONVERSION_CA_NAME"} {
+		if !slices.Contains(names, want) {
+			t.Errorf("EnvNames() = %v, want it to contain %s", names, want)
+		}
+	}
+	if slices.Contains(names, "EXTENSION_SERVER_API_VERSION") {
+		t.Error("EnvNames() contains EXTENSION_SERVER_API_VERSION")
+	}
+}
+
+func TestUpperSnakeCase(t *testing.T) {
+	for name, want := range map[string]string{
+		"port":             "PORT",
+		"fetchConcurrency": "FETCH_CONCURRENCY",
+		"clientCAFile":     "CLIENT_CA_FILE",
+		"conversionCAName": "CONVERSION_CA_NAME",
+	} {
+		if got := upperSnakeCase(name); got != want {
+			t.Errorf("upperSnakeCase(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func lookup(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
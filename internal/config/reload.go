@@ -0,0 +1,37 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Reloadable returns the settings of next that can be applied at runtime,
+// with the other settings taken from current.
+func Reloadable(current, next *Config) *Config {
+	cfg := *current
+	cfg.Logging.Level = next.Logging.Level
+	cfg.Hooks.Enabled = next.Hooks.Enabled
+	cfg.Hooks.FetchConcurrency = next.Hooks.FetchConcurrency
+	cfg.Hooks.PartialResults = next.Hooks.PartialResults
+	cfg.Hooks.ValidationMode = next.Hooks.ValidationMode
+	cfg.Hooks.Mutators = next.Hooks.Mutators
+	cfg.Policies = next.Policies
+	cfg.Fallback = next.Fallback
+	return &cfg
+}
+
+// RestartRequired returns the sections of next whose changes cannot be
+// applied at runtime.
+func RestartRequired(current, next *Config) []string {
+	applied := reflect.ValueOf(*Reloadable(current, next))
+	wanted := reflect.ValueOf(*next)
+
+	var sections []string
+	for i := range applied.NumField() {
+		if !reflect.DeepEqual(applied.Field(i).Interface(), wanted.Field(i).Interface()) {
+			tag, _, _ := strings.Cut(applied.Type().Field(i).Tag.Get("json"), ",")
+			sections = append(sections, tag)
+		}
+	}
+	return sections
+}
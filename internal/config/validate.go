@@ -0,0 +1,172 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/giantswarm/envoy-extension-server-app/internal/extensionserver"
+	"github.com/giantswarm/envoy-extension-server-app/internal/logging"
+)
+
+// Validate reports every invalid setting, each prefixed with the path of
+// its field in the configuration file.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(field, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if c.APIVersion != APIVersion {
+		invalid("apiVersion", "must be %s, got %q", APIVersion, c.APIVersion)
+	}
+	if c.Kind != Kind {
+		invalid("kind", "must be %s, got %q", Kind, c.Kind)
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		invalid("server.port", "must be between 1 and 65535, got %d", c.Server.Port)
+	}
+	tls := c.Server.TLS
+	if (tls.CertFile == "") != (tls.KeyFile == "") {
+		invalid("server.tls", "certFile and keyFile must be set together")
+	}
+	if tls.ClientCAFile != "" && tls.CertFile == "" {
+		invalid("server.tls.clientCAFile", "requires certFile and keyFile")
+	}
+
+	if _, err := logging.ParseLevel(c.Logging.Level); err != nil {
+		invalid("logging.level", "must be one of debug, info, warn or error, got %q", c.Logging.Level)
+	}
+	if _, err := logging.ParseFormat(c.Logging.Format); err != nil {
+		invalid("logging.format", "must be one of %v, got %q", logging.Formats, c.Logging.Format)
+	}
+
+	for i, hook := range c.Hooks.Enabled {
+		if !slices.Contains(extensionserver.Hooks, hook) {
+			invalid(fmt.Sprintf("hooks.enabled[%d]", i), "unknown hook %q, must be one of %v", hook, extensionserver.Hooks)
+		}
+	}
+	if c.Hooks.Timeout.Duration < 0 {
+		invalid("hooks.timeout", "must not be negative")
+	}
+	for hook, timeout := range c.Hooks.Timeouts {
+		if !slices.Contains(extensionserver.Hooks, hook) {
+			invalid("hooks.timeouts."+hook, "unknown hook, must be one of %v", extensionserver.Hooks)
+		}
+		if timeout.Duration < 0 {
+			invalid("hooks.timeouts."+hook, "must not be negative")
+		}
+	}
+	if c.Hooks.FetchConcurrency < 1 {
+		invalid("hooks.fetchConcurrency", "must be at least 1, got %d", c.Hooks.FetchConcurrency)
+	}
+	if _, err := extensionserver.ParseValidationMode(c.Hooks.ValidationMode); err != nil {
+		invalid("hooks.validationMode", "must be one of %v, got %q", extensionserver.ValidationModes, c.Hooks.ValidationMode)
+	}
+
+	mutators := extensionserver.DefaultRegistry().Names()
+	for i, mutator := range c.Hooks.Mutators {
+		if !slices.Contains(mutators, mutator) {
+			invalid(fmt.Sprintf("hooks.mutators[%d]", i), "unknown mutator %q, must be one of %v", mutator, mutators)
+		}
+	}
+
+	for i, kind := range c.Policies.Kinds {
+		if !slices.Contains(extensionserver.PolicyKinds, kind) {
+			invalid(fmt.Sprintf("policies.kinds[%d]", i), "unknown kind %q, must be one of %v", kind, extensionserver.PolicyKinds)
+		}
+	}
+
+	if c.Fallback.Secret != "" && !isNamespacedName(c.Fallback.Secret) {
+		invalid("fallback.secret", "must be in namespace/name format, got %q", c.Fallback.Secret)
+	}
+	if c.Fallback.Secret != "" && c.Fallback.Strict {
+		invalid("fallback", "secret has no effect in strict mode")
+	}
+
+	if c.SessionTicketKeys.RotationInterval.Duration <= 0 {
+		invalid("sessionTicketKeys.rotationInterval", "must be positive")
+	}
+	if c.SessionTicketKeys.Keys < 1 {
+		invalid("sessionTicketKeys.keys", "must be at least 1, got %d", c.SessionTicketKeys.Keys)
+	}
+
+	for i, namespace := range c.Cache.Namespaces {
+		if namespace == "" {
+			invalid(fmt.Sprintf("cache.namespaces[%d]", i), "must not be empty")
+		}
+	}
+	if len(c.Cache.Namespaces) > 0 && !c.Cache.Enabled {
+		invalid("cache.namespaces", "requires cache.enabled")
+	}
+	for _, selector := range []struct{ field, value string }{
+		{"cache.secretSelector", c.Cache.SecretSelector},
+		{"cache.policySelector", c.Cache.PolicySelector},
+	} {
+		if selector.value == "" {
+			continue
+		}
+		if _, err := labels.Parse(selector.value); err != nil {
+			invalid(selector.field, "invalid label selector: %v", err)
+		}
+		if !c.Cache.Enabled {
+			invalid(selector.field, "requires cache.enabled")
+		}
+	}
+
+	if election := c.LeaderElection; election.Enabled {
+		if election.LeaseName == "" {
+			invalid("leaderElection.leaseName", "must not be empty")
+		}
+		if election.RetryPeriod.Duration <= 0 {
+			invalid("leaderElection.retryPeriod", "must be positive")
+		}
+		if election.RenewDeadline.Duration <= election.RetryPeriod.Duration {
+			invalid("leaderElection.renewDeadline", "must be longer than retryPeriod")
+		}
+		if election.LeaseDuration.Duration <= election.RenewDeadline.Duration {
+			invalid("leaderElection.leaseDuration", "must be longer than renewDeadline")
+		}
+	}
+
+	if c.Metrics.Port < 0 || c.Metrics.Port > 65535 {
+		invalid("metrics.port", "must be between 0 and 65535, got %d", c.Metrics.Port)
+	}
+	if c.Metrics.CertificateExpiry.Threshold.Duration <= 0 {
+		invalid("metrics.certificateExpiry.threshold", "must be positive")
+	}
+	if c.Metrics.CertificateExpiry.ScanInterval.Duration <= 0 {
+		invalid("metrics.certificateExpiry.scanInterval", "must be positive")
+	}
+
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		invalid("tracing.sampleRatio", "must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	}
+
+	if c.Recording.MaxFiles < 1 {
+		invalid("recording.maxFiles", "must be at least 1, got %d", c.Recording.MaxFiles)
+	}
+
+	if c.Webhook.Port < 0 || c.Webhook.Port > 65535 {
+		invalid("webhook.port", "must be between 0 and 65535, got %d", c.Webhook.Port)
+	}
+	if c.Webhook.ConversionService != "" {
+		if !isNamespacedName(c.Webhook.ConversionService) {
+			invalid("webhook.conversionService", "must be in namespace/name format, got %q", c.Webhook.ConversionService)
+		}
+		if c.Webhook.Port == 0 {
+			invalid("webhook.conversionService", "requires webhook.port")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func isNamespacedName(value string) bool {
+	namespace, name, ok := strings.Cut(value, "/")
+	return ok && namespace != "" && name != "" && !strings.Contains(name, "/")
+}
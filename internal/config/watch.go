@@ -0,0 +1,63 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch calls changed whenever the content of the file at path changes,
+// until ctx is done. The directory of the file is watched rather than the
+// file itself, so that files of a mounted ConfigMap, which are replaced by
+// swapping symlinks, are followed too. Errors of the watcher are logged and
+// do not end the watch.
+func Watch(ctx context.Context, logger *slog.Logger, path string, changed func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer func() { _ = watcher.Close() }()
+
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", filepath.Dir(path), err)
+	}
+	watch(ctx, logger, path, watcher.Events, watcher.Errors, changed)
+	return nil
+}
+
+// watch calls changed for the events that change the content of the file at
+// path, until ctx is done or the channels are closed.
+func watch(ctx context.Context, logger *slog.Logger, path string, events <-chan fsnotify.Event, errs <-chan error, changed func()) {
+	// Read after the watch started, so that no change is missed.
+	last, _ := os.ReadFile(path)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err, ok := <-errs:
+			if !ok {
+				return
+			}
+			// Errors such as a queue overflow lose events, but the next
+			// event still compares the whole file.
+			logger.Warn("error watching the configuration file", slog.String("path", path), slog.String("error", err.Error()))
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+			current, err := os.ReadFile(path)
+			if err != nil || bytes.Equal(current, last) {
+				// The file is being replaced or did not change.
+				continue
+			}
+			last = current
+			changed()
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	writeFile(t, path, "logging:\n  level: info\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changed := make(chan struct{}, 10)
+	done := make(chan error)
+	go func() {
+		done <- Watch(ctx, testLogger(), path, func() { changed <- struct{}{} })
+	}()
+
+	// The watcher starts asynchronously, so keep changing the file until a
+	// change is reported.
+	writeFile(t, filepath.Join(dir, "other.yaml"), "unrelated")
+	timeout := time.After(10 * time.Second)
+	for i := 0; ; i++ {
+		writeFile(t, path, fmt.Sprintf("logging:\n  level: debug\n# revision %d\n", i))
+		select {
+		case <-changed:
+		case <-time.After(100 * time.Millisecond):
+			continue
+		case <-timeout:
+			t.Fatal("no change was reported")
+		}
+		break
+	}
+	drain(changed)
+
+	// A ConfigMap update swaps a symlink to a new directory.
+	data := filepath.Join(dir, "..data_new")
+	if err := os.Mkdir(data, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(data, "config.yaml"), "logging:\n  level: warn\n")
+	link := filepath.Join(dir, "config.yaml.tmp")
+	if err := os.Symlink(filepath.Join(data, "config.yaml"), link); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(link, path); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("no change was reported for the swapped symlink")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Watch() error = %v", err)
+	}
+}
+
+func TestWatchMissingDirectory(t *testing.T) {
+	err := Watch(context.Background(), testLogger(), filepath.Join(t.TempDir(), "missing", "config.yaml"), func() {})
+	if err == nil {
+		t.Error("Watch() expected an error for a missing directory")
+	}
+}
+
+func TestWatchContinuesAfterError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "logging:\n  level: info\n")
+
+	events := make(chan fsnotify.Event)
+	errs := make(chan error)
+	changed := make(chan struct{}, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		watch(context.Background(), testLogger(), path, events, errs, func() { changed <- struct{}{} })
+	}()
+
+	errs <- errors.New("event queue overflow")
+	writeFile(t, path, "logging:\n  level: debug\n")
+	events <- fsnotify.Event{Name: path, Op: fsnotify.Write}
+	<-changed
+
+	close(errs)
+	<-done
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stdout, nil))
+}
+
+func drain(changed chan struct{}) {
+	for {
+		select {
+		case <-changed:
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,4 @@
+// Package events provides an event recorder that deduplicates and rate limits
+// Kubernetes Events, so that outcomes reported on every Envoy Gateway
+// translation do not flood the API server.
+package events
@@ -0,0 +1,161 @@
+package events
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// DefaultWindow is the default time during which identical events are
+	// emitted only once.
+	DefaultWindow = 10 * time.Minute
+
+	// DefaultQPS is the default sustained rate of emitted events.
+	DefaultQPS = 1
+
+	// DefaultBurst is the default number of events emitted in a burst.
+	DefaultBurst = 25
+)
+
+// Options configures a Recorder.
+type Options struct {
+	// Window is the time during which identical events are emitted only
+	// once. Defaults to DefaultWindow.
+	Window time.Duration
+
+	// QPS is the sustained rate of emitted events. Defaults to DefaultQPS.
+	QPS float32
+
+	// Burst is the number of events emitted in a burst. Defaults to
+	// DefaultBurst.
+	Burst int
+
+	// Clock is used to expire deduplicated events and refill the rate
+	// limiter. Defaults to the real clock.
+	Clock clock.Clock
+}
+
+// eventKey identifies identical events.
+type eventKey struct {
+	kind      string
+	namespace string
+	name      string
+	eventType string
+	reason    string
+	message   string
+}
+
+// Recorder is a record.EventRecorder that drops events identical to one
+// emitted within the deduplication window and rate limits all others.
+type Recorder struct {
+	recorder record.EventRecorder
+	clock    clock.Clock
+	window   time.Duration
+	limiter  flowcontrol.RateLimiter
+
+	mu   sync.Mutex
+	seen map[eventKey]time.Time
+}
+
+var _ record.EventRecorder = &Recorder{}
+
+// NewRecorder wraps the given recorder.
+func NewRecorder(recorder record.EventRecorder, opts Options) *Recorder {
+	if opts.Window <= 0 {
+		opts.Window = DefaultWindow
+	}
+	if opts.QPS <= 0 {
+		opts.QPS = DefaultQPS
+	}
+	if opts.Burst <= 0 {
+		opts.Burst = DefaultBurst
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.RealClock{}
+	}
+	return &Recorder{
+		recorder: recorder,
+		clock:    opts.Clock,
+		window:   opts.Window,
+		limiter:  flowcontrol.NewTokenBucketRateLimiterWithClock(opts.QPS, opts.Burst, opts.Clock),
+		seen:     map[eventKey]time.Time{},
+	}
+}
+
+// Event emits the event unless it is a duplicate or rate limited.
+func (r *Recorder) Event(object runtime.Object, eventtype, reason, message string) {
+	if r.allow(object, eventtype, reason, message) {
+		r.recorder.Event(object, eventtype, reason, message)
+	}
+}
+
+// Eventf is just like Event, but with Sprintf for the message field.
+func (r *Recorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.Event(object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// AnnotatedEventf is just like Eventf, but with annotations attached.
+func (r *Recorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	message := fmt.Sprintf(messageFmt, args...)
+	if r.allow(object, eventtype, reason, message) {
+		r.recorder.AnnotatedEventf(object, annotations, eventtype, reason, "%s", message)
+	}
+}
+
+// allow reports whether an event should be emitted and remembers it if so.
+func (r *Recorder) allow(object runtime.Object, eventType, reason, message string) bool {
+	key := newEventKey(object, eventType, reason, message)
+	now := r.clock.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for seenKey, emitted := range r.seen {
+		if now.Sub(emitted) >= r.window {
+			delete(r.seen, seenKey)
+		}
+	}
+
+	if _, ok := r.seen[key]; ok {
+		return false
+	}
+	if !r.limiter.TryAccept() {
+		return false
+	}
+	r.seen[key] = now
+	return true
+}
+
+// newEventKey builds the deduplication key of an event.
+func newEventKey(object runtime.Object, eventType, reason, message string) eventKey {
+	key := eventKey{
+		eventType: eventType,
+		reason:    reason,
+		message:   message,
+	}
+
+	if ref, ok := object.(*corev1.ObjectReference); ok {
+		key.kind = ref.Kind
+		key.namespace = ref.Namespace
+		key.name = ref.Name
+		return key
+	}
+
+	key.kind = object.GetObjectKind().GroupVersionKind().Kind
+	if accessor, err := meta.Accessor(object); err == nil {
+		key.namespace = accessor.GetNamespace()
+		key.name = accessor.GetName()
+		if key.kind == "" {
+			key.kind = fmt.Sprintf("%T", object)
+		}
+	}
+	return key
+}
@@ -0,0 +1,87 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+var now = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func TestRecorderDeduplicates(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(now)
+	fake := record.NewFakeRecorder(10)
+	recorder := NewRecorder(fake, Options{Window: time.Minute, Clock: fakeClock})
+	policy := &v1beta1.CertificatePolicy{ObjectMeta: metav1.ObjectMeta{Name: "policy-1", Namespace: "default"}}
+
+	recorder.Event(policy, corev1.EventTypeWarning, "SecretFetchFailed", "not found")
+	recorder.Event(policy, corev1.EventTypeWarning, "SecretFetchFailed", "not found")
+	recorder.Eventf(policy, corev1.EventTypeWarning, "SecretFetchFailed", "%s", "not found")
+	if got := len(fake.Events); got != 1 {
+		t.Fatalf("got %d events for identical events, want 1", got)
+	}
+
+	recorder.Event(policy, corev1.EventTypeWarning, "SecretFetchFailed", "forbidden")
+	recorder.Event(&v1beta1.CertificatePolicy{ObjectMeta: metav1.ObjectMeta{Name: "policy-2", Namespace: "default"}}, corev1.EventTypeWarning, "SecretFetchFailed", "not found")
+	if got := len(fake.Events); got != 3 {
+		t.Fatalf("got %d events after distinct events, want 3", got)
+	}
+
+	fakeClock.Step(time.Minute)
+	recorder.Event(policy, corev1.EventTypeWarning, "SecretFetchFailed", "not found")
+	if got := len(fake.Events); got != 4 {
+		t.Fatalf("got %d events after the window expired, want 4", got)
+	}
+}
+
+func TestRecorderDeduplicatesObjectReferences(t *testing.T) {
+	fake := record.NewFakeRecorder(10)
+	recorder := NewRecorder(fake, Options{Clock: clocktesting.NewFakeClock(now)})
+	gateway := &corev1.ObjectReference{Kind: "Gateway", Namespace: "default", Name: "gateway-1"}
+
+	recorder.Event(gateway, corev1.EventTypeNormal, "Programmed", "served")
+	recorder.Event(gateway.DeepCopy(), corev1.EventTypeNormal, "Programmed", "served")
+	recorder.Event(&corev1.ObjectReference{Kind: "Gateway", Namespace: "default", Name: "gateway-2"}, corev1.EventTypeNormal, "Programmed", "served")
+	if got := len(fake.Events); got != 2 {
+		t.Errorf("got %d events, want 2", got)
+	}
+}
+
+func TestRecorderRateLimits(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(now)
+	fake := record.NewFakeRecorder(10)
+	recorder := NewRecorder(fake, Options{QPS: 1, Burst: 2, Clock: fakeClock})
+	policy := &v1beta1.CertificatePolicy{ObjectMeta: metav1.ObjectMeta{Name: "policy-1", Namespace: "default"}}
+
+	for _, message := range []string{"a", "b", "c"} {
+		recorder.Event(policy, corev1.EventTypeNormal, "Programmed", message)
+	}
+	if got := len(fake.Events); got != 2 {
+		t.Fatalf("got %d events, want the burst of 2", got)
+	}
+
+	// A rate limited event is not remembered and can be emitted later.
+	fakeClock.Step(time.Second)
+	recorder.Event(policy, corev1.EventTypeNormal, "Programmed", "c")
+	if got := len(fake.Events); got != 3 {
+		t.Errorf("got %d events after the limiter refilled, want 3", got)
+	}
+}
+
+func TestRecorderAnnotatedEventf(t *testing.T) {
+	fake := record.NewFakeRecorder(10)
+	recorder := NewRecorder(fake, Options{Clock: clocktesting.NewFakeClock(now)})
+	policy := &v1beta1.CertificatePolicy{ObjectMeta: metav1.ObjectMeta{Name: "policy-1", Namespace: "default"}}
+
+	recorder.AnnotatedEventf(policy, map[string]string{"a": "b"}, corev1.EventTypeNormal, "Programmed", "served %d", 1)
+	recorder.AnnotatedEventf(policy, map[string]string{"a": "b"}, corev1.EventTypeNormal, "Programmed", "served %d", 1)
+	if got := len(fake.Events); got != 1 {
+		t.Errorf("got %d events, want 1", got)
+	}
+}
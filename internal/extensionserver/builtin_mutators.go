@@ -0,0 +1,142 @@
+package extensionserver
+
+import (
+	"context"
+	"log/slog"
+
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+
+	"github.com/giantswarm/envoy-extension-server-app/internal/logging"
+)
+
+const (
+	// MutatorCertificates serves the certificates of policies: it adds their
+	// Envoy secrets to translations and references them from the TLS filter
+	// chains of listeners.
+	MutatorCertificates = "certificates"
+
+	// MutatorTLSParams applies the TLS parameters of policies to the TLS
+	// filter chains their certificates are served on.
+	MutatorTLSParams = "tls-params"
+
+	// MutatorSessionTicketKeys references the session ticket keys of
+	// policies from the TLS filter chains their certificates are served on.
+	MutatorSessionTicketKeys = "session-ticket-keys"
+
+	// MutatorALPN applies the ALPN protocols of policies to the TLS filter
+	// chains their certificates are served on.
+	MutatorALPN = "alpn"
+)
+
+// discardLogger is used by Mutators called without a request-scoped logger.
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// policySecretReferencer is implemented by the ListenerMutators that
+// reference secrets of policies, which only the certificates Mutator serves.
+type policySecretReferencer interface {
+	referencesPolicySecrets()
+}
+
+type certificatesMutator struct{}
+
+func (certificatesMutator) Name() string { return MutatorCertificates }
+
+func (certificatesMutator) referencesPolicySecrets() {}
+
+// MutateTranslation adds the secrets of the policies that are not part of
+// the translation yet.
+func (certificatesMutator) MutateTranslation(ctx context.Context, translation *Translation) error {
+	added := map[string]struct{}{}
+	for _, secret := range translation.Secrets {
+		added[secret.GetName()] = struct{}{}
+	}
+	for _, secrets := range translation.PolicySecrets {
+		for _, secret := range secrets {
+			// Several policies may share a secret, e.g. the fallback certificate.
+			if _, ok := added[secret.GetName()]; ok {
+				continue
+			}
+			added[secret.GetName()] = struct{}{}
+			translation.Secrets = append(translation.Secrets, secret)
+			logging.FromContext(ctx, discardLogger).Info("added secret to response", "secretName", secret.GetName())
+		}
+	}
+	return nil
+}
+
+// MutateFilterChain references the secrets of the certificates from the TLS
+// context.
+func (certificatesMutator) MutateFilterChain(_ context.Context, filterChain *FilterChain) error {
+	secretNames := make([]string, 0, len(filterChain.Certificates))
+	for _, certificate := range filterChain.Certificates {
+		secretNames = append(secretNames, certificate.SecretName)
+	}
+	appendSdsSecretConfigs(filterChain.TLSContext, secretNames)
+	return nil
+}
+
+type tlsParamsMutator struct{}
+
+func (tlsParamsMutator) Name() string { return MutatorTLSParams }
+
+// MutateFilterChain applies the TLS parameters of the certificates. Those of
+// later certificates take precedence. QUIC filter chains keep theirs, as
+// HTTP/3 requires TLS 1.3.
+func (tlsParamsMutator) MutateFilterChain(_ context.Context, filterChain *FilterChain) error {
+	if filterChain.QUIC {
+		return nil
+	}
+	for _, certificate := range filterChain.Certificates {
+		if certificate.TLSParams != nil {
+			applyTLSParams(filterChain.TLSContext, certificate.TLSParams)
+		}
+	}
+	return nil
+}
+
+type sessionTicketKeysMutator struct{}
+
+func (sessionTicketKeysMutator) Name() string { return MutatorSessionTicketKeys }
+
+func (sessionTicketKeysMutator) referencesPolicySecrets() {}
+
+// MutateFilterChain references the session ticket keys of the certificates,
+// so that all proxies serving the filter chain can resume each other's TLS
+// sessions. Those of later certificates take precedence.
+func (sessionTicketKeysMutator) MutateFilterChain(_ context.Context, filterChain *FilterChain) error {
+	for _, certificate := range filterChain.Certificates {
+		if certificate.SessionTicketKeys != "" {
+			filterChain.TLSContext.SessionTicketKeysType = &tlsv3.DownstreamTlsContext_SessionTicketKeysSdsSecretConfig{
+				SessionTicketKeysSdsSecretConfig: NewSdsSecretConfig(certificate.SessionTicketKeys),
+			}
+		}
+	}
+	return nil
+}
+
+type alpnMutator struct{}
+
+func (alpnMutator) Name() string { return MutatorALPN }
+
+// MutateFilterChain replaces the ALPN protocols of the TLS context with those
+// of the certificates. Those of later certificates take precedence. QUIC
+// filter chains keep h3.
+func (alpnMutator) MutateFilterChain(_ context.Context, filterChain *FilterChain) error {
+	if filterChain.QUIC {
+		return nil
+	}
+	for _, certificate := range filterChain.Certificates {
+		if len(certificate.ALPNProtocols) == 0 {
+			continue
+		}
+		if filterChain.TLSContext.CommonTlsContext == nil {
+			filterChain.TLSContext.CommonTlsContext = &tlsv3.CommonTlsContext{}
+		}
+		protocols := make([]string, 0, len(certificate.ALPNProtocols))
+		for _, protocol := range certificate.ALPNProtocols {
+			protocols = append(protocols, string(protocol))
+		}
+		filterChain.TLSContext.CommonTlsContext.AlpnProtocols = protocols
+	}
+	return nil
+}
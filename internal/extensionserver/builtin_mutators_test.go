@@ -0,0 +1,102 @@
+package extensionserver
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"k8s.io/utils/ptr"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+func secretNames(secrets []*tlsv3.Secret) []string {
+	var names []string
+	for _, secret := range secrets {
+		names = append(names, secret.GetName())
+	}
+	return names
+}
+
+func TestCertificatesMutatorTranslation(t *testing.T) {
+	translation := &Translation{
+		Secrets: []*tlsv3.Secret{{Name: "envoy-gateway/listener"}},
+		PolicySecrets: [][]*tlsv3.Secret{
+			{{Name: "default/secret-1"}, {Name: "default/secret-1/ca.crt"}},
+			{{Name: "default/fallback"}},
+			{{Name: "default/fallback"}},
+		},
+	}
+
+	if err := (certificatesMutator{}).MutateTranslation(context.Background(), translation); err != nil {
+		t.Fatalf("MutateTranslation() error = %v", err)
+	}
+	want := []string{"envoy-gateway/listener", "default/secret-1", "default/secret-1/ca.crt", "default/fallback"}
+	if got := secretNames(translation.Secrets); !slices.Equal(got, want) {
+		t.Errorf("secrets = %v, want %v", got, want)
+	}
+}
+
+func TestCertificatesMutatorFilterChain(t *testing.T) {
+	filterChain := &FilterChain{
+		TLSContext:   &tlsv3.DownstreamTlsContext{},
+		Certificates: []ListenerCertificate{{SecretName: "default/secret-1"}, {SecretName: "default/secret-2"}},
+	}
+
+	if err := (certificatesMutator{}).MutateFilterChain(context.Background(), filterChain); err != nil {
+		t.Fatalf("MutateFilterChain() error = %v", err)
+	}
+	var got []string
+	for _, config := range filterChain.TLSContext.GetCommonTlsContext().GetTlsCertificateSdsSecretConfigs() {
+		got = append(got, config.GetName())
+	}
+	if want := []string{"default/secret-1", "default/secret-2"}; !slices.Equal(got, want) {
+		t.Errorf("SDS secret configs = %v, want %v", got, want)
+	}
+	if filterChain.TLSContext.GetCommonTlsContext().GetTlsParams() != nil {
+		t.Error("TLS parameters were set by the certificates mutator")
+	}
+}
+
+func TestTLSParamsMutator(t *testing.T) {
+	filterChain := &FilterChain{
+		TLSContext: &tlsv3.DownstreamTlsContext{},
+		Certificates: []ListenerCertificate{
+			{SecretName: "default/secret-1", TLSParams: &v1beta1.TLSParameters{MinVersion: ptr.To(v1beta1.TLSv12)}},
+			{SecretName: "default/secret-2"},
+			{SecretName: "default/secret-3", TLSParams: &v1beta1.TLSParameters{MinVersion: ptr.To(v1beta1.TLSv13)}},
+		},
+	}
+
+	if err := (tlsParamsMutator{}).MutateFilterChain(context.Background(), filterChain); err != nil {
+		t.Fatalf("MutateFilterChain() error = %v", err)
+	}
+	commonTLSContext := filterChain.TLSContext.GetCommonTlsContext()
+	if got := commonTLSContext.GetTlsParams().GetTlsMinimumProtocolVersion(); got != tlsv3.TlsParameters_TLSv1_3 {
+		t.Errorf("minimum TLS version = %v, want the one of the last certificate", got)
+	}
+	if len(commonTLSContext.GetTlsCertificateSdsSecretConfigs()) != 0 {
+		t.Error("SDS secret configs were added by the TLS parameters mutator")
+	}
+}
+
+func TestALPNMutator(t *testing.T) {
+	filterChain := &FilterChain{
+		TLSContext: &tlsv3.DownstreamTlsContext{
+			CommonTlsContext: &tlsv3.CommonTlsContext{AlpnProtocols: []string{"h2", "http/1.1"}},
+		},
+		Certificates: []ListenerCertificate{
+			{SecretName: "default/secret-1", ALPNProtocols: []v1beta1.ALPNProtocol{v1beta1.ALPNHTTP2, v1beta1.ALPNHTTP11}},
+			{SecretName: "default/secret-2", ALPNProtocols: []v1beta1.ALPNProtocol{v1beta1.ALPNHTTP11}},
+			{SecretName: "default/secret-3"},
+		},
+	}
+
+	if err := (alpnMutator{}).MutateFilterChain(context.Background(), filterChain); err != nil {
+		t.Fatalf("MutateFilterChain() error = %v", err)
+	}
+	if got := filterChain.TLSContext.GetCommonTlsContext().GetAlpnProtocols(); !slices.Equal(got, []string{"http/1.1"}) {
+		t.Errorf("ALPN protocols = %v, want those of the last certificate setting them", got)
+	}
+}
@@ -0,0 +1,97 @@
+package extensionserver
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+// eventReasonSecretNotAttached is used when a policy in Reference mode
+// refers to a Secret no Gateway listener serves and no mount path is set.
+const eventReasonSecretNotAttached = "SecretNotAttached"
+
+// deliveryMode returns how the certificate of a policy reaches Envoy.
+func deliveryMode(policy v1beta1.CertificatePolicy) v1beta1.CertificateDeliveryMode {
+	if policy.Spec.Delivery == nil || policy.Spec.Delivery.Mode == "" {
+		return v1beta1.DeliveryInline
+	}
+	return policy.Spec.Delivery.Mode
+}
+
+// deliverPolicySecrets returns the Envoy secrets to serve for a policy: those
+// of its certificates, followed by its session ticket keys, which are
+// inlined in every delivery mode.
+func (s *Server) deliverPolicySecrets(ctx context.Context, policy v1beta1.CertificatePolicy, existing map[string]*tlsv3.Secret) ([]*tlsv3.Secret, error) {
+	secrets, err := s.deliverCertificates(ctx, policy, existing)
+	if err != nil {
+		return nil, err
+	}
+	ticketKeys, err := s.resolveSessionTicketKeys(ctx, policy)
+	if err != nil {
+		return nil, err
+	}
+	if ticketKeys != nil {
+		secrets = append(secrets, ticketKeys)
+	}
+	return secrets, nil
+}
+
+// deliverCertificates returns the Envoy secrets of the certificates of a
+// policy in its delivery mode. existing are the secrets of the translation by
+// name. Only the Inline mode reads the Secret; the others never put key
+// material into the response. The first returned secret is the TLS
+// certificate listeners must reference, the Inline mode adds those of the
+// additional Secrets.
+func (s *Server) deliverCertificates(ctx context.Context, policy v1beta1.CertificatePolicy, existing map[string]*tlsv3.Secret) ([]*tlsv3.Secret, error) {
+	name := SecretName(policy.Namespace, policy.Spec.SecretRef.Name)
+	switch deliveryMode(policy) {
+	case v1beta1.DeliveryReference:
+		// Envoy Gateway names the secrets of listener certificateRefs after
+		// the Secret as well.
+		if secret, ok := existing[name]; ok {
+			return []*tlsv3.Secret{secret}, nil
+		}
+		if policy.Spec.Delivery.MountPath == "" {
+			err := fmt.Errorf("secret %s is not served on a Gateway listener and no mountPath is set", name)
+			s.recordPolicyEvent(policy, corev1.EventTypeWarning, eventReasonSecretNotAttached,
+				"Secret %s is not served on a Gateway listener and no mountPath is set", policy.Spec.SecretRef.Name)
+			return nil, err
+		}
+		return []*tlsv3.Secret{filenameSecret(name, policy.Spec.Delivery.MountPath)}, nil
+	case v1beta1.DeliveryFilename:
+		return []*tlsv3.Secret{filenameSecret(name, policy.Spec.Delivery.MountPath)}, nil
+	default:
+		return s.resolveCertificates(ctx, policy)
+	}
+}
+
+// filenameSecret returns an Envoy secret reading the certificate and private
+// key of a Secret mounted at mountPath. Envoy reloads them when the
+// directory changes, e.g. when the kubelet updates the mounted Secret.
+func filenameSecret(name, mountPath string) *tlsv3.Secret {
+	return &tlsv3.Secret{
+		Name: name,
+		Type: &tlsv3.Secret_TlsCertificate{
+			TlsCertificate: &tlsv3.TlsCertificate{
+				CertificateChain: filename(path.Join(mountPath, corev1.TLSCertKey)),
+				PrivateKey:       filename(path.Join(mountPath, corev1.TLSPrivateKeyKey)),
+				WatchedDirectory: &corev3.WatchedDirectory{Path: mountPath},
+			},
+		},
+	}
+}
+
+// filename wraps a path into a file-based Envoy DataSource.
+func filename(path string) *corev3.DataSource {
+	return &corev3.DataSource{
+		Specifier: &corev3.DataSource_Filename{
+			Filename: path,
+		},
+	}
+}
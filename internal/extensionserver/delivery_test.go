@@ -0,0 +1,145 @@
+package extensionserver
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	pb "github.com/envoyproxy/gateway/proto/extension"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"google.golang.org/protobuf/proto"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+func createDeliveryPolicy(secretName string, mode v1beta1.CertificateDeliveryMode, mountPath string) v1beta1.CertificatePolicy {
+	policy := createPolicy(secretName)
+	policy.Spec.Delivery = &v1beta1.CertificateDelivery{Mode: mode, MountPath: mountPath}
+	return policy
+}
+
+func TestDeliverPolicySecrets(t *testing.T) {
+	attached := &tlsv3.Secret{Name: "default/attached"}
+	existing := map[string]*tlsv3.Secret{attached.Name: attached}
+
+	tests := []struct {
+		name      string
+		policy    v1beta1.CertificatePolicy
+		want      *tlsv3.Secret
+		wantEvent string
+	}{
+		{
+			name:   "inline reads the secret",
+			policy: createPolicy("secret-1"),
+			want: &tlsv3.Secret{Name: "default/secret-1", Type: &tlsv3.Secret_TlsCertificate{TlsCertificate: &tlsv3.TlsCertificate{
+				CertificateChain: inlineBytes([]byte("cert")),
+				PrivateKey:       inlineBytes([]byte("key")),
+			}}},
+		},
+		{
+			name:   "reference to a secret served on a listener",
+			policy: createDeliveryPolicy("attached", v1beta1.DeliveryReference, "/etc/envoy/certs/attached"),
+			want:   attached,
+		},
+		{
+			name:   "reference to a mounted secret",
+			policy: createDeliveryPolicy("mounted", v1beta1.DeliveryReference, "/etc/envoy/certs/mounted"),
+			want:   filenameSecret("default/mounted", "/etc/envoy/certs/mounted"),
+		},
+		{
+			name:      "reference to a secret neither served nor mounted",
+			policy:    createDeliveryPolicy("missing", v1beta1.DeliveryReference, ""),
+			wantEvent: "Warning SecretNotAttached Secret missing is not served on a Gateway listener",
+		},
+		{
+			name:   "filename does not read the secret",
+			policy: createDeliveryPolicy("missing", v1beta1.DeliveryFilename, "/etc/envoy/certs/missing"),
+			want:   filenameSecret("default/missing", "/etc/envoy/certs/missing"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			server := newTestServerWithOptions([]Option{WithEventRecorder(recorder)}, createTLSSecret("secret-1", nil))
+
+			secrets, err := server.deliverPolicySecrets(context.Background(), tt.policy, existing)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("deliverPolicySecrets() = %v, want an error", secrets)
+				}
+			} else if err != nil {
+				t.Fatalf("deliverPolicySecrets() error = %v", err)
+			} else if !proto.Equal(secrets[0], tt.want) {
+				t.Errorf("deliverPolicySecrets() = %v, want %v", secrets[0], tt.want)
+			}
+
+			events := drainEvents(recorder)
+			if tt.wantEvent != "" && !slices.ContainsFunc(events, func(event string) bool { return strings.HasPrefix(event, tt.wantEvent) }) {
+				t.Errorf("events = %v, want %q", events, tt.wantEvent)
+			}
+		})
+	}
+}
+
+func TestFilenameSecret(t *testing.T) {
+	certificate := filenameSecret("default/mounted", "/etc/envoy/certs/mounted").GetTlsCertificate()
+	if got := certificate.GetCertificateChain().GetFilename(); got != "/etc/envoy/certs/mounted/tls.crt" {
+		t.Errorf("certificate chain file = %q", got)
+	}
+	if got := certificate.GetPrivateKey().GetFilename(); got != "/etc/envoy/certs/mounted/tls.key" {
+		t.Errorf("private key file = %q", got)
+	}
+	if got := certificate.GetWatchedDirectory().GetPath(); got != "/etc/envoy/certs/mounted" {
+		t.Errorf("watched directory = %q", got)
+	}
+}
+
+func TestListenerCertificatesDoNotReadDeliveredSecrets(t *testing.T) {
+	server := newTestServerWithOptions([]Option{WithStrictMode(true)})
+	policies := []v1beta1.CertificatePolicy{
+		createDeliveryPolicy("mounted", v1beta1.DeliveryFilename, "/etc/envoy/certs/mounted"),
+		createPolicy("missing"),
+	}
+
+	certificates, err := server.listenerCertificates(context.Background(), policies)
+	if err != nil {
+		t.Fatalf("listenerCertificates() error = %v", err)
+	}
+	if len(certificates) != 1 || certificates[0].SecretName != "default/mounted" {
+		t.Errorf("listenerCertificates() = %v, want only default/mounted", certificates)
+	}
+}
+
+func TestReferencedSecretIsNotAttachedTwice(t *testing.T) {
+	server := newTestServerWithOptions(nil)
+	listener := newHTTPSListener(t, "default/gateway-1/https")
+	listener.FilterChains[0].TransportSocket.ConfigType = &corev3.TransportSocket_TypedConfig{
+		TypedConfig: mustAny(t, &tlsv3.DownstreamTlsContext{
+			CommonTlsContext: &tlsv3.CommonTlsContext{
+				TlsCertificateSdsSecretConfigs: []*tlsv3.SdsSecretConfig{NewSdsSecretConfig("default/attached")},
+			},
+		}),
+	}
+	data, err := json.Marshal(createDeliveryPolicy("attached", v1beta1.DeliveryReference, ""))
+	if err != nil {
+		t.Fatalf("failed to marshal policy: %v", err)
+	}
+
+	resp, err := server.PostHTTPListenerModify(context.Background(), &pb.PostHTTPListenerModifyRequest{
+		Listener: listener,
+		PostListenerContext: &pb.PostHTTPListenerExtensionContext{
+			ExtensionResources: []*pb.ExtensionResource{{UnstructuredBytes: data}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("PostHTTPListenerModify() error = %v", err)
+	}
+	if got := listenerSecretReferences(resp.Listener); !slices.Equal(got, []string{"default/attached"}) {
+		t.Errorf("SDS references = %v, want default/attached once", got)
+	}
+}
@@ -0,0 +1,88 @@
+package extensionserver
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// The hooks distinguish two kinds of errors:
+//
+//   - Errors caused by a single policy, such as a missing or invalid Secret,
+//     are permanent. The policy is skipped and the problem is reported as an
+//     Event, so that the other policies keep working.
+//   - Errors that are expected to resolve without changes to the policies,
+//     such as an unavailable API server, are transient. The hook fails with
+//     codes.Unavailable, so that Envoy Gateway retries it or keeps the
+//     previous configuration according to its failOpen setting, instead of
+//     receiving a translation with certificates missing.
+//
+// Errors in the request itself fail the hook with codes.InvalidArgument.
+
+// hookError is an error that fails a hook with a specific gRPC status code.
+type hookError struct {
+	code codes.Code
+	err  error
+}
+
+func (e *hookError) Error() string {
+	return e.err.Error()
+}
+
+func (e *hookError) Unwrap() error {
+	return e.err
+}
+
+// transient marks an error as expected to resolve on retry.
+func transient(err error) error {
+	return &hookError{code: codes.Unavailable, err: err}
+}
+
+// invalidRequest marks an error as caused by the hook request.
+func invalidRequest(err error) error {
+	return &hookError{code: codes.InvalidArgument, err: err}
+}
+
+// isTransient reports whether an error must fail the hook rather than skip
+// the policy it occurred for.
+func isTransient(err error) bool {
+	var hookErr *hookError
+	return errors.As(err, &hookErr) && hookErr.code == codes.Unavailable
+}
+
+// classifyAPIError marks errors of Kubernetes API calls as transient unless
+// they are caused by the requested object, e.g. because it does not exist or
+// access to it is forbidden.
+func classifyAPIError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return transient(err)
+	}
+	var apiStatus apierrors.APIStatus
+	if !errors.As(err, &apiStatus) {
+		// The request did not reach the API server.
+		return transient(err)
+	}
+	if apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err) || apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServiceUnavailable(err) || apierrors.IsInternalError(err) || apierrors.IsUnexpectedServerError(err) {
+		return transient(err)
+	}
+	return err
+}
+
+// statusError converts an error failing a hook into a gRPC status error.
+func statusError(err error) error {
+	code := codes.Internal
+	var hookErr *hookError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	case errors.As(err, &hookErr):
+		code = hookErr.code
+	}
+	return status.Error(code, err.Error())
+}
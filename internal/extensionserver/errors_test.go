@@ -0,0 +1,226 @@
+package extensionserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"testing"
+
+	pb "github.com/envoyproxy/gateway/proto/extension"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	quicv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/quic/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+var secretsResource = schema.GroupResource{Resource: "secrets"}
+
+func TestClassifyAPIError(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		wantTransient bool
+	}{
+		{name: "not found", err: apierrors.NewNotFound(secretsResource, "secret-1")},
+		{name: "forbidden", err: apierrors.NewForbidden(secretsResource, "secret-1", errors.New("denied"))},
+		{name: "service unavailable", err: apierrors.NewServiceUnavailable("down"), wantTransient: true},
+		{name: "too many requests", err: apierrors.NewTooManyRequests("slow down", 1), wantTransient: true},
+		{name: "server timeout", err: apierrors.NewServerTimeout(secretsResource, "get", 1), wantTransient: true},
+		{name: "internal error", err: apierrors.NewInternalError(errors.New("boom")), wantTransient: true},
+		{name: "connection refused", err: errors.New("dial tcp 10.0.0.1:443: connect: connection refused"), wantTransient: true},
+		{name: "deadline exceeded", err: fmt.Errorf("get: %w", context.DeadlineExceeded), wantTransient: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyAPIError(tt.err)
+			if got := isTransient(err); got != tt.wantTransient {
+				t.Errorf("isTransient(classifyAPIError(%v)) = %v, want %v", tt.err, got, tt.wantTransient)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("classifyAPIError() does not wrap %v", tt.err)
+			}
+		})
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode codes.Code
+	}{
+		{name: "unclassified", err: errors.New("boom"), wantCode: codes.Internal},
+		{name: "transient", err: transient(errors.New("down")), wantCode: codes.Unavailable},
+		{name: "invalid request", err: fmt.Errorf("listener: %w", invalidRequest(errors.New("bad"))), wantCode: codes.InvalidArgument},
+		{name: "deadline exceeded", err: transient(context.DeadlineExceeded), wantCode: codes.DeadlineExceeded},
+		{name: "canceled", err: transient(context.Canceled), wantCode: codes.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := statusError(tt.err)
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("status.Code() = %v, want %v", got, tt.wantCode)
+			}
+			if got := status.Convert(err).Message(); got != tt.err.Error() {
+				t.Errorf("status message = %q, want %q", got, tt.err.Error())
+			}
+		})
+	}
+}
+
+// newUnavailableServer returns a Server whose Kubernetes API is unavailable.
+func newUnavailableServer(opts ...Option) *Server {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1beta1.AddToScheme(scheme))
+
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(createTLSSecret("fallback", nil)).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				if key.Name == "fallback" {
+					return c.Get(ctx, key, obj, opts...)
+				}
+				return apierrors.NewServiceUnavailable("the API server is shutting down")
+			},
+		}).
+		Build()
+	return New(slog.New(slog.NewTextHandler(os.Stdout, nil)), k8sClient, opts...)
+}
+
+func TestHooksFailWhenSecretsAreUnavailable(t *testing.T) {
+	policies := []*pb.ExtensionResource{createExtensionResource(t, "secret-1")}
+
+	t.Run("PostTranslateModify", func(t *testing.T) {
+		// The fallback certificate must not replace a certificate that is
+		// only temporarily unavailable.
+		server := newUnavailableServer(WithFallbackSecret(types.NamespacedName{Namespace: "default", Name: "fallback"}))
+		_, err := server.PostTranslateModify(context.Background(), &pb.PostTranslateModifyRequest{
+			PostTranslateContext: &pb.PostTranslateExtensionContext{ExtensionResources: policies},
+		})
+		if got := status.Code(err); got != codes.Unavailable {
+			t.Errorf("PostTranslateModify() code = %v, want %v (error %v)", got, codes.Unavailable, err)
+		}
+	})
+
+	t.Run("PostHTTPListenerModify", func(t *testing.T) {
+		server := newUnavailableServer(WithStrictMode(true))
+		_, err := server.PostHTTPListenerModify(context.Background(), &pb.PostHTTPListenerModifyRequest{
+			Listener:            newHTTPSListener(t, "default/gateway-1/https"),
+			PostListenerContext: &pb.PostHTTPListenerExtensionContext{ExtensionResources: policies},
+		})
+		if got := status.Code(err); got != codes.Unavailable {
+			t.Errorf("PostHTTPListenerModify() code = %v, want %v (error %v)", got, codes.Unavailable, err)
+		}
+	})
+}
+
+func TestPostHTTPListenerModifyInvalidRequests(t *testing.T) {
+	policies := []*pb.ExtensionResource{createExtensionResource(t, "secret-1")}
+	corruptTLSContext := &corev3.TransportSocket{
+		Name: "envoy.transport_sockets.tls",
+		ConfigType: &corev3.TransportSocket_TypedConfig{TypedConfig: &anypb.Any{
+			TypeUrl: "type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.DownstreamTlsContext",
+			Value:   []byte("not a protobuf message"),
+		}},
+	}
+
+	tests := []struct {
+		name     string
+		listener *listenerv3.Listener
+	}{
+		{
+			name: "no listener",
+		},
+		{
+			name: "corrupt TLS context",
+			listener: &listenerv3.Listener{
+				Name:         "default/gateway-1/https",
+				FilterChains: []*listenerv3.FilterChain{{TransportSocket: corruptTLSContext}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestServer().PostHTTPListenerModify(context.Background(), &pb.PostHTTPListenerModifyRequest{
+				Listener:            tt.listener,
+				PostListenerContext: &pb.PostHTTPListenerExtensionContext{ExtensionResources: policies},
+			})
+			if got := status.Code(err); got != codes.InvalidArgument {
+				t.Errorf("PostHTTPListenerModify() code = %v, want %v (error %v)", got, codes.InvalidArgument, err)
+			}
+		})
+	}
+}
+
+func TestPostHTTPListenerModifyServesQUICFilterChains(t *testing.T) {
+	quicTransport := &corev3.TransportSocket{
+		Name: "envoy.transport_sockets.quic",
+		ConfigType: &corev3.TransportSocket_TypedConfig{
+			TypedConfig: mustAny(t, &quicv3.QuicDownstreamTransport{
+				DownstreamTlsContext: &tlsv3.DownstreamTlsContext{
+					CommonTlsContext: &tlsv3.CommonTlsContext{AlpnProtocols: []string{"h3"}},
+				},
+				EnableEarlyData: wrapperspb.Bool(false),
+			}),
+		},
+	}
+	listener := &listenerv3.Listener{
+		Name:         "default/gateway-1/https-quic",
+		FilterChains: []*listenerv3.FilterChain{{TransportSocket: quicTransport}},
+	}
+	policy := createPolicy("secret-1")
+	policy.Spec.ALPNProtocols = []v1beta1.ALPNProtocol{v1beta1.ALPNHTTP11}
+	policy.Spec.TLSParams = &v1beta1.TLSParameters{MaxVersion: ptr.To(v1beta1.TLSv12)}
+
+	resp, err := newTestServer().PostHTTPListenerModify(context.Background(), &pb.PostHTTPListenerModifyRequest{
+		Listener: listener,
+		PostListenerContext: &pb.PostHTTPListenerExtensionContext{
+			ExtensionResources: []*pb.ExtensionResource{marshalExtensionResource(t, policy)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("PostHTTPListenerModify() error = %v", err)
+	}
+
+	var got quicv3.QuicDownstreamTransport
+	if err := resp.Listener.FilterChains[0].TransportSocket.GetTypedConfig().UnmarshalTo(&got); err != nil {
+		t.Fatalf("transport socket is no longer a QUIC transport: %v", err)
+	}
+	if got.GetEnableEarlyData() == nil {
+		t.Error("QUIC settings were dropped")
+	}
+	commonTLSContext := got.GetDownstreamTlsContext().GetCommonTlsContext()
+	if configs := commonTLSContext.GetTlsCertificateSdsSecretConfigs(); len(configs) != 1 || configs[0].GetName() != "default/secret-1" {
+		t.Errorf("SDS secret configs = %v, want default/secret-1", configs)
+	}
+	if alpn := commonTLSContext.GetAlpnProtocols(); !slices.Equal(alpn, []string{"h3"}) {
+		t.Errorf("ALPN protocols = %v, want h3 kept", alpn)
+	}
+	if commonTLSContext.GetTlsParams() != nil {
+		t.Error("TLS parameters were applied to a QUIC filter chain")
+	}
+}
@@ -0,0 +1,149 @@
+package extensionserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+const (
+	// eventReasonProgrammed is used when the policy's certificate is sent to Envoy.
+	eventReasonProgrammed = "Programmed"
+
+	// eventReasonSecretFetchFailed is used when the policy's Secret cannot be read.
+	eventReasonSecretFetchFailed = "SecretFetchFailed"
+
+	// eventReasonInvalidSecret is used when the policy's Secret lacks required keys.
+	eventReasonInvalidSecret = "InvalidSecret"
+
+	// eventReasonFallbackServed is used when a fallback certificate is served
+	// in place of the policy's Secret.
+	eventReasonFallbackServed = "FallbackCertificateServed"
+
+	// eventReasonTargetNotResolved is used when a targetRef does not point at
+	// an existing Gateway listener.
+	eventReasonTargetNotResolved = "TargetNotResolved"
+
+	// gatewayKind is the only kind CertificatePolicies can target.
+	gatewayKind = "Gateway"
+)
+
+// recordPolicyEvent emits an event on the policy and on every Gateway it targets.
+func (s *Server) recordPolicyEvent(policy v1beta1.CertificatePolicy, eventType, reason, messageFmt string, args ...any) {
+	if s.recorder == nil {
+		return
+	}
+
+	message := fmt.Sprintf(messageFmt, args...)
+	s.recorder.Event(policyReference(policy), eventType, reason, message)
+
+	gatewayMessage := fmt.Sprintf("CertificatePolicy %s/%s: %s", policy.Namespace, policy.Name, message)
+	for _, ref := range gatewayReferences(policy) {
+		s.recorder.Event(ref, eventType, reason, gatewayMessage)
+	}
+}
+
+// recordSecretError emits an event describing why a Secret of the policy
+// could not be served.
+func (s *Server) recordSecretError(policy v1beta1.CertificatePolicy, secretName string, err error) {
+	reason := eventReasonInvalidSecret
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		reason = eventReasonSecretFetchFailed
+	}
+	s.recordPolicyEvent(policy, corev1.EventTypeWarning, reason, "Secret %s cannot be served: %v", secretName, err)
+}
+
+// checkTargets emits an event for every targetRef of the policy that does not
+// resolve to an existing Gateway listener.
+func (s *Server) checkTargets(ctx context.Context, policy v1beta1.CertificatePolicy) {
+	if s.recorder == nil {
+		return
+	}
+
+	for _, ref := range policy.Spec.TargetRefs {
+		if string(ref.Group) != gwapiv1.GroupName || string(ref.Kind) != gatewayKind {
+			s.recordPolicyEvent(policy, corev1.EventTypeWarning, eventReasonTargetNotResolved,
+				"targetRef %s/%s %s is not a Gateway", ref.Group, ref.Kind, ref.Name)
+			continue
+		}
+
+		var gateway gwapiv1.Gateway
+		key := types.NamespacedName{Namespace: policy.Namespace, Name: string(ref.Name)}
+		if err := s.getGateway(ctx, key, &gateway); err != nil {
+			if apierrors.IsNotFound(err) {
+				s.recordPolicyEvent(policy, corev1.EventTypeWarning, eventReasonTargetNotResolved,
+					"Gateway %s not found", ref.Name)
+				continue
+			}
+			s.logger(ctx).Error("failed to get target Gateway", "policy", policy.Name, "gateway", key.String(), "error", err)
+			continue
+		}
+
+		if ref.SectionName != nil && !hasListener(gateway, *ref.SectionName) {
+			s.recordPolicyEvent(policy, corev1.EventTypeWarning, eventReasonTargetNotResolved,
+				"Gateway %s has no listener %s", ref.Name, *ref.SectionName)
+		}
+	}
+}
+
+// getGateway reads a target Gateway of a policy.
+func (s *Server) getGateway(ctx context.Context, key types.NamespacedName, gateway *gwapiv1.Gateway) (err error) {
+	ctx, span := s.startSpan(ctx, "getGateway",
+		attributeNamespace.String(key.Namespace),
+		attributeGatewayName.String(key.Name),
+	)
+	defer func() { endSpan(span, err) }()
+	return s.client.Get(ctx, key, gateway)
+}
+
+// hasListener reports whether the Gateway has a listener with the given name.
+func hasListener(gateway gwapiv1.Gateway, name gwapiv1.SectionName) bool {
+	for _, listener := range gateway.Spec.Listeners {
+		if listener.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// policyReference returns the object reference events about the policy are attached to.
+func policyReference(policy v1beta1.CertificatePolicy) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion:      v1beta1.GroupVersion.String(),
+		Kind:            "CertificatePolicy",
+		Namespace:       policy.Namespace,
+		Name:            policy.Name,
+		UID:             policy.UID,
+		ResourceVersion: policy.ResourceVersion,
+	}
+}
+
+// gatewayReferences returns object references to the Gateways the policy targets.
+func gatewayReferences(policy v1beta1.CertificatePolicy) []*corev1.ObjectReference {
+	var refs []*corev1.ObjectReference
+	seen := map[gwapiv1.ObjectName]struct{}{}
+	for _, ref := range policy.Spec.TargetRefs {
+		if string(ref.Group) != gwapiv1.GroupName || string(ref.Kind) != gatewayKind {
+			continue
+		}
+		if _, ok := seen[ref.Name]; ok {
+			continue
+		}
+		seen[ref.Name] = struct{}{}
+		refs = append(refs, &corev1.ObjectReference{
+			APIVersion: gwapiv1.GroupVersion.String(),
+			Kind:       gatewayKind,
+			Namespace:  policy.Namespace,
+			Name:       string(ref.Name),
+		})
+	}
+	return refs
+}
@@ -0,0 +1,207 @@
+package extensionserver
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	pb "github.com/envoyproxy/gateway/proto/extension"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+func TestPostTranslateModifyEvents(t *testing.T) {
+	gateway := &gwapiv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "gateway-1", Namespace: "default"},
+		Spec: gwapiv1.GatewaySpec{
+			Listeners: []gwapiv1.Listener{{Name: "https"}},
+		},
+	}
+	invalidSecret := createTLSSecret("invalid", nil)
+	delete(invalidSecret.Data, corev1.TLSCertKey)
+	fallbackSecret := createTLSSecret("fallback", nil)
+
+	tests := []struct {
+		name       string
+		policy     v1beta1.CertificatePolicy
+		objs       []client.Object
+		wantEvents []string
+	}{
+		{
+			name:   "programmed",
+			policy: createTargetingPolicy("secret-1", gatewayTargetRef("gateway-1", "https")),
+			objs:   []client.Object{gateway, createTLSSecret("secret-1", nil)},
+			wantEvents: []string{
+				"Normal Programmed Certificate from Secret secret-1",
+				"Normal Programmed CertificatePolicy default/test-policy: Certificate from Secret secret-1",
+			},
+		},
+		{
+			name:   "secret not found",
+			policy: createTargetingPolicy("missing", gatewayTargetRef("gateway-1", "")),
+			objs:   []client.Object{gateway},
+			wantEvents: []string{
+				"Warning SecretFetchFailed Secret missing cannot be served",
+				"Warning SecretFetchFailed CertificatePolicy default/test-policy: Secret missing cannot be served",
+			},
+		},
+		{
+			name:   "secret missing keys",
+			policy: createTargetingPolicy("invalid", gatewayTargetRef("gateway-1", "")),
+			objs:   []client.Object{gateway, invalidSecret},
+			wantEvents: []string{
+				"Warning InvalidSecret Secret invalid cannot be served",
+				"Warning InvalidSecret CertificatePolicy default/test-policy: Secret invalid cannot be served",
+			},
+		},
+		{
+			name: "fallback served",
+			policy: func() v1beta1.CertificatePolicy {
+				policy := createTargetingPolicy("missing", gatewayTargetRef("gateway-1", ""))
+				policy.Spec.FallbackSecretRef = &v1beta1.SecretReference{Name: "fallback"}
+				return policy
+			}(),
+			objs: []client.Object{gateway, fallbackSecret},
+			wantEvents: []string{
+				"Warning SecretFetchFailed Secret missing cannot be served",
+				"Warning SecretFetchFailed CertificatePolicy default/test-policy: Secret missing cannot be served",
+				"Warning FallbackCertificateServed Serving fallback certificate from Secret default/fallback",
+				"Warning FallbackCertificateServed CertificatePolicy default/test-policy: Serving fallback certificate",
+			},
+		},
+		{
+			name:   "gateway not found",
+			policy: createTargetingPolicy("secret-1", gatewayTargetRef("gateway-2", "")),
+			objs:   []client.Object{createTLSSecret("secret-1", nil)},
+			wantEvents: []string{
+				"Warning TargetNotResolved Gateway gateway-2 not found",
+				"Warning TargetNotResolved CertificatePolicy default/test-policy: Gateway gateway-2 not found",
+				"Normal Programmed Certificate from Secret secret-1",
+				"Normal Programmed CertificatePolicy default/test-policy: Certificate from Secret secret-1",
+			},
+		},
+		{
+			name:   "listener not found",
+			policy: createTargetingPolicy("secret-1", gatewayTargetRef("gateway-1", "http")),
+			objs:   []client.Object{gateway, createTLSSecret("secret-1", nil)},
+			wantEvents: []string{
+				"Warning TargetNotResolved Gateway gateway-1 has no listener http",
+				"Warning TargetNotResolved CertificatePolicy default/test-policy: Gateway gateway-1 has no listener http",
+				"Normal Programmed Certificate from Secret secret-1",
+				"Normal Programmed CertificatePolicy default/test-policy: Certificate from Secret secret-1",
+			},
+		},
+		{
+			name: "target is not a gateway",
+			policy: createTargetingPolicy("secret-1", gwapiv1.LocalPolicyTargetReferenceWithSectionName{
+				LocalPolicyTargetReference: gwapiv1.LocalPolicyTargetReference{
+					Group: gwapiv1.GroupName,
+					Kind:  "HTTPRoute",
+					Name:  "route-1",
+				},
+			}),
+			objs: []client.Object{createTLSSecret("secret-1", nil)},
+			wantEvents: []string{
+				"Warning TargetNotResolved targetRef gateway.networking.k8s.io/HTTPRoute route-1 is not a Gateway",
+				"Normal Programmed Certificate from Secret secret-1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			server := newTestServerWithOptions([]Option{WithEventRecorder(recorder)}, tt.objs...)
+
+			_, err := server.PostTranslateModify(context.Background(), &pb.PostTranslateModifyRequest{
+				PostTranslateContext: &pb.PostTranslateExtensionContext{
+					ExtensionResources: []*pb.ExtensionResource{marshalExtensionResource(t, tt.policy)},
+				},
+			})
+			if err != nil {
+				t.Fatalf("PostTranslateModify() error = %v", err)
+			}
+
+			events := drainEvents(recorder)
+			if len(events) != len(tt.wantEvents) {
+				t.Fatalf("got events %q, want %d events", events, len(tt.wantEvents))
+			}
+			for i, want := range tt.wantEvents {
+				if !strings.HasPrefix(events[i], want) {
+					t.Errorf("event[%d] = %q, want prefix %q", i, events[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestRecordPolicyEventWithoutRecorder(t *testing.T) {
+	server := newTestServer()
+	// Must not panic without a recorder.
+	server.recordPolicyEvent(createPolicy("secret-1"), corev1.EventTypeNormal, eventReasonProgrammed, "message")
+	server.checkTargets(context.Background(), createPolicy("secret-1"))
+}
+
+func TestGatewayReferencesAreUnique(t *testing.T) {
+	policy := createTargetingPolicy("secret-1",
+		gatewayTargetRef("gateway-1", "https"),
+		gatewayTargetRef("gateway-1", "http"),
+		gatewayTargetRef("gateway-2", ""),
+	)
+
+	refs := gatewayReferences(policy)
+	if len(refs) != 2 {
+		t.Fatalf("got %d references, want 2", len(refs))
+	}
+	if refs[0].Name != "gateway-1" || refs[1].Name != "gateway-2" {
+		t.Errorf("got references to %q and %q, want gateway-1 and gateway-2", refs[0].Name, refs[1].Name)
+	}
+}
+
+func createTargetingPolicy(secretName string, targetRefs ...gwapiv1.LocalPolicyTargetReferenceWithSectionName) v1beta1.CertificatePolicy {
+	policy := createPolicy(secretName)
+	policy.Spec.TargetRefs = targetRefs
+	return policy
+}
+
+func gatewayTargetRef(name, sectionName string) gwapiv1.LocalPolicyTargetReferenceWithSectionName {
+	ref := gwapiv1.LocalPolicyTargetReferenceWithSectionName{
+		LocalPolicyTargetReference: gwapiv1.LocalPolicyTargetReference{
+			Group: gwapiv1.GroupName,
+			Kind:  "Gateway",
+			Name:  gwapiv1.ObjectName(name),
+		},
+	}
+	if sectionName != "" {
+		section := gwapiv1.SectionName(sectionName)
+		ref.SectionName = &section
+	}
+	return ref
+}
+
+func marshalExtensionResource(t *testing.T, policy v1beta1.CertificatePolicy) *pb.ExtensionResource {
+	t.Helper()
+	data, err := json.Marshal(policy)
+	if err != nil {
+		t.Fatalf("failed to marshal policy: %v", err)
+	}
+	return &pb.ExtensionResource{UnstructuredBytes: data}
+}
+
+func drainEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package extensionserver
+
+import (
+	"context"
+
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+// resolvePolicySecrets returns the Envoy secrets to serve for a policy. When
+// the policy's Secret is missing or invalid, the policy's fallback Secret and
+// then the global fallback Secret are tried, unless strict mode is enabled.
+// Transient errors are returned right away, so that a certificate is not
+// replaced by a fallback while the API server is unavailable.
+// The first returned secret is the TLS certificate listeners must reference.
+func (s *Server) resolvePolicySecrets(ctx context.Context, policy v1beta1.CertificatePolicy) ([]*tlsv3.Secret, error) {
+	secrets, err := s.fetchAndConvertSecret(ctx, policy)
+	if err == nil || isTransient(err) {
+		return secrets, err
+	}
+	s.recordSecretError(policy, policy.Spec.SecretRef.Name, err)
+	if s.strict {
+		return nil, err
+	}
+
+	for _, fallback := range s.fallbackSecrets(policy) {
+		fallbackSecrets, fallbackErr := s.fetchAndConvertNamedSecret(ctx, fallback, nil)
+		if isTransient(fallbackErr) {
+			return nil, fallbackErr
+		}
+		if fallbackErr != nil {
+			s.logger(ctx).Error("failed to fetch fallback secret for policy",
+				"policy", policy.Name,
+				"fallbackSecret", fallback.String(),
+				"error", fallbackErr,
+			)
+			continue
+		}
+
+		s.logger(ctx).Warn("serving fallback certificate for policy",
+			"policy", policy.Name,
+			"secretName", policy.Spec.SecretRef.Name,
+			"fallbackSecret", fallback.String(),
+			"error", err,
+		)
+		s.recordPolicyEvent(policy, corev1.EventTypeWarning, eventReasonFallbackServed,
+			"Serving fallback certificate from Secret %s in place of Secret %s", fallback.String(), policy.Spec.SecretRef.Name)
+		return fallbackSecrets, nil
+	}
+
+	return nil, err
+}
+
+// fallbackSecrets returns the fallback Secrets of a policy in order of
+// preference.
+func (s *Server) fallbackSecrets(policy v1beta1.CertificatePolicy) []types.NamespacedName {
+	var fallbacks []types.NamespacedName
+	if policy.Spec.FallbackSecretRef != nil {
+		fallbacks = append(fallbacks, types.NamespacedName{
+			Namespace: policy.Namespace,
+			Name:      policy.Spec.FallbackSecretRef.Name,
+		})
+	}
+	if s.fallbackSecret.Name != "" {
+		fallbacks = append(fallbacks, s.fallbackSecret)
+	}
+	return fallbacks
+}
+
+// listenerCertificates returns the certificates listeners should reference
+// for the given policies, along with the session ticket keys of each policy.
+//
+// The Secrets of the certificates are only read when fallback certificates
+// or strict mode make the reference depend on whether they can be read.
+// Otherwise the policy's Secrets are referenced by name, and
+// PostTranslateModify removes the references to those it does not serve and
+// keeps one certificate per key algorithm on every filter chain. Secrets that
+// are not inlined are never read. Only transient errors are returned.
+func (s *Server) listenerCertificates(ctx context.Context, policies []v1beta1.CertificatePolicy) ([]ListenerCertificate, error) {
+	results := s.resolveAll(ctx, policies, func(ctx context.Context, policy v1beta1.CertificatePolicy) ([]*tlsv3.Secret, error) {
+		secrets, err := s.listenerPolicySecrets(ctx, policy)
+		if err != nil {
+			return nil, err
+		}
+		ticketKeys, err := s.resolveSessionTicketKeys(ctx, policy)
+		if err != nil {
+			return nil, err
+		}
+		if ticketKeys != nil {
+			secrets = append(secrets, ticketKeys)
+		}
+		return secrets, nil
+	})
+
+	var certificates []ListenerCertificate
+	for i, policy := range policies {
+		secrets, err := results[i].secrets, results[i].err
+		if s.skipOnTimeout(ctx, policy, err) {
+			continue
+		}
+		if isTransient(err) {
+			return nil, err
+		}
+		if err != nil {
+			s.logger(ctx).Error("omitting SDS reference for policy",
+				"policy", policy.Name,
+				"secretName", policy.Spec.SecretRef.Name,
+				"error", err,
+			)
+			continue
+		}
+		secrets, _ = pairCertificates(secrets)
+		var sessionTicketKeys string
+		for _, secret := range secrets {
+			if secret.GetSessionTicketKeys() != nil {
+				sessionTicketKeys = secret.GetName()
+			}
+		}
+		for _, secret := range secrets {
+			// Secrets without a type only carry the name of a certificate.
+			if secret.GetTlsCertificate() != nil || secret.GetType() == nil {
+				certificate := newListenerCertificate(policy, secret)
+				certificate.SessionTicketKeys = sessionTicketKeys
+				certificates = append(certificates, certificate)
+			}
+		}
+	}
+	return certificates, nil
+}
+
+// listenerPolicySecrets returns the Envoy secrets of the certificates of a
+// policy for listenerCertificates. Secrets that are not read only carry the
+// name of a certificate.
+func (s *Server) listenerPolicySecrets(ctx context.Context, policy v1beta1.CertificatePolicy) ([]*tlsv3.Secret, error) {
+	names := []*tlsv3.Secret{{Name: SecretName(policy.Namespace, policy.Spec.SecretRef.Name)}}
+	if deliveryMode(policy) != v1beta1.DeliveryInline {
+		// Fallbacks and additional Secrets do not apply to Secrets that are
+		// not inlined.
+		return names, nil
+	}
+	if !s.strict && len(s.fallbackSecrets(policy)) == 0 {
+		// The translation serves the Secrets once they can be read.
+		for _, ref := range policy.Spec.AdditionalSecretRefs {
+			names = append(names, &tlsv3.Secret{Name: SecretName(policy.Namespace, ref.Name)})
+		}
+		return names, nil
+	}
+	return s.resolveCertificates(ctx, policy)
+}
@@ -0,0 +1,188 @@
+package extensionserver
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	pb "github.com/envoyproxy/gateway/proto/extension"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+var globalFallback = types.NamespacedName{Namespace: "kube-system", Name: "global-fallback"}
+
+func TestResolvePolicySecrets(t *testing.T) {
+	globalFallbackSecret := createTLSSecret(globalFallback.Name, nil)
+	globalFallbackSecret.Namespace = globalFallback.Namespace
+
+	tests := []struct {
+		name               string
+		opts               []Option
+		objs               []client.Object
+		fallbackSecretName string
+		wantName           string
+		wantErr            bool
+	}{
+		{
+			name:     "valid secret is served",
+			opts:     []Option{WithFallbackSecret(globalFallback)},
+			objs:     []client.Object{createTLSSecret("secret-1", nil), globalFallbackSecret},
+			wantName: "default/secret-1",
+		},
+		{
+			name:    "missing secret without fallback",
+			wantErr: true,
+		},
+		{
+			name:     "missing secret with global fallback",
+			opts:     []Option{WithFallbackSecret(globalFallback)},
+			objs:     []client.Object{globalFallbackSecret},
+			wantName: "kube-system/global-fallback",
+		},
+		{
+			name:               "policy fallback takes precedence over global fallback",
+			opts:               []Option{WithFallbackSecret(globalFallback)},
+			objs:               []client.Object{createTLSSecret("policy-fallback", nil), globalFallbackSecret},
+			fallbackSecretName: "policy-fallback",
+			wantName:           "default/policy-fallback",
+		},
+		{
+			name:               "broken policy fallback falls through to global fallback",
+			opts:               []Option{WithFallbackSecret(globalFallback)},
+			objs:               []client.Object{globalFallbackSecret},
+			fallbackSecretName: "policy-fallback",
+			wantName:           "kube-system/global-fallback",
+		},
+		{
+			name:    "all fallbacks broken",
+			opts:    []Option{WithFallbackSecret(globalFallback)},
+			wantErr: true,
+		},
+		{
+			name:               "strict mode ignores fallbacks",
+			opts:               []Option{WithFallbackSecret(globalFallback), WithStrictMode(true)},
+			objs:               []client.Object{createTLSSecret("policy-fallback", nil), globalFallbackSecret},
+			fallbackSecretName: "policy-fallback",
+			wantErr:            true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServerWithOptions(tt.opts, tt.objs...)
+			policy := createPolicy("secret-1")
+			if tt.fallbackSecretName != "" {
+				policy.Spec.FallbackSecretRef = &v1beta1.SecretReference{Name: tt.fallbackSecretName}
+			}
+
+			secrets, err := server.resolvePolicySecrets(context.Background(), policy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolvePolicySecrets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if secrets[0].Name != tt.wantName {
+				t.Errorf("secrets[0].Name = %q, want %q", secrets[0].Name, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestListenerCertificates(t *testing.T) {
+	globalFallbackSecret := createTLSSecret(globalFallback.Name, nil)
+	globalFallbackSecret.Namespace = globalFallback.Namespace
+	policies := []v1beta1.CertificatePolicy{createPolicy("secret-1"), createPolicy("missing")}
+
+	tests := []struct {
+		name      string
+		opts      []Option
+		wantNames []string
+	}{
+		{
+			name:      "unreadable secret is still referenced without fallback or strict mode",
+			wantNames: []string{"default/secret-1", "default/missing"},
+		},
+		{
+			name:      "broken secret is replaced by the fallback",
+			opts:      []Option{WithFallbackSecret(globalFallback)},
+			wantNames: []string{"default/secret-1", "kube-system/global-fallback"},
+		},
+		{
+			name:      "broken secret is omitted in strict mode",
+			opts:      []Option{WithStrictMode(true)},
+			wantNames: []string{"default/secret-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServerWithOptions(tt.opts, createTLSSecret("secret-1", nil), globalFallbackSecret)
+
+			var names []string
+			certificates, err := server.listenerCertificates(context.Background(), policies)
+			if err != nil {
+				t.Fatalf("listenerCertificates() error = %v", err)
+			}
+			for _, certificate := range certificates {
+				names = append(names, certificate.SecretName)
+			}
+			if !slices.Equal(names, tt.wantNames) {
+				t.Errorf("listenerCertificates() secret names = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestFallbackAcrossHooks(t *testing.T) {
+	globalFallbackSecret := createTLSSecret(globalFallback.Name, nil)
+	globalFallbackSecret.Namespace = globalFallback.Namespace
+	server := newTestServerWithOptions([]Option{WithFallbackSecret(globalFallback)}, globalFallbackSecret)
+	extensions := []*pb.ExtensionResource{
+		createExtensionResource(t, "missing-1"),
+		createExtensionResource(t, "missing-2"),
+	}
+
+	listenerResp, err := server.PostHTTPListenerModify(context.Background(), &pb.PostHTTPListenerModifyRequest{
+		Listener: &listenerv3.Listener{
+			FilterChains: []*listenerv3.FilterChain{
+				{TransportSocket: createTransportSocketWithTLS(t)},
+			},
+		},
+		PostListenerContext: &pb.PostHTTPListenerExtensionContext{ExtensionResources: extensions},
+	})
+	if err != nil {
+		t.Fatalf("PostHTTPListenerModify() error = %v", err)
+	}
+
+	tlsContext, err := extractDownstreamTlsContext(listenerResp.Listener.FilterChains[0].TransportSocket)
+	if err != nil {
+		t.Fatalf("failed to extract TLS context: %v", err)
+	}
+	referenced := map[string]bool{}
+	for _, config := range tlsContext.GetCommonTlsContext().GetTlsCertificateSdsSecretConfigs() {
+		referenced[config.GetName()] = true
+	}
+
+	translateResp, err := server.PostTranslateModify(context.Background(), &pb.PostTranslateModifyRequest{
+		PostTranslateContext: &pb.PostTranslateExtensionContext{ExtensionResources: extensions},
+	})
+	if err != nil {
+		t.Fatalf("PostTranslateModify() error = %v", err)
+	}
+
+	// The fallback is shared by both policies and must only be sent once.
+	if len(translateResp.Secrets) != 1 {
+		t.Fatalf("got %d secrets, want 1", len(translateResp.Secrets))
+	}
+	for name := range referenced {
+		if !slices.ContainsFunc(translateResp.Secrets, func(secret *tlsv3.Secret) bool { return secret.Name == name }) {
+			t.Errorf("listener references secret %q which is not sent by the translation hook", name)
+		}
+	}
+}
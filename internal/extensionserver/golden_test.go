@@ -0,0 +1,135 @@
+package extensionserver_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/envoy-extension-server-app/internal/extensionserver"
+	"github.com/giantswarm/envoy-extension-server-app/internal/recorder"
+	"github.com/giantswarm/envoy-extension-server-app/internal/render"
+)
+
+var update = flag.Bool("update", false, "regenerate the golden responses in testdata/golden")
+
+// Golden test cases are the directories in testdata/golden. Each holds:
+//
+//   - request.json: the hook request in the format written by --record-dir,
+//     so recorded Envoy Gateway traffic can be copied in as is.
+//   - objects.yaml (optional): CertificatePolicies, Secrets and Gateways.
+//     CertificatePolicies are added to the extension resources of the request.
+//   - options.yaml (optional): the goldenOptions the server runs with.
+//   - response.json: the expected response, regenerated with -update.
+const (
+	goldenDir    = "testdata/golden"
+	requestFile  = "request.json"
+	objectsFile  = "objects.yaml"
+	optionsFile  = "options.yaml"
+	responseFile = "response.json"
+)
+
+// goldenOptions configure the server of a golden test case.
+type goldenOptions struct {
+	FallbackSecret *struct {
+		Namespace string `json:"namespace"`
+		Name      string `json:"name"`
+	} `json:"fallbackSecret,omitempty"`
+	Strict bool `json:"strict,omitempty"`
+}
+
+func TestGolden(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join(goldenDir, "*"))
+	if err != nil {
+		t.Fatalf("failed to list golden test cases: %v", err)
+	}
+	if len(dirs) == 0 {
+		t.Fatalf("no golden test cases found in %s", goldenDir)
+	}
+
+	for _, dir := range dirs {
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			got := renderGoldenCase(t, dir)
+
+			golden := filepath.Join(dir, responseFile)
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatalf("failed to update %s: %v", golden, err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read %s, run the test with -update to create it: %v", golden, err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("response does not match %s, run the test with -update if the change is intended\ngot:\n%s\nwant:\n%s", golden, got, want)
+			}
+		})
+	}
+}
+
+// renderGoldenCase runs the hook of a golden test case and returns the
+// rendered response.
+func renderGoldenCase(t *testing.T, dir string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(dir, requestFile))
+	if err != nil {
+		t.Fatalf("failed to read request: %v", err)
+	}
+	recording, err := recorder.ReadRecording(data)
+	if err != nil {
+		t.Fatalf("invalid request: %v", err)
+	}
+
+	var manifests [][]byte
+	if objects := readOptionalFile(t, filepath.Join(dir, objectsFile)); objects != nil {
+		manifests = append(manifests, objects)
+	}
+
+	var opts goldenOptions
+	if data := readOptionalFile(t, filepath.Join(dir, optionsFile)); data != nil {
+		if err := yaml.UnmarshalStrict(data, &opts); err != nil {
+			t.Fatalf("invalid options: %v", err)
+		}
+	}
+	serverOpts := []extensionserver.Option{extensionserver.WithStrictMode(opts.Strict)}
+	if opts.FallbackSecret != nil {
+		serverOpts = append(serverOpts, extensionserver.WithFallbackSecret(types.NamespacedName{
+			Namespace: opts.FallbackSecret.Namespace,
+			Name:      opts.FallbackSecret.Name,
+		}))
+	}
+
+	out, err := render.Render(context.Background(), render.Options{
+		Hook:          render.Hook(recording.Hook()),
+		Request:       recording.Request,
+		Manifests:     manifests,
+		ServerOptions: serverOpts,
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	return out
+}
+
+// readOptionalFile returns the content of a file, or nil if it does not exist.
+func readOptionalFile(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", name, err)
+	}
+	return data
+}
@@ -0,0 +1,102 @@
+package extensionserver
+
+import (
+	"context"
+	"slices"
+	"sync/atomic"
+
+	pb "github.com/envoyproxy/gateway/proto/extension"
+)
+
+const (
+	// HookPostTranslateModify is the name of the hook adding the policies'
+	// Secrets to a translation.
+	HookPostTranslateModify = "PostTranslateModify"
+
+	// HookPostHTTPListenerModify is the name of the hook referencing the
+	// policies' Secrets from HTTP listeners.
+	HookPostHTTPListenerModify = "PostHTTPListenerModify"
+
+	// HookPostRouteModify is the name of the hook running RouteMutators.
+	HookPostRouteModify = "PostRouteModify"
+
+	// HookPostVirtualHostModify is the name of the hook running
+	// VirtualHostMutators.
+	HookPostVirtualHostModify = "PostVirtualHostModify"
+
+	// HookPostClusterModify is the name of the hook running ClusterMutators.
+	HookPostClusterModify = "PostClusterModify"
+
+	// KindCertificatePolicy is the kind of CertificatePolicy extension
+	// resources.
+	KindCertificatePolicy = "CertificatePolicy"
+)
+
+// Hooks lists the hooks the Server implements.
+var Hooks = []string{
+	HookPostTranslateModify,
+	HookPostHTTPListenerModify,
+	HookPostRouteModify,
+	HookPostVirtualHostModify,
+	HookPostClusterModify,
+}
+
+// PolicyKinds lists the kinds of extension resources the Server processes.
+var PolicyKinds = []string{KindCertificatePolicy}
+
+// hookEnabled reports whether a hook modifies the resources it receives.
+func (s *Server) hookEnabled(hook string) bool {
+	return s.enabledHooks == nil || slices.Contains(s.enabledHooks, hook)
+}
+
+// policyKindEnabled reports whether extension resources of a kind are
+// processed.
+func (s *Server) policyKindEnabled(kind string) bool {
+	return s.policyKinds == nil || slices.Contains(s.policyKinds, kind)
+}
+
+// Reloadable serves the hooks of the Server stored last, so that its
+// settings can change without restarting the gRPC server. Calls in flight
+// finish with the Server they started with.
+type Reloadable struct {
+	pb.UnimplementedEnvoyGatewayExtensionServer
+
+	server atomic.Pointer[Server]
+}
+
+// NewReloadable returns a Reloadable serving the hooks of server.
+func NewReloadable(server *Server) *Reloadable {
+	r := &Reloadable{}
+	r.Store(server)
+	return r
+}
+
+// Store makes server serve all following hook calls.
+func (r *Reloadable) Store(server *Server) {
+	r.server.Store(server)
+}
+
+// PostTranslateModify calls the hook of the current Server.
+func (r *Reloadable) PostTranslateModify(ctx context.Context, req *pb.PostTranslateModifyRequest) (*pb.PostTranslateModifyResponse, error) {
+	return r.server.Load().PostTranslateModify(ctx, req)
+}
+
+// PostHTTPListenerModify calls the hook of the current Server.
+func (r *Reloadable) PostHTTPListenerModify(ctx context.Context, req *pb.PostHTTPListenerModifyRequest) (*pb.PostHTTPListenerModifyResponse, error) {
+	return r.server.Load().PostHTTPListenerModify(ctx, req)
+}
+
+// PostRouteModify calls the hook of the current Server.
+func (r *Reloadable) PostRouteModify(ctx context.Context, req *pb.PostRouteModifyRequest) (*pb.PostRouteModifyResponse, error) {
+	return r.server.Load().PostRouteModify(ctx, req)
+}
+
+// PostVirtualHostModify calls the hook of the current Server.
+func (r *Reloadable) PostVirtualHostModify(ctx context.Context, req *pb.PostVirtualHostModifyRequest) (*pb.PostVirtualHostModifyResponse, error) {
+	return r.server.Load().PostVirtualHostModify(ctx, req)
+}
+
+// PostClusterModify calls the hook of the current Server.
+func (r *Reloadable) PostClusterModify(ctx context.Context, req *pb.PostClusterModifyRequest) (*pb.PostClusterModifyResponse, error) {
+	return r.server.Load().PostClusterModify(ctx, req)
+}
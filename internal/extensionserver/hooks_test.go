@@ -0,0 +1,127 @@
+package extensionserver
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	pb "github.com/envoyproxy/gateway/proto/extension"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDisabledHooks(t *testing.T) {
+	server := newTestServerWithOptions([]Option{WithEnabledHooks([]string{})}, createTLSSecret("secret-1", nil))
+	policies := []*pb.ExtensionResource{createExtensionResource(t, "secret-1")}
+
+	translated, err := server.PostTranslateModify(context.Background(), &pb.PostTranslateModifyRequest{
+		PostTranslateContext: &pb.PostTranslateExtensionContext{ExtensionResources: policies},
+	})
+	if err != nil {
+		t.Fatalf("PostTranslateModify() error = %v", err)
+	}
+	if len(translated.Secrets) != 0 {
+		t.Errorf("secrets = %v, want the request's secrets unchanged", translated.Secrets)
+	}
+
+	listener := newHTTPSListener(t, "default/gateway-1/https")
+	modified, err := server.PostHTTPListenerModify(context.Background(), &pb.PostHTTPListenerModifyRequest{
+		Listener:            proto.Clone(listener).(*listenerv3.Listener),
+		PostListenerContext: &pb.PostHTTPListenerExtensionContext{ExtensionResources: policies},
+	})
+	if err != nil {
+		t.Fatalf("PostHTTPListenerModify() error = %v", err)
+	}
+	if !proto.Equal(modified.Listener, listener) {
+		t.Errorf("listener = %v, want it unchanged", modified.Listener)
+	}
+}
+
+func TestEnabledHooks(t *testing.T) {
+	server := newTestServerWithOptions([]Option{WithEnabledHooks([]string{HookPostTranslateModify})}, createTLSSecret("secret-1", nil))
+	policies := []*pb.ExtensionResource{createExtensionResource(t, "secret-1")}
+
+	listener := newHTTPSListener(t, "default/gateway-1/https")
+	modified, err := server.PostHTTPListenerModify(context.Background(), &pb.PostHTTPListenerModifyRequest{
+		Listener:            proto.Clone(listener).(*listenerv3.Listener),
+		PostListenerContext: &pb.PostHTTPListenerExtensionContext{ExtensionResources: policies},
+	})
+	if err != nil {
+		t.Fatalf("PostHTTPListenerModify() error = %v", err)
+	}
+	if !proto.Equal(modified.Listener, listener) {
+		t.Errorf("listener = %v, want it unchanged by the disabled hook", modified.Listener)
+	}
+
+	translated, err := server.PostTranslateModify(context.Background(), &pb.PostTranslateModifyRequest{
+		PostTranslateContext: &pb.PostTranslateExtensionContext{ExtensionResources: policies},
+	})
+	if err != nil {
+		t.Fatalf("PostTranslateModify() error = %v", err)
+	}
+	if len(translated.Secrets) != 1 || translated.Secrets[0].GetName() != "default/secret-1" {
+		t.Errorf("secrets = %v, want default/secret-1", translated.Secrets)
+	}
+}
+
+func TestPolicyKinds(t *testing.T) {
+	other := createPolicy("secret-2")
+	other.Kind = "OtherPolicy"
+	data, err := json.Marshal(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extensions := []*pb.ExtensionResource{createExtensionResource(t, "secret-1"), {UnstructuredBytes: data}}
+
+	tests := []struct {
+		name  string
+		kinds []string
+		want  int
+	}{
+		{name: "kinds are not checked by default", want: 2},
+		{name: "CertificatePolicy enabled", kinds: []string{KindCertificatePolicy}, want: 1},
+		{name: "no kinds enabled", kinds: []string{}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.kinds != nil {
+				opts = append(opts, WithPolicyKinds(tt.kinds))
+			}
+			policies := newTestServerWithOptions(opts).extractCertificatePolicies(context.Background(), extensions)
+			if len(policies) != tt.want {
+				t.Errorf("policies = %v, want %d", policies, tt.want)
+			}
+		})
+	}
+}
+
+func TestReloadable(t *testing.T) {
+	secret := createTLSSecret("secret-1", nil)
+	reloadable := NewReloadable(newTestServerWithOptions([]Option{WithEnabledHooks([]string{})}, secret))
+	req := &pb.PostTranslateModifyRequest{
+		PostTranslateContext: &pb.PostTranslateExtensionContext{
+			ExtensionResources: []*pb.ExtensionResource{createExtensionResource(t, "secret-1")},
+		},
+	}
+
+	resp, err := reloadable.PostTranslateModify(context.Background(), req)
+	if err != nil || len(resp.Secrets) != 0 {
+		t.Fatalf("PostTranslateModify() = %v, %v, want no secrets", resp, err)
+	}
+
+	reloadable.Store(newTestServerWithObjects(secret))
+	resp, err = reloadable.PostTranslateModify(context.Background(), req)
+	if err != nil || len(resp.Secrets) != 1 {
+		t.Fatalf("PostTranslateModify() = %v, %v, want the secret after the reload", resp, err)
+	}
+
+	listener, err := reloadable.PostHTTPListenerModify(context.Background(), &pb.PostHTTPListenerModifyRequest{
+		Listener:            newHTTPSListener(t, "default/gateway-1/https"),
+		PostListenerContext: &pb.PostHTTPListenerExtensionContext{ExtensionResources: req.PostTranslateContext.ExtensionResources},
+	})
+	if err != nil || len(listenerSecretReferences(listener.Listener)) != 1 {
+		t.Fatalf("PostHTTPListenerModify() = %v, %v, want the SDS reference after the reload", listener, err)
+	}
+}
@@ -0,0 +1,245 @@
+package extensionserver
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"slices"
+	"strings"
+
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+// eventReasonKeyAlgorithmConflict is used when a certificate is not served
+// because another one with the same key algorithm is.
+const eventReasonKeyAlgorithmConflict = "KeyAlgorithmConflict"
+
+// KeyAlgorithm is the public key algorithm of a certificate. Envoy serves at
+// most one certificate per key algorithm on a filter chain and picks the one
+// the client supports.
+type KeyAlgorithm string
+
+const (
+	// KeyAlgorithmUnknown is used for certificates that cannot be parsed,
+	// e.g. ones that are not inlined. They never conflict with others.
+	KeyAlgorithmUnknown KeyAlgorithm = ""
+	// KeyAlgorithmRSA is used for certificates with an RSA key.
+	KeyAlgorithmRSA KeyAlgorithm = "RSA"
+	// KeyAlgorithmECDSA is used for certificates with an ECDSA key.
+	KeyAlgorithmECDSA KeyAlgorithm = "ECDSA"
+	// KeyAlgorithmEd25519 is used for certificates with an Ed25519 key.
+	KeyAlgorithmEd25519 KeyAlgorithm = "Ed25519"
+)
+
+// keyAlgorithm returns the key algorithm of the leaf certificate of a PEM
+// encoded certificate chain.
+func keyAlgorithm(certChain []byte) KeyAlgorithm {
+	for {
+		var block *pem.Block
+		block, certChain = pem.Decode(certChain)
+		if block == nil {
+			return KeyAlgorithmUnknown
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return KeyAlgorithmUnknown
+		}
+		switch certificate.PublicKeyAlgorithm {
+		case x509.RSA:
+			return KeyAlgorithmRSA
+		case x509.ECDSA:
+			return KeyAlgorithmECDSA
+		case x509.Ed25519:
+			return KeyAlgorithmEd25519
+		default:
+			return KeyAlgorithmUnknown
+		}
+	}
+}
+
+// secretKeyAlgorithm returns the key algorithm of the certificate of an
+// Envoy secret. Only inlined certificates are parsed.
+func secretKeyAlgorithm(secret *tlsv3.Secret) KeyAlgorithm {
+	return keyAlgorithm(secret.GetTlsCertificate().GetCertificateChain().GetInlineBytes())
+}
+
+// resolveCertificates returns the Envoy secrets of the policy's Secret,
+// resolved like resolvePolicySecrets does, followed by those of its
+// additional Secrets. Additional Secrets that are missing or invalid are left
+// out, as they have no fallback. Transient errors are returned.
+func (s *Server) resolveCertificates(ctx context.Context, policy v1beta1.CertificatePolicy) ([]*tlsv3.Secret, error) {
+	secrets, err := s.resolvePolicySecrets(ctx, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ref := range policy.Spec.AdditionalSecretRefs {
+		key := types.NamespacedName{Namespace: policy.Namespace, Name: ref.Name}
+		additional, err := s.fetchAndConvertNamedSecret(ctx, key, policy.Spec.PrivateKeyProvider)
+		if isTransient(err) {
+			return nil, err
+		}
+		if err != nil {
+			s.logger(ctx).Error("omitting additional secret of policy",
+				"policy", policy.Name,
+				"secretName", ref.Name,
+				"error", err,
+			)
+			s.recordSecretError(policy, ref.Name, err)
+			continue
+		}
+		secrets = append(secrets, additional...)
+	}
+	return secrets, nil
+}
+
+// pairCertificates keeps the first certificate of every key algorithm among
+// the secrets of a policy, so that an RSA and an ECDSA certificate can be
+// served side by side. The names of the certificates left out are returned
+// as well; their CA secrets are left out with them.
+func pairCertificates(secrets []*tlsv3.Secret) ([]*tlsv3.Secret, []string) {
+	var (
+		paired  []*tlsv3.Secret
+		dropped []string
+	)
+	served := map[KeyAlgorithm]struct{}{}
+	skipped := map[string]struct{}{}
+	for _, secret := range secrets {
+		if _, ok := skipped[secret.GetName()]; ok {
+			continue
+		}
+		if algorithm := secretKeyAlgorithm(secret); algorithm != KeyAlgorithmUnknown {
+			if _, ok := served[algorithm]; ok {
+				dropped = append(dropped, secret.GetName())
+				skipped[secret.GetName()+"/"+caCertKey] = struct{}{}
+				continue
+			}
+			served[algorithm] = struct{}{}
+		}
+		paired = append(paired, secret)
+	}
+	return paired, dropped
+}
+
+// reportKeyAlgorithms emits an event for every certificate of the policy left
+// out by pairCertificates and keeps the KeyAlgorithmConflict condition of the
+// policy up to date. Only the leader writes the condition, and only for
+// policies with additional Secrets or the condition already set.
+func (s *Server) reportKeyAlgorithms(ctx context.Context, policy v1beta1.CertificatePolicy, dropped []string) {
+	for _, name := range dropped {
+		s.recordPolicyEvent(policy, corev1.EventTypeWarning, eventReasonKeyAlgorithmConflict,
+			"Envoy secret %s is not served, an earlier Secret of the policy holds a certificate with the same key algorithm", name)
+	}
+
+	if len(policy.Spec.AdditionalSecretRefs) == 0 && meta.FindStatusCondition(policy.Status.Conditions, v1beta1.KeyAlgorithmConflictConditionType) == nil {
+		return
+	}
+	if !s.leader.IsLeader() {
+		return
+	}
+	if err := s.setKeyAlgorithmCondition(ctx, client.ObjectKeyFromObject(&policy), dropped); err != nil {
+		s.logger(ctx).Error("failed to update the status of policy", "policy", policy.Name, "error", err)
+	}
+}
+
+// setKeyAlgorithmCondition updates the KeyAlgorithmConflict condition of a
+// policy when it changed.
+func (s *Server) setKeyAlgorithmCondition(ctx context.Context, key types.NamespacedName, dropped []string) error {
+	var policy v1beta1.CertificatePolicy
+	if err := s.client.Get(ctx, key, &policy); err != nil {
+		return fmt.Errorf("failed to get policy: %w", err)
+	}
+
+	condition := metav1.Condition{
+		Type:               v1beta1.KeyAlgorithmConflictConditionType,
+		Status:             metav1.ConditionFalse,
+		Reason:             v1beta1.UniqueKeyAlgorithmsReason,
+		Message:            "Every certificate of the policy has a different key algorithm",
+		ObservedGeneration: policy.Generation,
+	}
+	if len(dropped) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = v1beta1.DuplicateKeyAlgorithmReason
+		condition.Message = fmt.Sprintf("Envoy secrets %s are not served, earlier Secrets of the policy hold certificates with the same key algorithm",
+			strings.Join(dropped, ", "))
+	}
+
+	if meta.SetStatusCondition(&policy.Status.Conditions, condition) {
+		if err := s.client.Status().Update(ctx, &policy); err != nil {
+			return fmt.Errorf("failed to update status: %w", err)
+		}
+	}
+	return nil
+}
+
+// pairListenerCertificates keeps at most one certificate per key algorithm
+// on the TLS filter chains of the listeners, as Envoy rejects filter chains
+// with several certificates of the same key algorithm. The certificates
+// Envoy Gateway configured itself, whose secrets are among configured, are
+// always kept. Of the certificates the listener hook added for the policies,
+// the first of every key algorithm not served yet is kept. The key
+// algorithms are read from secrets, the secrets of the translation.
+func (s *Server) pairListenerCertificates(ctx context.Context, listeners []*listenerv3.Listener, configured, secrets []*tlsv3.Secret, policies []v1beta1.CertificatePolicy) error {
+	algorithms := make(map[string]KeyAlgorithm, len(secrets))
+	for _, secret := range secrets {
+		algorithms[secret.GetName()] = secretKeyAlgorithm(secret)
+	}
+	own := make(map[string]struct{}, len(configured))
+	for _, secret := range configured {
+		own[secret.GetName()] = struct{}{}
+	}
+	policySecrets := s.policySecretNames(policies)
+	added := func(name string) bool {
+		_, isOwn := own[name]
+		_, isPolicySecret := policySecrets[name]
+		return isPolicySecret && !isOwn
+	}
+
+	return mutateTLSContexts(listeners, func(filterChain *listenerv3.FilterChain, tlsContext *tlsv3.DownstreamTlsContext) {
+		commonTlsContext := tlsContext.GetCommonTlsContext()
+		if commonTlsContext == nil {
+			return
+		}
+		served := map[KeyAlgorithm]string{}
+		for _, config := range commonTlsContext.TlsCertificateSdsSecretConfigs {
+			algorithm := algorithms[config.GetName()]
+			if _, ok := served[algorithm]; !ok && algorithm != KeyAlgorithmUnknown && !added(config.GetName()) {
+				served[algorithm] = config.GetName()
+			}
+		}
+		commonTlsContext.TlsCertificateSdsSecretConfigs = slices.DeleteFunc(commonTlsContext.TlsCertificateSdsSecretConfigs, func(config *tlsv3.SdsSecretConfig) bool {
+			name := config.GetName()
+			algorithm := algorithms[name]
+			if algorithm == KeyAlgorithmUnknown || !added(name) {
+				return false
+			}
+			first, ok := served[algorithm]
+			if !ok {
+				served[algorithm] = name
+				return false
+			}
+			s.logger(ctx).Warn("omitting certificate with a key algorithm the filter chain already serves",
+				"filterChain", filterChain.GetName(),
+				"secretName", name,
+				"servedSecretName", first,
+				"keyAlgorithm", algorithm,
+			)
+			s.recordPolicyEvent(*policySecrets[name], corev1.EventTypeWarning, eventReasonKeyAlgorithmConflict,
+				"Envoy secret %s is not served on filter chain %s, which already serves the %s certificate of Envoy secret %s",
+				name, filterChain.GetName(), algorithm, first)
+			return true
+		})
+	})
+}
@@ -0,0 +1,329 @@
+package extensionserver
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"log/slog"
+	"math/big"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/envoyproxy/gateway/proto/extension"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+// follower is a leader.Gate of a replica that is not the leader.
+type follower struct{}
+
+func (follower) IsLeader() bool { return false }
+
+// selfSignedCertificate returns a PEM encoded self-signed certificate for the
+// public key of signer.
+func selfSignedCertificate(t *testing.T, signer crypto.Signer) []byte {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "www.example.org"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, signer.Public(), signer)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func newRSAKey(t *testing.T) crypto.Signer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	return key
+}
+
+func newECDSAKey(t *testing.T) crypto.Signer {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ECDSA key: %v", err)
+	}
+	return key
+}
+
+// createKeyedTLSSecret returns a TLS Secret holding a certificate for the
+// public key of signer.
+func createKeyedTLSSecret(t *testing.T, name string, signer crypto.Signer) *corev1.Secret {
+	t.Helper()
+	secret := createTLSSecret(name, nil)
+	secret.Data[corev1.TLSCertKey] = selfSignedCertificate(t, signer)
+	return secret
+}
+
+func TestKeyAlgorithm(t *testing.T) {
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate Ed25519 key: %v", err)
+	}
+	ecdsaCertificate := selfSignedCertificate(t, newECDSAKey(t))
+
+	tests := []struct {
+		name      string
+		certChain []byte
+		want      KeyAlgorithm
+	}{
+		{name: "RSA", certChain: selfSignedCertificate(t, newRSAKey(t)), want: KeyAlgorithmRSA},
+		{name: "ECDSA", certChain: ecdsaCertificate, want: KeyAlgorithmECDSA},
+		{name: "Ed25519", certChain: selfSignedCertificate(t, ed25519Key), want: KeyAlgorithmEd25519},
+		{
+			name:      "leaf after another PEM block",
+			certChain: append(pem.EncodeToMemory(&pem.Block{Type: "EC PARAMETERS", Bytes: []byte{0}}), ecdsaCertificate...),
+			want:      KeyAlgorithmECDSA,
+		},
+		{name: "not PEM", certChain: []byte("cert")},
+		{name: "invalid certificate", certChain: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("cert")})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keyAlgorithm(tt.certChain); got != tt.want {
+				t.Errorf("keyAlgorithm() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPairCertificates(t *testing.T) {
+	server := newTestServerWithObjects(
+		createKeyedTLSSecret(t, "rsa", newRSAKey(t)),
+		createKeyedTLSSecret(t, "ecdsa", newECDSAKey(t)),
+		createKeyedTLSSecret(t, "other-rsa", newRSAKey(t)),
+		createTLSSecret("unparsable", nil),
+	)
+	otherRSA := createKeyedTLSSecret(t, "other-rsa-with-ca", newRSAKey(t))
+	otherRSA.Data[caCertKey] = []byte("ca")
+	if err := server.client.Create(context.Background(), otherRSA); err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+
+	var secrets []*tlsv3.Secret
+	for _, name := range []string{"rsa", "ecdsa", "other-rsa-with-ca", "unparsable", "other-rsa"} {
+		converted, err := server.fetchAndConvertNamedSecret(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, nil)
+		if err != nil {
+			t.Fatalf("fetchAndConvertNamedSecret(%s) error = %v", name, err)
+		}
+		secrets = append(secrets, converted...)
+	}
+
+	paired, dropped := pairCertificates(secrets)
+	if want := []string{"default/rsa", "default/ecdsa", "default/unparsable"}; !slices.Equal(secretNames(paired), want) {
+		t.Errorf("paired secrets = %v, want %v", secretNames(paired), want)
+	}
+	if want := []string{"default/other-rsa-with-ca", "default/other-rsa"}; !slices.Equal(dropped, want) {
+		t.Errorf("dropped secrets = %v, want %v", dropped, want)
+	}
+}
+
+// createDualPolicy returns a policy serving the certificates of all given
+// Secrets.
+func createDualPolicy(secretName string, additional ...string) v1beta1.CertificatePolicy {
+	policy := createTargetingPolicy(secretName, gatewayTargetRef("gateway-1", ""))
+	for _, name := range additional {
+		policy.Spec.AdditionalSecretRefs = append(policy.Spec.AdditionalSecretRefs, v1beta1.SecretReference{Name: name})
+	}
+	return policy
+}
+
+func TestPostTranslateModifyPairsCertificates(t *testing.T) {
+	secrets := []client.Object{
+		createKeyedTLSSecret(t, "rsa", newRSAKey(t)),
+		createKeyedTLSSecret(t, "ecdsa", newECDSAKey(t)),
+		createKeyedTLSSecret(t, "other-rsa", newRSAKey(t)),
+	}
+
+	tests := []struct {
+		name        string
+		additional  []string
+		opts        []Option
+		wantSecrets []string
+		// wantReason is the reason of the KeyAlgorithmConflict condition,
+		// empty when the condition must not be set.
+		wantReason string
+		wantEvent  string
+	}{
+		{
+			name:        "RSA and ECDSA are served side by side",
+			additional:  []string{"ecdsa"},
+			wantSecrets: []string{"default/rsa", "default/ecdsa"},
+			wantReason:  v1beta1.UniqueKeyAlgorithmsReason,
+		},
+		{
+			name:        "a second RSA certificate is left out",
+			additional:  []string{"ecdsa", "other-rsa"},
+			wantSecrets: []string{"default/rsa", "default/ecdsa"},
+			wantReason:  v1beta1.DuplicateKeyAlgorithmReason,
+			wantEvent:   "Warning KeyAlgorithmConflict Envoy secret default/other-rsa is not served",
+		},
+		{
+			name:        "followers do not write the condition",
+			additional:  []string{"other-rsa"},
+			opts:        []Option{WithLeader(follower{})},
+			wantSecrets: []string{"default/rsa"},
+			wantEvent:   "Warning KeyAlgorithmConflict Envoy secret default/other-rsa is not served",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := createDualPolicy("rsa", tt.additional...)
+			recorder := record.NewFakeRecorder(10)
+			server := newTestServerWithOptions(append(tt.opts, WithEventRecorder(recorder)), append(slices.Clone(secrets), policy.DeepCopy())...)
+
+			resp, err := server.PostTranslateModify(context.Background(), &pb.PostTranslateModifyRequest{
+				PostTranslateContext: &pb.PostTranslateExtensionContext{
+					ExtensionResources: []*pb.ExtensionResource{marshalExtensionResource(t, policy)},
+				},
+			})
+			if err != nil {
+				t.Fatalf("PostTranslateModify() error = %v", err)
+			}
+			if got := secretNames(resp.Secrets); !slices.Equal(got, tt.wantSecrets) {
+				t.Errorf("secrets = %v, want %v", got, tt.wantSecrets)
+			}
+
+			var stored v1beta1.CertificatePolicy
+			if err := server.client.Get(context.Background(), client.ObjectKeyFromObject(&policy), &stored); err != nil {
+				t.Fatalf("failed to get policy: %v", err)
+			}
+			var reason string
+			if condition := meta.FindStatusCondition(stored.Status.Conditions, v1beta1.KeyAlgorithmConflictConditionType); condition != nil {
+				reason = condition.Reason
+			}
+			if reason != tt.wantReason {
+				t.Errorf("KeyAlgorithmConflict reason = %q, want %q", reason, tt.wantReason)
+			}
+
+			var recorded bool
+			for _, event := range drainEvents(recorder) {
+				recorded = recorded || strings.HasPrefix(event, tt.wantEvent)
+			}
+			if tt.wantEvent != "" && !recorded {
+				t.Errorf("no %q event was recorded", tt.wantEvent)
+			}
+		})
+	}
+}
+
+func TestEndToEndPairsCertificatesOnFilterChains(t *testing.T) {
+	objects := []client.Object{
+		createKeyedTLSSecret(t, "gateway-cert", newRSAKey(t)),
+		createKeyedTLSSecret(t, "rsa", newRSAKey(t)),
+		createKeyedTLSSecret(t, "ecdsa", newECDSAKey(t)),
+		createKeyedTLSSecret(t, "other-rsa", newRSAKey(t)),
+	}
+	dual := createDualPolicy("rsa", "ecdsa")
+	other := createDualPolicy("other-rsa")
+	other.Name = "other-policy"
+
+	tests := []struct {
+		name string
+		// configured are the certificates Envoy Gateway configured on the
+		// filter chain itself.
+		configured     []string
+		wantReferences []string
+		wantEvent      string
+	}{
+		{
+			name:           "certificates of later policies are left out",
+			wantReferences: []string{"default/rsa", "default/ecdsa"},
+			wantEvent:      "Warning KeyAlgorithmConflict Envoy secret default/other-rsa is not served on filter chain default/gateway-1/https, which already serves the RSA certificate of Envoy secret default/rsa",
+		},
+		{
+			name:           "certificates of Envoy Gateway come first",
+			configured:     []string{"default/gateway-cert"},
+			wantReferences: []string{"default/gateway-cert", "default/ecdsa"},
+			wantEvent:      "Warning KeyAlgorithmConflict Envoy secret default/rsa is not served on filter chain default/gateway-1/https, which already serves the RSA certificate of Envoy secret default/gateway-cert",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(20)
+			server := newTestServerWithOptions([]Option{WithEventRecorder(recorder)}, objects...)
+			var configured []*tlsv3.Secret
+			for _, name := range tt.configured {
+				namespace, secretName, _ := strings.Cut(name, "/")
+				converted, err := server.fetchAndConvertNamedSecret(context.Background(), types.NamespacedName{Namespace: namespace, Name: secretName}, nil)
+				if err != nil {
+					t.Fatalf("fetchAndConvertNamedSecret(%s) error = %v", name, err)
+				}
+				configured = append(configured, converted...)
+			}
+			gateway := startFakeEnvoyGateway(t, server)
+
+			snapshot, err := gateway.translate(context.Background(),
+				[]*listenerv3.Listener{newHTTPSListenerServing(t, "default/gateway-1/https", tt.configured...)},
+				configured,
+				[]*pb.ExtensionResource{marshalExtensionResource(t, dual), marshalExtensionResource(t, other)},
+			)
+			if err != nil {
+				t.Fatalf("translation failed: %v", err)
+			}
+			if got := snapshot.sdsReferences(t); !slices.Equal(got, tt.wantReferences) {
+				t.Errorf("SDS references = %v, want %v", got, tt.wantReferences)
+			}
+			snapshot.assertConsistent(t)
+
+			if events := drainEvents(recorder); !slices.ContainsFunc(events, func(event string) bool { return strings.HasPrefix(event, tt.wantEvent) }) {
+				t.Errorf("events = %v, want %q", events, tt.wantEvent)
+			}
+		})
+	}
+}
+
+func TestListenerCertificatesDoNotReadSecrets(t *testing.T) {
+	var reads int
+	k8sClient := interceptor.NewClient(fake.NewClientBuilder().Build(), interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			reads++
+			return c.Get(ctx, key, obj, opts...)
+		},
+	})
+	server := New(slog.New(slog.DiscardHandler), k8sClient)
+
+	certificates, err := server.listenerCertificates(context.Background(), []v1beta1.CertificatePolicy{createDualPolicy("rsa", "ecdsa")})
+	if err != nil {
+		t.Fatalf("listenerCertificates() error = %v", err)
+	}
+	var names []string
+	for _, certificate := range certificates {
+		names = append(names, certificate.SecretName)
+	}
+	if want := []string{"default/rsa", "default/ecdsa"}; !slices.Equal(names, want) {
+		t.Errorf("certificates = %v, want %v", names, want)
+	}
+	if reads != 0 {
+		t.Errorf("listenerCertificates() read %d objects, want none", reads)
+	}
+}
@@ -0,0 +1,119 @@
+package extensionserver
+
+import (
+	"strings"
+
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+// tlsProtocols maps policy TLS versions to Envoy TLS protocol versions.
+var tlsProtocols = map[v1beta1.TLSVersion]tlsv3.TlsParameters_TlsProtocol{
+	v1beta1.TLSAuto: tlsv3.TlsParameters_TLS_AUTO,
+	v1beta1.TLSv10:  tlsv3.TlsParameters_TLSv1_0,
+	v1beta1.TLSv11:  tlsv3.TlsParameters_TLSv1_1,
+	v1beta1.TLSv12:  tlsv3.TlsParameters_TLSv1_2,
+	v1beta1.TLSv13:  tlsv3.TlsParameters_TLSv1_3,
+}
+
+// ListenerCertificate is a certificate a policy adds to the TLS filter chains
+// of a listener.
+type ListenerCertificate struct {
+	// SecretName is the name of the Envoy secret holding the certificate.
+	SecretName string
+
+	// Hostnames restricts the filter chains the certificate is added to.
+	Hostnames []gwapiv1.Hostname
+
+	// TLSParams overrides the TLS parameters of those filter chains.
+	TLSParams *v1beta1.TLSParameters
+
+	// ALPNProtocols overrides the application protocols offered on those
+	// filter chains.
+	ALPNProtocols []v1beta1.ALPNProtocol
+
+	// SessionTicketKeys is the name of the Envoy secret holding the session
+	// ticket keys of the policy, if it has any.
+	SessionTicketKeys string
+
+	// policy is the policy of the certificate, which events about it are
+	// recorded on.
+	policy *v1beta1.CertificatePolicy
+}
+
+// newListenerCertificate returns the listener certificate of a policy served
+// from the given Envoy secret.
+func newListenerCertificate(policy v1beta1.CertificatePolicy, secret *tlsv3.Secret) ListenerCertificate {
+	return ListenerCertificate{
+		SecretName:    secret.GetName(),
+		Hostnames:     policy.Spec.Hostnames,
+		TLSParams:     policy.Spec.TLSParams,
+		ALPNProtocols: policy.Spec.ALPNProtocols,
+		policy:        &policy,
+	}
+}
+
+// matchesFilterChain reports whether the certificate is added to the filter
+// chain. Certificates without hostnames and filter chains without server
+// names match everything.
+func (c ListenerCertificate) matchesFilterChain(filterChain *listenerv3.FilterChain) bool {
+	serverNames := filterChain.GetFilterChainMatch().GetServerNames()
+	if len(c.Hostnames) == 0 || len(serverNames) == 0 {
+		return true
+	}
+	for _, hostname := range c.Hostnames {
+		for _, serverName := range serverNames {
+			if hostnamesOverlap(string(hostname), serverName) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hostnamesOverlap reports whether two hostnames, each of which may be a
+// "*." wildcard, can match the same server name.
+func hostnamesOverlap(a, b string) bool {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	if a == b {
+		return true
+	}
+	if suffix, ok := strings.CutPrefix(a, "*"); ok && strings.HasSuffix(b, suffix) {
+		return true
+	}
+	if suffix, ok := strings.CutPrefix(b, "*"); ok && strings.HasSuffix(a, suffix) {
+		return true
+	}
+	return false
+}
+
+// applyTLSParams overrides the TLS parameters of a TLS context with the ones
+// set in params. Unset parameters keep the value Envoy Gateway generated.
+func applyTLSParams(tlsContext *tlsv3.DownstreamTlsContext, params *v1beta1.TLSParameters) {
+	if tlsContext.CommonTlsContext == nil {
+		tlsContext.CommonTlsContext = &tlsv3.CommonTlsContext{}
+	}
+	if tlsContext.CommonTlsContext.TlsParams == nil {
+		tlsContext.CommonTlsContext.TlsParams = &tlsv3.TlsParameters{}
+	}
+	tlsParams := tlsContext.CommonTlsContext.TlsParams
+
+	if params.MinVersion != nil {
+		tlsParams.TlsMinimumProtocolVersion = tlsProtocols[*params.MinVersion]
+	}
+	if params.MaxVersion != nil {
+		tlsParams.TlsMaximumProtocolVersion = tlsProtocols[*params.MaxVersion]
+	}
+	if len(params.CipherSuites) > 0 {
+		tlsParams.CipherSuites = params.CipherSuites
+	}
+	if len(params.ECDHCurves) > 0 {
+		tlsParams.EcdhCurves = params.ECDHCurves
+	}
+	if len(params.SignatureAlgorithms) > 0 {
+		tlsParams.SignatureAlgorithms = params.SignatureAlgorithms
+	}
+}
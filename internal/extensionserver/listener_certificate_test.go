@@ -0,0 +1,233 @@
+package extensionserver
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1alpha1"
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+func TestHostnamesOverlap(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "www.example.com", b: "www.example.com", want: true},
+		{a: "WWW.example.com", b: "www.example.com", want: true},
+		{a: "*.example.com", b: "www.example.com", want: true},
+		{a: "www.example.com", b: "*.example.com", want: true},
+		{a: "*.example.com", b: "*.foo.example.com", want: true},
+		{a: "*.example.com", b: "example.com", want: false},
+		{a: "www.example.com", b: "www.example.org", want: false},
+		{a: "*.example.com", b: "*.example.org", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := hostnamesOverlap(tt.a, tt.b); got != tt.want {
+			t.Errorf("hostnamesOverlap(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestListenerCertificateMatchesFilterChain(t *testing.T) {
+	withServerNames := func(names ...string) *listenerv3.FilterChain {
+		return &listenerv3.FilterChain{FilterChainMatch: &listenerv3.FilterChainMatch{ServerNames: names}}
+	}
+
+	tests := []struct {
+		name        string
+		hostnames   []gwapiv1.Hostname
+		filterChain *listenerv3.FilterChain
+		want        bool
+	}{
+		{
+			name:        "no hostnames match every filter chain",
+			filterChain: withServerNames("www.example.com"),
+			want:        true,
+		},
+		{
+			name:        "filter chain without server names matches",
+			hostnames:   []gwapiv1.Hostname{"www.example.com"},
+			filterChain: &listenerv3.FilterChain{},
+			want:        true,
+		},
+		{
+			name:        "overlapping server name",
+			hostnames:   []gwapiv1.Hostname{"api.example.org", "*.example.com"},
+			filterChain: withServerNames("www.example.com"),
+			want:        true,
+		},
+		{
+			name:        "disjoint server names",
+			hostnames:   []gwapiv1.Hostname{"www.example.org"},
+			filterChain: withServerNames("www.example.com", "*.example.net"),
+			want:        false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			certificate := ListenerCertificate{SecretName: "default/secret-1", Hostnames: tt.hostnames}
+			if got := certificate.matchesFilterChain(tt.filterChain); got != tt.want {
+				t.Errorf("matchesFilterChain() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPoliciesToFilterChainHostnamesAndTLSParams(t *testing.T) {
+	server := newTestServer()
+	filterChain := &listenerv3.FilterChain{
+		FilterChainMatch: &listenerv3.FilterChainMatch{ServerNames: []string{"www.example.com"}},
+		TransportSocket:  createTransportSocketWithTLS(t),
+	}
+	certificates := []ListenerCertificate{
+		{
+			SecretName: "default/matching",
+			Hostnames:  []gwapiv1.Hostname{"*.example.com"},
+			TLSParams: &v1beta1.TLSParameters{
+				MinVersion:   ptr.To(v1beta1.TLSv12),
+				CipherSuites: []string{"ECDHE-RSA-AES128-GCM-SHA256"},
+			},
+		},
+		{
+			SecretName: "default/other-host",
+			Hostnames:  []gwapiv1.Hostname{"www.example.org"},
+			TLSParams:  &v1beta1.TLSParameters{MinVersion: ptr.To(v1beta1.TLSv10)},
+		},
+		{
+			SecretName: "default/any-host",
+			TLSParams:  &v1beta1.TLSParameters{MaxVersion: ptr.To(v1beta1.TLSv13)},
+		},
+	}
+
+	if err := server.applyPoliciesToFilterChain(context.Background(), nil, filterChain, certificates); err != nil {
+		t.Fatalf("applyPoliciesToFilterChain() error = %v", err)
+	}
+
+	tlsContext, err := extractDownstreamTlsContext(filterChain.TransportSocket)
+	if err != nil {
+		t.Fatalf("failed to extract TLS context: %v", err)
+	}
+	var names []string
+	for _, config := range tlsContext.GetCommonTlsContext().GetTlsCertificateSdsSecretConfigs() {
+		names = append(names, config.GetName())
+	}
+	if want := []string{"default/matching", "default/any-host"}; !slices.Equal(names, want) {
+		t.Errorf("SDS secret configs = %v, want %v", names, want)
+	}
+
+	params := tlsContext.GetCommonTlsContext().GetTlsParams()
+	if params.GetTlsMinimumProtocolVersion() != tlsv3.TlsParameters_TLSv1_2 {
+		t.Errorf("TlsMinimumProtocolVersion = %v, want TLSv1_2", params.GetTlsMinimumProtocolVersion())
+	}
+	if params.GetTlsMaximumProtocolVersion() != tlsv3.TlsParameters_TLSv1_3 {
+		t.Errorf("TlsMaximumProtocolVersion = %v, want TLSv1_3", params.GetTlsMaximumProtocolVersion())
+	}
+	if !slices.Equal(params.GetCipherSuites(), []string{"ECDHE-RSA-AES128-GCM-SHA256"}) {
+		t.Errorf("CipherSuites = %v", params.GetCipherSuites())
+	}
+}
+
+func TestApplyPoliciesToFilterChainWithoutMatchingCertificates(t *testing.T) {
+	server := newTestServer()
+	transportSocket := createTransportSocketWithTLS(t)
+	original := transportSocket.GetTypedConfig().GetValue()
+	filterChain := &listenerv3.FilterChain{
+		FilterChainMatch: &listenerv3.FilterChainMatch{ServerNames: []string{"www.example.com"}},
+		TransportSocket:  transportSocket,
+	}
+
+	certificates := []ListenerCertificate{{SecretName: "default/secret-1", Hostnames: []gwapiv1.Hostname{"www.example.org"}}}
+	if err := server.applyPoliciesToFilterChain(context.Background(), nil, filterChain, certificates); err != nil {
+		t.Fatalf("applyPoliciesToFilterChain() error = %v", err)
+	}
+	if !slices.Equal(filterChain.TransportSocket.GetTypedConfig().GetValue(), original) {
+		t.Error("transport socket was modified although no certificate matches")
+	}
+}
+
+func TestDecodeCertificatePolicy(t *testing.T) {
+	alpha := v1alpha1.CertificatePolicy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: "CertificatePolicy"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-policy", Namespace: "default"},
+		Spec: v1alpha1.CertificatePolicySpec{
+			SecretName:         "secret-1",
+			FallbackSecretName: "fallback",
+		},
+	}
+	alphaData, err := json.Marshal(alpha)
+	if err != nil {
+		t.Fatalf("failed to marshal policy: %v", err)
+	}
+	betaData, err := json.Marshal(createPolicy("secret-2"))
+	if err != nil {
+		t.Fatalf("failed to marshal policy: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		data         []byte
+		wantSecret   string
+		wantFallback string
+		wantErr      bool
+	}{
+		{
+			name:         "v1alpha1 is converted",
+			data:         alphaData,
+			wantSecret:   "secret-1",
+			wantFallback: "fallback",
+		},
+		{
+			name:       "v1beta1",
+			data:       betaData,
+			wantSecret: "secret-2",
+		},
+		{
+			name:    "v1beta1 without secretRef",
+			data:    []byte(`{"apiVersion":"gateway.giantswarm.io/v1beta1","kind":"CertificatePolicy","spec":{"secretName":"secret-1"}}`),
+			wantErr: true,
+		},
+		{
+			name:    "unknown apiVersion",
+			data:    []byte(`{"apiVersion":"example.com/v1","kind":"CertificatePolicy"}`),
+			wantErr: true,
+		},
+		{
+			name:    "invalid JSON",
+			data:    []byte("invalid json"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := decodeCertificatePolicy(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeCertificatePolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if policy.Spec.SecretRef.Name != tt.wantSecret {
+				t.Errorf("SecretRef.Name = %q, want %q", policy.Spec.SecretRef.Name, tt.wantSecret)
+			}
+			var fallback string
+			if policy.Spec.FallbackSecretRef != nil {
+				fallback = policy.Spec.FallbackSecretRef.Name
+			}
+			if fallback != tt.wantFallback {
+				t.Errorf("FallbackSecretRef.Name = %q, want %q", fallback, tt.wantFallback)
+			}
+		})
+	}
+}
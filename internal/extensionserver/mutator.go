@@ -0,0 +1,197 @@
+package extensionserver
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	pb "github.com/envoyproxy/gateway/proto/extension"
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+// Mutator is a unit of behavior of the hooks that can be enabled per
+// deployment. A Mutator implements one or more of ListenerMutator,
+// RouteMutator, VirtualHostMutator, ClusterMutator and TranslateMutator.
+// The hooks fetch the resources policies refer to, the Mutators only modify
+// xDS, so that they can be tested without a cluster or gRPC.
+type Mutator interface {
+	// Name identifies the Mutator in the configuration.
+	Name() string
+}
+
+// ListenerMutator modifies the TLS filter chains of the listeners passed to
+// PostHTTPListenerModify.
+type ListenerMutator interface {
+	Mutator
+
+	// MutateFilterChain modifies a filter chain that certificates of
+	// policies were matched to.
+	MutateFilterChain(ctx context.Context, filterChain *FilterChain) error
+}
+
+// RouteMutator modifies the routes passed to PostRouteModify.
+type RouteMutator interface {
+	Mutator
+
+	// MutateRoute modifies a route generated for an HTTPRoute or GRPCRoute.
+	MutateRoute(ctx context.Context, route *routev3.Route, routeContext *pb.PostRouteExtensionContext) error
+}
+
+// VirtualHostMutator modifies the virtual hosts passed to
+// PostVirtualHostModify.
+type VirtualHostMutator interface {
+	Mutator
+
+	// MutateVirtualHost modifies a virtual host.
+	MutateVirtualHost(ctx context.Context, virtualHost *routev3.VirtualHost) error
+}
+
+// ClusterMutator modifies the clusters passed to PostClusterModify.
+type ClusterMutator interface {
+	Mutator
+
+	// MutateCluster modifies a cluster generated for custom backendRefs.
+	MutateCluster(ctx context.Context, cluster *clusterv3.Cluster, clusterContext *pb.PostClusterExtensionContext) error
+}
+
+// TranslateMutator modifies the xDS passed to PostTranslateModify.
+type TranslateMutator interface {
+	Mutator
+
+	// MutateTranslation modifies the xDS of a translation.
+	MutateTranslation(ctx context.Context, translation *Translation) error
+}
+
+// FilterChain is a TLS filter chain being modified by ListenerMutators. The
+// hook decodes its TLS context before and encodes it after all Mutators ran.
+type FilterChain struct {
+	// Listener is the listener of the filter chain.
+	Listener *listenerv3.Listener
+
+	// FilterChain is the filter chain. Its transport socket is replaced with
+	// TLSContext once all Mutators ran.
+	FilterChain *listenerv3.FilterChain
+
+	// TLSContext is the decoded downstream TLS context of the filter chain.
+	TLSContext *tlsv3.DownstreamTlsContext
+
+	// QUIC reports whether TLSContext belongs to the QUIC transport of an
+	// HTTP/3 listener, which always negotiates h3 over TLS 1.3.
+	QUIC bool
+
+	// Certificates are the certificates matched to the filter chain, in
+	// policy order.
+	Certificates []ListenerCertificate
+}
+
+// Translation is the xDS of a translation being modified by
+// TranslateMutators. Resources Envoy Gateway did not send are nil.
+type Translation struct {
+	Clusters  []*clusterv3.Cluster
+	Secrets   []*tlsv3.Secret
+	Listeners []*listenerv3.Listener
+	Routes    []*routev3.RouteConfiguration
+
+	// Policies are the policies of the translation.
+	Policies []v1beta1.CertificatePolicy
+
+	// PolicySecrets are the Envoy secrets resolved for the policies, in
+	// policy order. Policies whose Secrets could not be resolved are left
+	// out.
+	PolicySecrets [][]*tlsv3.Secret
+}
+
+// Registry holds the Mutators that can be enabled, in the order they run.
+type Registry struct {
+	mutators []Mutator
+}
+
+// NewRegistry returns a Registry of the given Mutators, which run in the
+// given order.
+func NewRegistry(mutators ...Mutator) (*Registry, error) {
+	r := &Registry{}
+	for _, mutator := range mutators {
+		if mutator.Name() == "" {
+			return nil, fmt.Errorf("mutator %T has no name", mutator)
+		}
+		if slices.Contains(r.Names(), mutator.Name()) {
+			return nil, fmt.Errorf("mutator %q is registered twice", mutator.Name())
+		}
+		r.mutators = append(r.mutators, mutator)
+	}
+	return r, nil
+}
+
+// DefaultRegistry returns a Registry of the built-in Mutators.
+func DefaultRegistry() *Registry {
+	return &Registry{mutators: []Mutator{certificatesMutator{}, tlsParamsMutator{}, sessionTicketKeysMutator{}, alpnMutator{}}}
+}
+
+// Names returns the names of the registered Mutators.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.mutators))
+	for _, mutator := range r.mutators {
+		names = append(names, mutator.Name())
+	}
+	return names
+}
+
+// All returns the registered Mutators.
+func (r *Registry) All() []Mutator {
+	return slices.Clone(r.mutators)
+}
+
+// Select returns the Mutators with the given names in registry order.
+func (r *Registry) Select(names []string) ([]Mutator, error) {
+	for _, name := range names {
+		if !slices.Contains(r.Names(), name) {
+			return nil, fmt.Errorf("unknown mutator %q, must be one of %v", name, r.Names())
+		}
+	}
+	var selected []Mutator
+	for _, mutator := range r.mutators {
+		if slices.Contains(names, mutator.Name()) {
+			selected = append(selected, mutator)
+		}
+	}
+	return selected, nil
+}
+
+// mutatorsOf returns the enabled Mutators implementing T.
+func mutatorsOf[T Mutator](s *Server) []T {
+	var mutators []T
+	for _, mutator := range s.mutators {
+		if m, ok := mutator.(T); ok {
+			mutators = append(mutators, m)
+		}
+	}
+	return mutators
+}
+
+// servesPolicySecrets reports whether PostTranslateModify adds the secrets of
+// policies to translations.
+func (s *Server) servesPolicySecrets() bool {
+	return s.hookEnabled(HookPostTranslateModify) && slices.ContainsFunc(s.mutators, func(mutator Mutator) bool {
+		_, ok := mutator.(certificatesMutator)
+		return ok
+	})
+}
+
+// listenerMutators returns the enabled ListenerMutators. Those referencing
+// secrets of policies are left out while the secrets are not served, as
+// Envoy would wait for them forever.
+func (s *Server) listenerMutators() []ListenerMutator {
+	mutators := mutatorsOf[ListenerMutator](s)
+	if s.servesPolicySecrets() {
+		return mutators
+	}
+	return slices.DeleteFunc(mutators, func(mutator ListenerMutator) bool {
+		_, ok := mutator.(policySecretReferencer)
+		return ok
+	})
+}
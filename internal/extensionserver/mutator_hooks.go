@@ -0,0 +1,93 @@
+package extensionserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	pb "github.com/envoyproxy/gateway/proto/extension"
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"google.golang.org/protobuf/proto"
+)
+
+// PostRouteModify runs the RouteMutators on a route. It is unimplemented
+// when no RouteMutator is enabled.
+func (s *Server) PostRouteModify(ctx context.Context, req *pb.PostRouteModifyRequest) (_ *pb.PostRouteModifyResponse, err error) {
+	mutators := mutatorsOf[RouteMutator](s)
+	if len(mutators) == 0 {
+		return s.UnimplementedEnvoyGatewayExtensionServer.PostRouteModify(ctx, req)
+	}
+	ctx, span := s.startSpan(ctx, HookPostRouteModify)
+	defer func() { endSpan(span, err) }()
+
+	if req.Route == nil {
+		return nil, statusError(invalidRequest(errors.New("request has no route")))
+	}
+	if !s.hookEnabled(HookPostRouteModify) {
+		return &pb.PostRouteModifyResponse{Route: req.Route}, nil
+	}
+
+	route, _ := proto.Clone(req.Route).(*routev3.Route)
+	for _, mutator := range mutators {
+		if err := mutator.MutateRoute(ctx, route, req.PostRouteContext); err != nil {
+			s.logger(ctx).Error("failed to modify route", "mutator", mutator.Name(), "route", route.GetName(), "error", err)
+			return nil, statusError(fmt.Errorf("mutator %s: %w", mutator.Name(), err))
+		}
+	}
+	return &pb.PostRouteModifyResponse{Route: route}, nil
+}
+
+// PostVirtualHostModify runs the VirtualHostMutators on a virtual host. It
+// is unimplemented when no VirtualHostMutator is enabled.
+func (s *Server) PostVirtualHostModify(ctx context.Context, req *pb.PostVirtualHostModifyRequest) (_ *pb.PostVirtualHostModifyResponse, err error) {
+	mutators := mutatorsOf[VirtualHostMutator](s)
+	if len(mutators) == 0 {
+		return s.UnimplementedEnvoyGatewayExtensionServer.PostVirtualHostModify(ctx, req)
+	}
+	ctx, span := s.startSpan(ctx, HookPostVirtualHostModify)
+	defer func() { endSpan(span, err) }()
+
+	if req.VirtualHost == nil {
+		return nil, statusError(invalidRequest(errors.New("request has no virtual host")))
+	}
+	if !s.hookEnabled(HookPostVirtualHostModify) {
+		return &pb.PostVirtualHostModifyResponse{VirtualHost: req.VirtualHost}, nil
+	}
+
+	virtualHost, _ := proto.Clone(req.VirtualHost).(*routev3.VirtualHost)
+	for _, mutator := range mutators {
+		if err := mutator.MutateVirtualHost(ctx, virtualHost); err != nil {
+			s.logger(ctx).Error("failed to modify virtual host", "mutator", mutator.Name(), "virtualHost", virtualHost.GetName(), "error", err)
+			return nil, statusError(fmt.Errorf("mutator %s: %w", mutator.Name(), err))
+		}
+	}
+	return &pb.PostVirtualHostModifyResponse{VirtualHost: virtualHost}, nil
+}
+
+// PostClusterModify runs the ClusterMutators on a cluster. It is
+// unimplemented when no ClusterMutator is enabled.
+func (s *Server) PostClusterModify(ctx context.Context, req *pb.PostClusterModifyRequest) (_ *pb.PostClusterModifyResponse, err error) {
+	mutators := mutatorsOf[ClusterMutator](s)
+	if len(mutators) == 0 {
+		return s.UnimplementedEnvoyGatewayExtensionServer.PostClusterModify(ctx, req)
+	}
+	ctx, span := s.startSpan(ctx, HookPostClusterModify)
+	defer func() { endSpan(span, err) }()
+
+	if req.Cluster == nil {
+		return nil, statusError(invalidRequest(errors.New("request has no cluster")))
+	}
+	if !s.hookEnabled(HookPostClusterModify) {
+		return &pb.PostClusterModifyResponse{Cluster: req.Cluster}, nil
+	}
+
+	cluster, _ := proto.Clone(req.Cluster).(*clusterv3.Cluster)
+	for _, mutator := range mutators {
+		if err := mutator.MutateCluster(ctx, cluster, req.PostClusterContext); err != nil {
+			s.logger(ctx).Error("failed to modify cluster", "mutator", mutator.Name(), "cluster", cluster.GetName(), "error", err)
+			return nil, statusError(fmt.Errorf("mutator %s: %w", mutator.Name(), err))
+		}
+	}
+	return &pb.PostClusterModifyResponse{Cluster: cluster}, nil
+}
@@ -0,0 +1,255 @@
+package extensionserver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	pb "github.com/envoyproxy/gateway/proto/extension"
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+	"github.com/giantswarm/envoy-extension-server-app/internal/ticketkeys"
+)
+
+// renamingMutator appends a suffix to the names of the routes, virtual hosts
+// and clusters it modifies.
+type renamingMutator struct {
+	name string
+	err  error
+}
+
+func (m renamingMutator) Name() string { return m.name }
+
+func (m renamingMutator) MutateRoute(_ context.Context, route *routev3.Route, _ *pb.PostRouteExtensionContext) error {
+	route.Name += "-" + m.name
+	return m.err
+}
+
+func (m renamingMutator) MutateVirtualHost(_ context.Context, virtualHost *routev3.VirtualHost) error {
+	virtualHost.Name += "-" + m.name
+	return m.err
+}
+
+func (m renamingMutator) MutateCluster(_ context.Context, cluster *clusterv3.Cluster, _ *pb.PostClusterExtensionContext) error {
+	cluster.Name += "-" + m.name
+	return m.err
+}
+
+func TestNewRegistry(t *testing.T) {
+	registry, err := NewRegistry(renamingMutator{name: "a"}, renamingMutator{name: "b"})
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+	if got := registry.Names(); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("Names() = %v, want [a b]", got)
+	}
+
+	if _, err := NewRegistry(renamingMutator{name: "a"}, renamingMutator{name: "a"}); err == nil {
+		t.Error("NewRegistry() expected an error for a duplicate name")
+	}
+	if _, err := NewRegistry(renamingMutator{}); err == nil {
+		t.Error("NewRegistry() expected an error for a mutator without name")
+	}
+}
+
+func TestRegistrySelect(t *testing.T) {
+	registry := DefaultRegistry()
+	if got := registry.Names(); !slices.Equal(got, []string{MutatorCertificates, MutatorTLSParams, MutatorSessionTicketKeys, MutatorALPN}) {
+		t.Errorf("DefaultRegistry().Names() = %v", got)
+	}
+
+	// Mutators run in registry order, whatever the order they are enabled in.
+	selected, err := registry.Select([]string{MutatorTLSParams, MutatorCertificates})
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	if len(selected) != 2 || selected[0].Name() != MutatorCertificates || selected[1].Name() != MutatorTLSParams {
+		t.Errorf("Select() = %v, want registry order", selected)
+	}
+
+	if selected, err := registry.Select(nil); err != nil || len(selected) != 0 {
+		t.Errorf("Select(nil) = %v, %v, want none", selected, err)
+	}
+	if _, err := registry.Select([]string{"headers"}); err == nil || !strings.Contains(err.Error(), `unknown mutator "headers"`) {
+		t.Errorf("Select() error = %v, want an unknown mutator error", err)
+	}
+}
+
+func TestMutatorHooksUnimplementedWithoutMutators(t *testing.T) {
+	server := newTestServer()
+	ctx := context.Background()
+
+	_, err := server.PostRouteModify(ctx, &pb.PostRouteModifyRequest{Route: &routev3.Route{}})
+	if got := status.Code(err); got != codes.Unimplemented {
+		t.Errorf("PostRouteModify() code = %v, want %v", got, codes.Unimplemented)
+	}
+	_, err = server.PostVirtualHostModify(ctx, &pb.PostVirtualHostModifyRequest{VirtualHost: &routev3.VirtualHost{}})
+	if got := status.Code(err); got != codes.Unimplemented {
+		t.Errorf("PostVirtualHostModify() code = %v, want %v", got, codes.Unimplemented)
+	}
+	_, err = server.PostClusterModify(ctx, &pb.PostClusterModifyRequest{Cluster: &clusterv3.Cluster{}})
+	if got := status.Code(err); got != codes.Unimplemented {
+		t.Errorf("PostClusterModify() code = %v, want %v", got, codes.Unimplemented)
+	}
+}
+
+func TestMutatorHooks(t *testing.T) {
+	server := newTestServerWithOptions([]Option{WithMutators(renamingMutator{name: "a"}, renamingMutator{name: "b"})})
+	ctx := context.Background()
+
+	route := &routev3.Route{Name: "route"}
+	routeResp, err := server.PostRouteModify(ctx, &pb.PostRouteModifyRequest{Route: route})
+	if err != nil || routeResp.Route.GetName() != "route-a-b" {
+		t.Errorf("PostRouteModify() = %v, %v, want route-a-b", routeResp, err)
+	}
+	if route.Name != "route" {
+		t.Errorf("request route was modified to %q", route.Name)
+	}
+
+	virtualHostResp, err := server.PostVirtualHostModify(ctx, &pb.PostVirtualHostModifyRequest{VirtualHost: &routev3.VirtualHost{Name: "vhost"}})
+	if err != nil || virtualHostResp.VirtualHost.GetName() != "vhost-a-b" {
+		t.Errorf("PostVirtualHostModify() = %v, %v, want vhost-a-b", virtualHostResp, err)
+	}
+
+	clusterResp, err := server.PostClusterModify(ctx, &pb.PostClusterModifyRequest{Cluster: &clusterv3.Cluster{Name: "cluster"}})
+	if err != nil || clusterResp.Cluster.GetName() != "cluster-a-b" {
+		t.Errorf("PostClusterModify() = %v, %v, want cluster-a-b", clusterResp, err)
+	}
+
+	if _, err := server.PostRouteModify(ctx, &pb.PostRouteModifyRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("PostRouteModify() without route error = %v, want InvalidArgument", err)
+	}
+}
+
+func TestMutatorHooksErrors(t *testing.T) {
+	server := newTestServerWithOptions([]Option{WithMutators(renamingMutator{name: "broken", err: errors.New("boom")})})
+
+	_, err := server.PostClusterModify(context.Background(), &pb.PostClusterModifyRequest{Cluster: &clusterv3.Cluster{Name: "cluster"}})
+	if status.Code(err) != codes.Internal || !strings.Contains(err.Error(), "mutator broken: boom") {
+		t.Errorf("PostClusterModify() error = %v, want the mutator error", err)
+	}
+}
+
+func TestMutatorHooksDisabled(t *testing.T) {
+	server := newTestServerWithOptions([]Option{
+		WithMutators(renamingMutator{name: "a"}),
+		WithEnabledHooks([]string{}),
+	})
+
+	resp, err := server.PostRouteModify(context.Background(), &pb.PostRouteModifyRequest{Route: &routev3.Route{Name: "route"}})
+	if err != nil || resp.Route.GetName() != "route" {
+		t.Errorf("PostRouteModify() = %v, %v, want the route unchanged", resp, err)
+	}
+}
+
+func TestWithMutators(t *testing.T) {
+	secret := createTLSSecret("secret-1", nil)
+	policies := []*pb.ExtensionResource{createExtensionResource(t, "secret-1")}
+
+	tests := []struct {
+		name        string
+		mutators    []string
+		wantSecrets int
+		wantSDS     int
+	}{
+		{name: "all built-in mutators", mutators: DefaultRegistry().Names(), wantSecrets: 1, wantSDS: 1},
+		{name: "without certificates", mutators: []string{MutatorTLSParams}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mutators, err := DefaultRegistry().Select(tt.mutators)
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := newTestServerWithOptions([]Option{WithMutators(mutators...)}, secret)
+
+			translated, err := server.PostTranslateModify(context.Background(), &pb.PostTranslateModifyRequest{
+				PostTranslateContext: &pb.PostTranslateExtensionContext{ExtensionResources: policies},
+			})
+			if err != nil {
+				t.Fatalf("PostTranslateModify() error = %v", err)
+			}
+			if len(translated.Secrets) != tt.wantSecrets {
+				t.Errorf("secrets = %v, want %d", translated.Secrets, tt.wantSecrets)
+			}
+
+			modified, err := server.PostHTTPListenerModify(context.Background(), &pb.PostHTTPListenerModifyRequest{
+				Listener:            newHTTPSListener(t, "default/gateway-1/https"),
+				PostListenerContext: &pb.PostHTTPListenerExtensionContext{ExtensionResources: policies},
+			})
+			if err != nil {
+				t.Fatalf("PostHTTPListenerModify() error = %v", err)
+			}
+			if got := listenerSecretReferences(modified.Listener); len(got) != tt.wantSDS {
+				t.Errorf("SDS references = %v, want %d", got, tt.wantSDS)
+			}
+		})
+	}
+}
+
+func TestPolicySecretsOnlyReferencedWhenServed(t *testing.T) {
+	policy := createTargetingPolicy("secret-1", gatewayTargetRef("gateway-1", ""))
+	policy.Spec.SessionTicketKeysSecretRef = &v1beta1.SecretReference{Name: "ticket-keys"}
+	policy.Spec.ALPNProtocols = []v1beta1.ALPNProtocol{v1beta1.ALPNHTTP11}
+	ticketKeys := createTicketKeysSecret("ticket-keys", bytes.Repeat([]byte{1}, ticketkeys.KeyLength))
+
+	tests := []struct {
+		name     string
+		mutators []string
+		hooks    []string
+	}{
+		{
+			name:     "without the certificates mutator",
+			mutators: []string{MutatorSessionTicketKeys, MutatorALPN},
+		},
+		{
+			name:     "without the translate hook",
+			mutators: DefaultRegistry().Names(),
+			hooks:    []string{HookPostHTTPListenerModify},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mutators, err := DefaultRegistry().Select(tt.mutators)
+			if err != nil {
+				t.Fatal(err)
+			}
+			recorder := record.NewFakeRecorder(10)
+			server := newTestServerWithOptions([]Option{
+				WithMutators(mutators...),
+				WithEnabledHooks(tt.hooks),
+				WithEventRecorder(recorder),
+			}, createTLSSecret("secret-1", nil), ticketKeys, policy.DeepCopy())
+			gateway := startFakeEnvoyGateway(t, server)
+
+			snapshot, err := gateway.translate(context.Background(),
+				[]*listenerv3.Listener{newHTTPSListener(t, "default/gateway-1/https")}, nil,
+				[]*pb.ExtensionResource{marshalExtensionResource(t, policy)},
+			)
+			if err != nil {
+				t.Fatalf("translation failed: %v", err)
+			}
+			if got := listenerSecretReferences(snapshot.listeners[0]); len(got) != 0 {
+				t.Errorf("SDS references = %v, want none as the secrets are not served", got)
+			}
+			if len(snapshot.secrets) != 0 {
+				t.Errorf("secrets = %v, want none", slices.Collect(maps.Keys(snapshot.secrets)))
+			}
+			if events := drainEvents(recorder); slices.ContainsFunc(events, func(event string) bool { return strings.Contains(event, eventReasonProgrammed) }) {
+				t.Errorf("events = %v, want no %s event for a policy that was not applied", events, eventReasonProgrammed)
+			}
+		})
+	}
+}
@@ -3,79 +3,226 @@ package extensionserver
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
 
 	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	quicv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/quic/v3"
 	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	pb "github.com/envoyproxy/gateway/proto/extension"
 
 	"github.com/giantswarm/envoy-extension-server-app/api/v1alpha1"
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+	"github.com/giantswarm/envoy-extension-server-app/internal/logging"
 )
 
 // PostHTTPListenerModify is called after Envoy Gateway is done generating a
 // Listener xDS configuration and before that configuration is passed on to
 // Envoy Proxy.
-func (s *Server) PostHTTPListenerModify(ctx context.Context, req *pb.PostHTTPListenerModifyRequest) (*pb.PostHTTPListenerModifyResponse, error) {
+func (s *Server) PostHTTPListenerModify(ctx context.Context, req *pb.PostHTTPListenerModifyRequest) (_ *pb.PostHTTPListenerModifyResponse, err error) {
+	ctx, span := s.startSpan(ctx, HookPostHTTPListenerModify, attributeListener.String(req.GetListener().GetName()))
+	defer func() { endSpan(span, err) }()
+
+	if req.Listener == nil {
+		return nil, statusError(invalidRequest(errors.New("request has no listener")))
+	}
+	if !s.hookEnabled(HookPostHTTPListenerModify) {
+		return &pb.PostHTTPListenerModifyResponse{Listener: req.Listener}, nil
+	}
 	listenerName := req.Listener.GetName()
-	s.log.Info("postHTTPListenerModify callback was invoked", "listener", listenerName)
+	ctx = logging.NewContext(ctx, s.logger(ctx).With("listener", listenerName))
+	s.logger(ctx).Info("postHTTPListenerModify callback was invoked")
 
 	filterChains := req.Listener.GetFilterChains()
 	if len(filterChains) == 0 {
-		s.log.Info("no filter chains found for listener", "listener", listenerName)
+		s.logger(ctx).Info("no filter chains found for listener")
 	}
 
-	policies := s.extractCertificatePolicies(req.PostListenerContext.GetExtensionResources())
+	policies := s.extractCertificatePolicies(ctx, req.PostListenerContext.GetExtensionResources())
+	if len(s.listenerMutators()) == 0 {
+		// Nothing is applied, so the policies are neither resolved nor
+		// reported.
+		policies = nil
+	}
+	certificates, err := s.listenerCertificates(ctx, policies)
+	if err != nil {
+		s.logger(ctx).Error("aborting listener modification, secrets are temporarily unavailable",
+			"error", err,
+		)
+		return nil, statusError(err)
+	}
 
+	original, _ := proto.Clone(req.Listener).(*listenerv3.Listener)
 	for _, filterChain := range filterChains {
-		if err := s.applyPoliciesToFilterChain(filterChain, policies); err != nil {
-			s.log.Error("failed to apply policies to filter chain", "error", err)
+		if err := s.applyPoliciesToFilterChain(ctx, req.Listener, filterChain, certificates); err != nil {
+			s.logger(ctx).Error("failed to apply policies to filter chain",
+				"filterChain", filterChain.GetName(),
+				"error", err,
+			)
+			return nil, statusError(fmt.Errorf("filter chain %q of listener %q: %w", filterChain.GetName(), listenerName, err))
 		}
 	}
 
+	useOriginal, err := s.checkOutput(ctx, HookPostHTTPListenerModify, policies,
+		func() []error { return listenerErrors(original) },
+		func() []error { return listenerErrors(req.Listener) },
+	)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	if useOriginal {
+		return &pb.PostHTTPListenerModifyResponse{
+			Listener: original,
+		}, nil
+	}
+
 	return &pb.PostHTTPListenerModifyResponse{
 		Listener: req.Listener,
 	}, nil
 }
 
-// extractCertificatePolicies unmarshals extension resources into CertificatePolicy objects.
-func (s *Server) extractCertificatePolicies(extensions []*pb.ExtensionResource) []v1alpha1.CertificatePolicy {
-	var policies []v1alpha1.CertificatePolicy
+// extractCertificatePolicies unmarshals extension resources into CertificatePolicy
+// objects. Policies of older API versions are converted to v1beta1.
+func (s *Server) extractCertificatePolicies(ctx context.Context, extensions []*pb.ExtensionResource) []v1beta1.CertificatePolicy {
+	_, span := s.startSpan(ctx, "extractCertificatePolicies")
+	defer span.End()
+
+	var policies []v1beta1.CertificatePolicy
 	for _, ext := range extensions {
-		var certPolicy v1alpha1.CertificatePolicy
-		if err := json.Unmarshal(ext.GetUnstructuredBytes(), &certPolicy); err != nil {
-			s.log.Error("failed to unmarshal the extension", slog.String("error", err.Error()))
+		var typeMeta metav1.TypeMeta
+		if err := json.Unmarshal(ext.GetUnstructuredBytes(), &typeMeta); err == nil && !s.policyKindEnabled(typeMeta.Kind) {
+			s.logger(ctx).Debug("ignoring extension resource of disabled kind", "kind", typeMeta.Kind)
 			continue
 		}
-		s.log.Info("processing an extension context", slog.String("secretName", certPolicy.Spec.SecretName))
+		certPolicy, err := decodeCertificatePolicy(ext.GetUnstructuredBytes())
+		if err != nil {
+			s.logger(ctx).Error("failed to unmarshal the extension", slog.String("error", err.Error()))
+			span.RecordError(err)
+			continue
+		}
+		s.logger(ctx).Info("processing an extension context", slog.String("secretName", certPolicy.Spec.SecretRef.Name))
 		policies = append(policies, certPolicy)
 	}
+	span.SetAttributes(attributePolicyCount.Int(len(policies)))
 	return policies
 }
 
-// applyPoliciesToFilterChain adds SDS secret configs from policies to a filter chain's TLS context.
-func (s *Server) applyPoliciesToFilterChain(filterChain *listenerv3.FilterChain, policies []v1alpha1.CertificatePolicy) error {
+// decodeCertificatePolicy decodes a CertificatePolicy of any served API
+// version into the v1beta1 hub version. Policies without a Secret are
+// rejected, e.g. v1alpha1 objects read as v1beta1 without conversion.
+func decodeCertificatePolicy(data []byte) (v1beta1.CertificatePolicy, error) {
+	var policy v1beta1.CertificatePolicy
+	var typeMeta metav1.TypeMeta
+	if err := json.Unmarshal(data, &typeMeta); err != nil {
+		return policy, err
+	}
+
+	switch typeMeta.APIVersion {
+	case v1beta1.GroupVersion.String():
+		if err := json.Unmarshal(data, &policy); err != nil {
+			return policy, err
+		}
+	case v1alpha1.GroupVersion.String():
+		var spoke v1alpha1.CertificatePolicy
+		if err := json.Unmarshal(data, &spoke); err != nil {
+			return policy, err
+		}
+		if err := spoke.ConvertTo(&policy); err != nil {
+			return policy, fmt.Errorf("failed to convert %s CertificatePolicy: %w", typeMeta.APIVersion, err)
+		}
+	default:
+		return policy, fmt.Errorf("unsupported CertificatePolicy apiVersion %q", typeMeta.APIVersion)
+	}
+	if policy.Spec.SecretRef.Name == "" {
+		return policy, fmt.Errorf("CertificatePolicy %s/%s has no secretRef.name", policy.Namespace, policy.Name)
+	}
+	return policy, nil
+}
+
+// applyPoliciesToFilterChain runs the ListenerMutators on a TLS or QUIC
+// filter chain the certificates are matched to.
+func (s *Server) applyPoliciesToFilterChain(ctx context.Context, listener *listenerv3.Listener, filterChain *listenerv3.FilterChain, certificates []ListenerCertificate) error {
 	transportSocket := filterChain.GetTransportSocket()
 	if transportSocket == nil || transportSocket.GetTypedConfig() == nil {
 		return nil
 	}
+	// Other transport sockets are left alone.
+	if !transportSocket.GetTypedConfig().MessageIs(&tlsv3.DownstreamTlsContext{}) && !isQUIC(transportSocket) {
+		return nil
+	}
+
+	var matched []ListenerCertificate
+	for _, certificate := range certificates {
+		if certificate.matchesFilterChain(filterChain) {
+			matched = append(matched, certificate)
+		}
+	}
+	mutators := s.listenerMutators()
+	if len(matched) == 0 || len(mutators) == 0 {
+		return nil
+	}
 
-	s.log.Info("transport socket", "transportSocket", transportSocket)
+	s.logger(ctx).Debug("applying policies to filter chain",
+		"filterChain", filterChain.GetName(),
+		"transportSocket", transportSocket,
+	)
 
+	_, span := s.startSpan(ctx, "unmarshalTLSContext")
 	downstreamTlsContext, err := extractDownstreamTlsContext(transportSocket)
+	endSpan(span, err)
 	if err != nil {
-		return err
+		return invalidRequest(fmt.Errorf("failed to decode TLS context: %w", err))
+	}
+
+	mutated := &FilterChain{
+		Listener:     listener,
+		FilterChain:  filterChain,
+		TLSContext:   downstreamTlsContext,
+		QUIC:         isQUIC(transportSocket),
+		Certificates: matched,
+	}
+	for _, mutator := range mutators {
+		if err := mutator.MutateFilterChain(ctx, mutated); err != nil {
+			return fmt.Errorf("mutator %s: %w", mutator.Name(), err)
+		}
 	}
 
-	appendSdsSecretConfigs(downstreamTlsContext, policies)
+	_, span = s.startSpan(ctx, "marshalTLSContext")
+	err = updateTransportSocket(transportSocket, downstreamTlsContext)
+	endSpan(span, err)
+	if err != nil {
+		return fmt.Errorf("failed to encode TLS context: %w", err)
+	}
+	return nil
+}
 
-	return updateTransportSocket(transportSocket, downstreamTlsContext)
+// isQUIC reports whether a transport socket is the QUIC transport of an
+// HTTP/3 listener, which wraps a DownstreamTlsContext.
+func isQUIC(transportSocket *corev3.TransportSocket) bool {
+	return transportSocket.GetTypedConfig().MessageIs(&quicv3.QuicDownstreamTransport{})
 }
 
 // extractDownstreamTlsContext unmarshals the transport socket config into a DownstreamTlsContext.
+// The TLS context of a QUIC transport is unwrapped.
 func extractDownstreamTlsContext(transportSocket *corev3.TransportSocket) (*tlsv3.DownstreamTlsContext, error) {
+	if isQUIC(transportSocket) {
+		quicTransport := &quicv3.QuicDownstreamTransport{}
+		if err := transportSocket.GetTypedConfig().UnmarshalTo(quicTransport); err != nil {
+			return nil, err
+		}
+		if quicTransport.DownstreamTlsContext == nil {
+			return &tlsv3.DownstreamTlsContext{}, nil
+		}
+		return quicTransport.DownstreamTlsContext, nil
+	}
+
 	downstreamTlsContext := &tlsv3.DownstreamTlsContext{}
 	if err := transportSocket.GetTypedConfig().UnmarshalTo(downstreamTlsContext); err != nil {
 		return nil, err
@@ -83,8 +230,10 @@ func extractDownstreamTlsContext(transportSocket *corev3.TransportSocket) (*tlsv
 	return downstreamTlsContext, nil
 }
 
-// appendSdsSecretConfigs adds SDS secret configs for each policy to the TLS context.
-func appendSdsSecretConfigs(tlsContext *tlsv3.DownstreamTlsContext, policies []v1alpha1.CertificatePolicy) {
+// appendSdsSecretConfigs adds SDS secret configs for each secret name to the TLS context.
+// Names the TLS context references already are skipped, e.g. the certificate
+// of a policy in Reference mode Envoy Gateway attached itself.
+func appendSdsSecretConfigs(tlsContext *tlsv3.DownstreamTlsContext, secretNames []string) {
 	if tlsContext.CommonTlsContext == nil {
 		tlsContext.CommonTlsContext = &tlsv3.CommonTlsContext{}
 	}
@@ -92,18 +241,28 @@ func appendSdsSecretConfigs(tlsContext *tlsv3.DownstreamTlsContext, policies []v
 		tlsContext.CommonTlsContext.TlsCertificateSdsSecretConfigs = []*tlsv3.SdsSecretConfig{}
 	}
 
-	for _, policy := range policies {
-		newSdsConfig := NewSdsSecretConfig(policy.Spec.SecretName)
-		tlsContext.CommonTlsContext.TlsCertificateSdsSecretConfigs = append(
-			tlsContext.CommonTlsContext.TlsCertificateSdsSecretConfigs,
-			newSdsConfig,
-		)
+	for _, secretName := range secretNames {
+		configs := tlsContext.CommonTlsContext.TlsCertificateSdsSecretConfigs
+		if slices.ContainsFunc(configs, func(config *tlsv3.SdsSecretConfig) bool { return config.GetName() == secretName }) {
+			continue
+		}
+		tlsContext.CommonTlsContext.TlsCertificateSdsSecretConfigs = append(configs, NewSdsSecretConfig(secretName))
 	}
 }
 
 // updateTransportSocket marshals the TLS context back and updates the transport socket.
+// A QUIC transport keeps its other settings.
 func updateTransportSocket(transportSocket *corev3.TransportSocket, tlsContext *tlsv3.DownstreamTlsContext) error {
-	modifiedTypedConfig, err := anypb.New(tlsContext)
+	var config proto.Message = tlsContext
+	if isQUIC(transportSocket) {
+		quicTransport := &quicv3.QuicDownstreamTransport{}
+		if err := transportSocket.GetTypedConfig().UnmarshalTo(quicTransport); err != nil {
+			return err
+		}
+		quicTransport.DownstreamTlsContext = tlsContext
+		config = quicTransport
+	}
+	modifiedTypedConfig, err := anypb.New(config)
 	if err != nil {
 		return err
 	}
@@ -1,10 +1,12 @@
 package extensionserver
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"log/slog"
-	"os"
+	"strings"
 	"testing"
 
 	pb "github.com/envoyproxy/gateway/proto/extension"
@@ -15,11 +17,12 @@ import (
 	"google.golang.org/protobuf/types/known/anypb"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
-	"github.com/giantswarm/envoy-extension-server-app/api/v1alpha1"
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+	"github.com/giantswarm/envoy-extension-server-app/internal/logging"
 )
 
 func newTestServer() *Server {
-	return New(slog.New(slog.NewTextHandler(os.Stdout, nil)), nil)
+	return newTestServerWithObjects()
 }
 
 func TestExtractCertificatePolicies(t *testing.T) {
@@ -69,15 +72,15 @@ func TestExtractCertificatePolicies(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			policies := server.extractCertificatePolicies(tt.extensions)
+			policies := server.extractCertificatePolicies(context.Background(), tt.extensions)
 
 			if len(policies) != tt.wantCount {
 				t.Errorf("extractCertificatePolicies() returned %d policies, want %d", len(policies), tt.wantCount)
 			}
 
 			for i, wantName := range tt.wantNames {
-				if policies[i].Spec.SecretName != wantName {
-					t.Errorf("policy[%d].Spec.SecretName = %q, want %q", i, policies[i].Spec.SecretName, wantName)
+				if policies[i].Spec.SecretRef.Name != wantName {
+					t.Errorf("policy[%d].Spec.SecretRef.Name = %q, want %q", i, policies[i].Spec.SecretRef.Name, wantName)
 				}
 			}
 		})
@@ -127,14 +130,14 @@ func TestAppendSdsSecretConfigs(t *testing.T) {
 	tests := []struct {
 		name            string
 		tlsContext      *tlsv3.DownstreamTlsContext
-		policies        []v1alpha1.CertificatePolicy
+		secretNames     []string
 		wantConfigCount int
 		wantSecretNames []string
 	}{
 		{
 			name:            "nil CommonTlsContext gets initialized",
 			tlsContext:      &tlsv3.DownstreamTlsContext{},
-			policies:        []v1alpha1.CertificatePolicy{createPolicy("secret-1")},
+			secretNames:     []string{"secret-1"},
 			wantConfigCount: 1,
 			wantSecretNames: []string{"secret-1"},
 		},
@@ -147,21 +150,34 @@ func TestAppendSdsSecretConfigs(t *testing.T) {
 					},
 				},
 			},
-			policies:        []v1alpha1.CertificatePolicy{createPolicy("new-secret")},
+			secretNames:     []string{"new-secret"},
+			wantConfigCount: 2,
+			wantSecretNames: []string{"existing-secret", "new-secret"},
+		},
+		{
+			name: "skips attached secrets",
+			tlsContext: &tlsv3.DownstreamTlsContext{
+				CommonTlsContext: &tlsv3.CommonTlsContext{
+					TlsCertificateSdsSecretConfigs: []*tlsv3.SdsSecretConfig{
+						{Name: "existing-secret"},
+					},
+				},
+			},
+			secretNames:     []string{"existing-secret", "new-secret", "new-secret"},
 			wantConfigCount: 2,
 			wantSecretNames: []string{"existing-secret", "new-secret"},
 		},
 		{
 			name:            "multiple policies",
 			tlsContext:      &tlsv3.DownstreamTlsContext{},
-			policies:        []v1alpha1.CertificatePolicy{createPolicy("secret-1"), createPolicy("secret-2")},
+			secretNames:     []string{"secret-1", "secret-2"},
 			wantConfigCount: 2,
 			wantSecretNames: []string{"secret-1", "secret-2"},
 		},
 		{
 			name:            "empty policies",
 			tlsContext:      &tlsv3.DownstreamTlsContext{},
-			policies:        []v1alpha1.CertificatePolicy{},
+			secretNames:     []string{},
 			wantConfigCount: 0,
 			wantSecretNames: nil,
 		},
@@ -169,7 +185,7 @@ func TestAppendSdsSecretConfigs(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			appendSdsSecretConfigs(tt.tlsContext, tt.policies)
+			appendSdsSecretConfigs(tt.tlsContext, tt.secretNames)
 
 			configs := tt.tlsContext.CommonTlsContext.TlsCertificateSdsSecretConfigs
 			if len(configs) != tt.wantConfigCount {
@@ -220,32 +236,32 @@ func TestApplyPoliciesToFilterChain(t *testing.T) {
 	server := newTestServer()
 
 	tests := []struct {
-		name        string
-		filterChain *listenerv3.FilterChain
-		policies    []v1alpha1.CertificatePolicy
-		wantErr     bool
+		name         string
+		filterChain  *listenerv3.FilterChain
+		certificates []ListenerCertificate
+		wantErr      bool
 	}{
 		{
 			name: "nil transport socket returns nil",
 			filterChain: &listenerv3.FilterChain{
 				TransportSocket: nil,
 			},
-			policies: []v1alpha1.CertificatePolicy{createPolicy("secret-1")},
-			wantErr:  false,
+			certificates: []ListenerCertificate{{SecretName: "default/secret-1"}},
+			wantErr:      false,
 		},
 		{
 			name: "applies policies to valid filter chain",
 			filterChain: &listenerv3.FilterChain{
 				TransportSocket: createTransportSocketWithTLS(t),
 			},
-			policies: []v1alpha1.CertificatePolicy{createPolicy("secret-1"), createPolicy("secret-2")},
-			wantErr:  false,
+			certificates: []ListenerCertificate{{SecretName: "default/secret-1"}, {SecretName: "default/secret-2"}},
+			wantErr:      false,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := server.applyPoliciesToFilterChain(tt.filterChain, tt.policies)
+			err := server.applyPoliciesToFilterChain(context.Background(), nil, tt.filterChain, tt.certificates)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("applyPoliciesToFilterChain() error = %v, wantErr %v", err, tt.wantErr)
@@ -314,6 +330,55 @@ func TestPostHTTPListenerModify(t *testing.T) {
 
 // Helper functions
 
+func TestPostHTTPListenerModifyLogging(t *testing.T) {
+	transportSocket := &corev3.TransportSocket{
+		Name: "envoy.transport_sockets.tls",
+		ConfigType: &corev3.TransportSocket_TypedConfig{
+			TypedConfig: mustAny(t, &tlsv3.DownstreamTlsContext{
+				CommonTlsContext: &tlsv3.CommonTlsContext{
+					TlsCertificates: []*tlsv3.TlsCertificate{{PrivateKey: inlineBytes([]byte("inline-private-key"))}},
+				},
+			}),
+		},
+	}
+	listener := &listenerv3.Listener{
+		Name:         "default/gateway-1/https",
+		FilterChains: []*listenerv3.FilterChain{{Name: "https", TransportSocket: transportSocket}},
+	}
+
+	var buf bytes.Buffer
+	server := newTestServer()
+	server.log = logging.New(&buf, logging.FormatJSON, slog.LevelDebug)
+	_, err := server.PostHTTPListenerModify(context.Background(), &pb.PostHTTPListenerModifyRequest{
+		Listener: listener,
+		PostListenerContext: &pb.PostHTTPListenerExtensionContext{
+			ExtensionResources: []*pb.ExtensionResource{createExtensionResource(t, "secret-1")},
+		},
+	})
+	if err != nil {
+		t.Fatalf("PostHTTPListenerModify() error = %v", err)
+	}
+
+	var loggedTransportSocket bool
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var record map[string]any
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("log line %q is not JSON: %v", line, err)
+		}
+		if record["listener"] != listener.Name {
+			t.Errorf("log record %v does not carry the listener name", record)
+		}
+		_, ok := record["transportSocket"]
+		loggedTransportSocket = loggedTransportSocket || ok
+	}
+	if !loggedTransportSocket {
+		t.Error("the transport socket was not logged")
+	}
+	if strings.Contains(buf.String(), "inline-private-key") || strings.Contains(buf.String(), base64.StdEncoding.EncodeToString([]byte("inline-private-key"))) {
+		t.Errorf("log output contains the private key: %s", buf.String())
+	}
+}
+
 func createExtensionResource(t *testing.T, secretName string) *pb.ExtensionResource {
 	t.Helper()
 	policy := createPolicy(secretName)
@@ -326,18 +391,18 @@ func createExtensionResource(t *testing.T, secretName string) *pb.ExtensionResou
 	}
 }
 
-func createPolicy(secretName string) v1alpha1.CertificatePolicy {
-	return v1alpha1.CertificatePolicy{
+func createPolicy(secretName string) v1beta1.CertificatePolicy {
+	return v1beta1.CertificatePolicy{
 		TypeMeta: metav1.TypeMeta{
-			APIVersion: "example.extensions.io/v1alpha1",
+			APIVersion: v1beta1.GroupVersion.String(),
 			Kind:       "CertificatePolicy",
 		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "test-policy",
 			Namespace: "default",
 		},
-		Spec: v1alpha1.CertificatePolicySpec{
-			SecretName: secretName,
+		Spec: v1beta1.CertificatePolicySpec{
+			SecretRef: v1beta1.SecretReference{Name: secretName},
 		},
 	}
 }
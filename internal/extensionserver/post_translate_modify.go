@@ -3,6 +3,7 @@ package extensionserver
 import (
 	"context"
 	"fmt"
+	"slices"
 
 	pb "github.com/envoyproxy/gateway/proto/extension"
 	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
@@ -10,14 +11,23 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 
-	"github.com/giantswarm/envoy-extension-server-app/api/v1alpha1"
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+	"github.com/giantswarm/envoy-extension-server-app/internal/logging"
 )
 
-func (s *Server) PostTranslateModify(ctx context.Context, req *pb.PostTranslateModifyRequest) (*pb.PostTranslateModifyResponse, error) {
-	s.log.Info("PostTranslateModify callback was invoked")
+func (s *Server) PostTranslateModify(ctx context.Context, req *pb.PostTranslateModifyRequest) (_ *pb.PostTranslateModifyResponse, err error) {
+	ctx, span := s.startSpan(ctx, HookPostTranslateModify)
+	defer func() { endSpan(span, err) }()
+
+	if !s.hookEnabled(HookPostTranslateModify) {
+		return &pb.PostTranslateModifyResponse{Secrets: req.Secrets}, nil
+	}
+
+	ctx = logging.NewContext(ctx, s.logger(ctx))
+	s.logger(ctx).Info("PostTranslateModify callback was invoked")
 
 	// Log incoming request details
-	s.log.Debug("request details",
+	s.logger(ctx).Debug("request details",
 		"clustersCount", len(req.GetClusters()),
 		"secretsCount", len(req.GetSecrets()),
 		"listenersCount", len(req.GetListeners()),
@@ -27,65 +37,159 @@ func (s *Server) PostTranslateModify(ctx context.Context, req *pb.PostTranslateM
 
 	// Log existing secrets from the request
 	for i, secret := range req.GetSecrets() {
-		s.log.Debug("existing secret in request",
+		s.logger(ctx).Debug("existing secret in request",
 			"index", i,
 			"name", secret.GetName(),
 		)
 	}
 
 	// Extract CertificatePolicies from the request's extension resources
-	policies := s.extractCertificatePolicies(req.PostTranslateContext.GetExtensionResources())
+	policies := s.extractCertificatePolicies(ctx, req.PostTranslateContext.GetExtensionResources())
 
-	s.log.Info("fetched CertificatePolicies", "count", len(policies))
+	s.logger(ctx).Info("fetched CertificatePolicies", "count", len(policies))
 
-	// Start with the existing secrets from the request
-	secrets := req.Secrets
+	mutators := mutatorsOf[TranslateMutator](s)
+	if len(mutators) == 0 {
+		// Nothing is applied, so the policies are neither resolved nor
+		// reported.
+		policies = nil
+	}
+	translation := &Translation{
+		Clusters:  req.Clusters,
+		Secrets:   slices.Clone(req.Secrets),
+		Listeners: req.Listeners,
+		Routes:    req.Routes,
+		Policies:  policies,
+	}
+
+	existing := make(map[string]*tlsv3.Secret, len(req.Secrets))
+	for _, secret := range req.Secrets {
+		existing[secret.GetName()] = secret
+	}
 
-	// Fetch and add secrets referenced by each policy
-	for _, policy := range policies {
-		s.log.Info("processing CertificatePolicy",
+	// Fetch the secrets referenced by the policies concurrently
+	results := s.resolveAll(ctx, policies, func(ctx context.Context, policy v1beta1.CertificatePolicy) ([]*tlsv3.Secret, error) {
+		s.logger(ctx).Info("processing CertificatePolicy",
 			"name", policy.Name,
 			"namespace", policy.Namespace,
-			"secretName", policy.Spec.SecretName,
+			"secretName", policy.Spec.SecretRef.Name,
 		)
+		s.checkTargets(ctx, policy)
+		return s.deliverPolicySecrets(ctx, policy, existing)
+	})
 
-		envoySecret, err := s.fetchAndConvertSecret(ctx, policy)
+	// Keep them in policy order to keep the response stable
+	for i, policy := range policies {
+		envoySecrets, err := results[i].secrets, results[i].err
+		if s.skipOnTimeout(ctx, policy, err) {
+			continue
+		}
+		if isTransient(err) {
+			s.logger(ctx).Error("aborting translation, secret for policy is temporarily unavailable",
+				"policy", policy.Name,
+				"secretName", policy.Spec.SecretRef.Name,
+				"error", err,
+			)
+			return nil, statusError(err)
+		}
 		if err != nil {
-			s.log.Error("failed to fetch secret for policy",
+			s.logger(ctx).Error("failed to fetch secret for policy",
 				"policy", policy.Name,
-				"secretName", policy.Spec.SecretName,
+				"secretName", policy.Spec.SecretRef.Name,
 				"error", err,
 			)
 			continue
 		}
 
-		secrets = append(secrets, envoySecret)
-		s.log.Info("added secret to response",
-			"secretName", envoySecret.Name,
-		)
+		envoySecrets, dropped := pairCertificates(envoySecrets)
+		s.reportKeyAlgorithms(ctx, policy, dropped)
+
+		if primary := SecretName(policy.Namespace, policy.Spec.SecretRef.Name); envoySecrets[0].Name == primary {
+			s.recordPolicyEvent(policy, corev1.EventTypeNormal, eventReasonProgrammed,
+				"Certificate from Secret %s is served as Envoy secret %s", policy.Spec.SecretRef.Name, primary)
+		}
+		translation.PolicySecrets = append(translation.PolicySecrets, envoySecrets)
+	}
+
+	for _, mutator := range mutators {
+		if err := mutator.MutateTranslation(ctx, translation); err != nil {
+			s.logger(ctx).Error("failed to modify translation", "mutator", mutator.Name(), "error", err)
+			return nil, statusError(fmt.Errorf("mutator %s: %w", mutator.Name(), err))
+		}
+	}
+	// Policies left out, e.g. because their Secret could not be read in
+	// time, must not leave references behind.
+	if err := s.removeUnservedReferences(translation.Listeners, translation.Secrets, translation.Policies); err != nil {
+		return nil, statusError(err)
+	}
+	if err := s.pairListenerCertificates(ctx, translation.Listeners, req.Secrets, translation.Secrets, translation.Policies); err != nil {
+		return nil, statusError(err)
 	}
+	secrets := translation.Secrets
+
+	span.SetAttributes(attributeEnvoySecretCount.Int(len(secrets)))
 
 	// Log final response summary
-	s.log.Debug("response summary",
+	s.logger(ctx).Debug("response summary",
 		"totalSecretsCount", len(secrets),
 		"addedSecretsCount", len(secrets)-len(req.GetSecrets()),
 	)
 
+	useOriginal, err := s.checkOutput(ctx, HookPostTranslateModify, translation.Policies,
+		func() []error { return translationErrors(req.Listeners, req.Routes, req.Clusters, req.Secrets) },
+		func() []error {
+			return translationErrors(translation.Listeners, translation.Routes, translation.Clusters, translation.Secrets)
+		},
+	)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	if useOriginal {
+		// The listener hook referenced the secrets of the policies already.
+		listeners := cloneListeners(req.Listeners)
+		if err := s.removeUnservedReferences(listeners, req.Secrets, translation.Policies); err != nil {
+			return nil, statusError(err)
+		}
+		return &pb.PostTranslateModifyResponse{
+			Clusters:  req.Clusters,
+			Secrets:   req.Secrets,
+			Listeners: listeners,
+			Routes:    req.Routes,
+		}, nil
+	}
+
 	return &pb.PostTranslateModifyResponse{
-		Secrets: secrets,
+		Clusters:  translation.Clusters,
+		Secrets:   translation.Secrets,
+		Listeners: translation.Listeners,
+		Routes:    translation.Routes,
 	}, nil
 }
 
-// fetchAndConvertSecret fetches a K8s TLS secret and converts it to an Envoy Secret.
-func (s *Server) fetchAndConvertSecret(ctx context.Context, policy v1alpha1.CertificatePolicy) (*tlsv3.Secret, error) {
-	var k8sSecret corev1.Secret
+// fetchAndConvertSecret fetches the K8s TLS secret referenced by a policy and
+// converts it to Envoy Secrets.
+func (s *Server) fetchAndConvertSecret(ctx context.Context, policy v1beta1.CertificatePolicy) ([]*tlsv3.Secret, error) {
 	secretKey := types.NamespacedName{
 		Namespace: policy.Namespace,
-		Name:      policy.Spec.SecretName,
+		Name:      policy.Spec.SecretRef.Name,
 	}
+	return s.fetchAndConvertNamedSecret(ctx, secretKey, policy.Spec.PrivateKeyProvider)
+}
 
+// fetchAndConvertNamedSecret fetches a K8s TLS secret and converts it to Envoy Secrets.
+// The first returned secret always holds the certificate chain and private key.
+// When the K8s secret also carries a ca.crt key, a ValidationContext secret with
+// the issuing CA is returned alongside it.
+func (s *Server) fetchAndConvertNamedSecret(ctx context.Context, secretKey types.NamespacedName, privateKeyProvider *v1beta1.PrivateKeyProvider) (_ []*tlsv3.Secret, err error) {
+	ctx, span := s.startSpan(ctx, "fetchSecret",
+		attributeNamespace.String(secretKey.Namespace),
+		attributeSecretName.String(secretKey.Name),
+	)
+	defer func() { endSpan(span, err) }()
+
+	var k8sSecret corev1.Secret
 	if err := s.client.Get(ctx, secretKey, &k8sSecret); err != nil {
-		return nil, fmt.Errorf("failed to get secret %s/%s: %w", secretKey.Namespace, secretKey.Name, err)
+		return nil, classifyAPIError(fmt.Errorf("failed to get secret %s/%s: %w", secretKey.Namespace, secretKey.Name, err))
 	}
 
 	certChain, ok := k8sSecret.Data[corev1.TLSCertKey]
@@ -93,29 +197,66 @@ func (s *Server) fetchAndConvertSecret(ctx context.Context, policy v1alpha1.Cert
 		return nil, fmt.Errorf("secret %s/%s missing %s key", secretKey.Namespace, secretKey.Name, corev1.TLSCertKey)
 	}
 
-	privateKey, ok := k8sSecret.Data[corev1.TLSPrivateKeyKey]
-	if !ok {
-		return nil, fmt.Errorf("secret %s/%s missing %s key", secretKey.Namespace, secretKey.Name, corev1.TLSPrivateKeyKey)
+	tlsCertificate, err := newTlsCertificate(privateKeyProvider, certChain, k8sSecret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, fmt.Errorf("secret %s/%s: %w", secretKey.Namespace, secretKey.Name, err)
 	}
 
-	// Use namespace/name format for the Envoy secret name to ensure uniqueness
-	envoySecretName := fmt.Sprintf("%s/%s", secretKey.Namespace, secretKey.Name)
+	secrets := []*tlsv3.Secret{
+		{
+			Name: SecretName(secretKey.Namespace, secretKey.Name),
+			Type: &tlsv3.Secret_TlsCertificate{
+				TlsCertificate: tlsCertificate,
+			},
+		},
+	}
+	s.logger(ctx).Debug("converted secret",
+		"secretName", secrets[0].Name,
+		"keyAlgorithm", secretKeyAlgorithm(secrets[0]),
+	)
 
-	return &tlsv3.Secret{
-		Name: envoySecretName,
-		Type: &tlsv3.Secret_TlsCertificate{
-			TlsCertificate: &tlsv3.TlsCertificate{
-				CertificateChain: &corev3.DataSource{
-					Specifier: &corev3.DataSource_InlineBytes{
-						InlineBytes: certChain,
-					},
-				},
-				PrivateKey: &corev3.DataSource{
-					Specifier: &corev3.DataSource_InlineBytes{
-						InlineBytes: privateKey,
-					},
+	if caCert, ok := k8sSecret.Data[caCertKey]; ok && len(caCert) > 0 {
+		secrets = append(secrets, &tlsv3.Secret{
+			Name: ValidationContextSecretName(secretKey.Namespace, secretKey.Name),
+			Type: &tlsv3.Secret_ValidationContext{
+				ValidationContext: &tlsv3.CertificateValidationContext{
+					TrustedCa: inlineBytes(caCert),
 				},
 			},
+		})
+	}
+
+	return secrets, nil
+}
+
+// newTlsCertificate builds an Envoy TlsCertificate. The private key is inlined
+// unless it is delegated to a private key provider.
+func newTlsCertificate(privateKeyProvider *v1beta1.PrivateKeyProvider, certChain, privateKey []byte) (*tlsv3.TlsCertificate, error) {
+	tlsCertificate := &tlsv3.TlsCertificate{
+		CertificateChain: inlineBytes(certChain),
+	}
+
+	if privateKeyProvider == nil {
+		if len(privateKey) == 0 {
+			return nil, fmt.Errorf("missing %s key", corev1.TLSPrivateKeyKey)
+		}
+		tlsCertificate.PrivateKey = inlineBytes(privateKey)
+		return tlsCertificate, nil
+	}
+
+	provider, err := newPrivateKeyProvider(privateKeyProvider, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	tlsCertificate.PrivateKeyProvider = provider
+	return tlsCertificate, nil
+}
+
+// inlineBytes wraps raw bytes into an inline Envoy DataSource.
+func inlineBytes(data []byte) *corev3.DataSource {
+	return &corev3.DataSource{
+		Specifier: &corev3.DataSource_InlineBytes{
+			InlineBytes: data,
 		},
-	}, nil
+	}
 }
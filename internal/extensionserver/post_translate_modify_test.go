@@ -0,0 +1,181 @@
+package extensionserver
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	pb "github.com/envoyproxy/gateway/proto/extension"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+func newTestServerWithObjects(objs ...client.Object) *Server {
+	return newTestServerWithOptions(nil, objs...)
+}
+
+func newTestServerWithOptions(opts []Option, objs ...client.Object) *Server {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(gwapiv1.AddToScheme(scheme))
+	utilruntime.Must(v1beta1.AddToScheme(scheme))
+
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(&v1beta1.CertificatePolicy{}).
+		Build()
+	return New(slog.New(slog.NewTextHandler(os.Stdout, nil)), k8sClient, opts...)
+}
+
+func TestFetchAndConvertSecret(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    *corev1.Secret
+		wantErr   bool
+		wantNames []string
+	}{
+		{
+			name:      "certificate without CA",
+			secret:    createTLSSecret("secret-1", nil),
+			wantNames: []string{"default/secret-1"},
+		},
+		{
+			name:      "certificate with CA",
+			secret:    createTLSSecret("secret-1", []byte("ca")),
+			wantNames: []string{"default/secret-1", "default/secret-1/ca.crt"},
+		},
+		{
+			name:      "empty CA is ignored",
+			secret:    createTLSSecret("secret-1", []byte{}),
+			wantNames: []string{"default/secret-1"},
+		},
+		{
+			name: "missing certificate",
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "secret-1", Namespace: "default"},
+				Data:       map[string][]byte{corev1.TLSPrivateKeyKey: []byte("key")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing private key",
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "secret-1", Namespace: "default"},
+				Data:       map[string][]byte{corev1.TLSCertKey: []byte("cert")},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "secret does not exist",
+			secret:  createTLSSecret("other", nil),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServerWithObjects(tt.secret)
+
+			secrets, err := server.fetchAndConvertSecret(context.Background(), createPolicy("secret-1"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fetchAndConvertSecret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if len(secrets) != len(tt.wantNames) {
+				t.Fatalf("got %d secrets, want %d", len(secrets), len(tt.wantNames))
+			}
+			for i, wantName := range tt.wantNames {
+				if secrets[i].Name != wantName {
+					t.Errorf("secret[%d].Name = %q, want %q", i, secrets[i].Name, wantName)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchAndConvertSecretValidationContext(t *testing.T) {
+	server := newTestServerWithObjects(createTLSSecret("secret-1", []byte("ca")))
+
+	secrets, err := server.fetchAndConvertSecret(context.Background(), createPolicy("secret-1"))
+	if err != nil {
+		t.Fatalf("fetchAndConvertSecret() error = %v", err)
+	}
+
+	tlsCert := secrets[0].GetTlsCertificate()
+	if string(tlsCert.GetCertificateChain().GetInlineBytes()) != "cert" {
+		t.Errorf("certificate chain = %q, want %q", tlsCert.GetCertificateChain().GetInlineBytes(), "cert")
+	}
+	if string(tlsCert.GetPrivateKey().GetInlineBytes()) != "key" {
+		t.Errorf("private key = %q, want %q", tlsCert.GetPrivateKey().GetInlineBytes(), "key")
+	}
+
+	validationContext := secrets[1].GetValidationContext()
+	if validationContext == nil {
+		t.Fatal("second secret is not a ValidationContext")
+	}
+	if string(validationContext.GetTrustedCa().GetInlineBytes()) != "ca" {
+		t.Errorf("trusted CA = %q, want %q", validationContext.GetTrustedCa().GetInlineBytes(), "ca")
+	}
+}
+
+func TestPostTranslateModify(t *testing.T) {
+	server := newTestServerWithObjects(
+		createTLSSecret("secret-1", []byte("ca")),
+		createTLSSecret("secret-2", nil),
+	)
+
+	req := &pb.PostTranslateModifyRequest{
+		Secrets: []*tlsv3.Secret{{Name: "existing"}},
+		PostTranslateContext: &pb.PostTranslateExtensionContext{
+			ExtensionResources: []*pb.ExtensionResource{
+				createExtensionResource(t, "secret-1"),
+				createExtensionResource(t, "secret-2"),
+				createExtensionResource(t, "missing"),
+			},
+		},
+	}
+
+	resp, err := server.PostTranslateModify(context.Background(), req)
+	if err != nil {
+		t.Fatalf("PostTranslateModify() error = %v", err)
+	}
+
+	wantNames := []string{"existing", "default/secret-1", "default/secret-1/ca.crt", "default/secret-2"}
+	if len(resp.Secrets) != len(wantNames) {
+		t.Fatalf("got %d secrets, want %d", len(resp.Secrets), len(wantNames))
+	}
+	for i, wantName := range wantNames {
+		if resp.Secrets[i].Name != wantName {
+			t.Errorf("secret[%d].Name = %q, want %q", i, resp.Secrets[i].Name, wantName)
+		}
+	}
+}
+
+func createTLSSecret(name string, caCert []byte) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       []byte("cert"),
+			corev1.TLSPrivateKeyKey: []byte("key"),
+		},
+	}
+	if caCert != nil {
+		secret.Data[caCertKey] = caCert
+	}
+	return secret
+}
@@ -0,0 +1,127 @@
+package extensionserver
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	xdstypev3 "github.com/cncf/xds/go/xds/type/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+const (
+	// cryptoMBProviderName is the name Envoy registers the CryptoMB private
+	// key method provider under.
+	cryptoMBProviderName = "cryptomb"
+
+	// cryptoMBConfigTypeURL is the type URL of the CryptoMB provider config.
+	cryptoMBConfigTypeURL = "type.googleapis.com/envoy.extensions.private_key_providers.cryptomb.v3alpha.CryptoMbPrivateKeyMethodConfig"
+
+	// defaultCryptoMBPollDelay is used when a CryptoMB provider does not
+	// configure a poll delay.
+	defaultCryptoMBPollDelay = 20 * time.Millisecond
+)
+
+// ValidatePrivateKeyProvider checks that a private key provider block is
+// consistent before it is turned into Envoy configuration.
+func ValidatePrivateKeyProvider(provider *v1beta1.PrivateKeyProvider) error {
+	if provider.ProviderName == "" {
+		return errors.New("privateKeyProvider.providerName must not be empty")
+	}
+
+	if provider.ProviderName == cryptoMBProviderName {
+		if provider.TypeURL != "" || provider.Config != nil {
+			return errors.New("privateKeyProvider.typeURL and privateKeyProvider.config must not be set for the cryptomb provider")
+		}
+		if provider.CryptoMB != nil && provider.CryptoMB.PollDelay != nil && provider.CryptoMB.PollDelay.Duration <= 0 {
+			return errors.New("privateKeyProvider.cryptomb.pollDelay must be positive")
+		}
+		return nil
+	}
+
+	if provider.CryptoMB != nil {
+		return fmt.Errorf("privateKeyProvider.cryptomb must not be set for provider %q", provider.ProviderName)
+	}
+	if provider.TypeURL == "" {
+		return fmt.Errorf("privateKeyProvider.typeURL must be set for provider %q", provider.ProviderName)
+	}
+	return nil
+}
+
+// newPrivateKeyProvider converts a policy private key provider block into the
+// Envoy PrivateKeyProvider configuration. The provider specific configuration
+// is passed as a TypedStruct so that providers which are not compiled into
+// this binary can still be configured.
+func newPrivateKeyProvider(provider *v1beta1.PrivateKeyProvider, privateKey []byte) (*tlsv3.PrivateKeyProvider, error) {
+	if err := ValidatePrivateKeyProvider(provider); err != nil {
+		return nil, err
+	}
+
+	typeURL, fields, err := privateKeyProviderConfig(provider, privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert private key provider config: %w", err)
+	}
+
+	typedConfig, err := anypb.New(&xdstypev3.TypedStruct{
+		TypeUrl: typeURL,
+		Value:   value,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key provider config: %w", err)
+	}
+
+	result := &tlsv3.PrivateKeyProvider{
+		ProviderName: provider.ProviderName,
+		ConfigType: &tlsv3.PrivateKeyProvider_TypedConfig{
+			TypedConfig: typedConfig,
+		},
+		Fallback: provider.Fallback,
+	}
+	if err := result.ValidateAll(); err != nil {
+		return nil, fmt.Errorf("invalid private key provider: %w", err)
+	}
+	return result, nil
+}
+
+// privateKeyProviderConfig returns the type URL and JSON fields of the
+// provider specific configuration.
+func privateKeyProviderConfig(provider *v1beta1.PrivateKeyProvider, privateKey []byte) (string, map[string]any, error) {
+	if provider.ProviderName == cryptoMBProviderName {
+		if len(privateKey) == 0 {
+			return "", nil, fmt.Errorf("missing %s key required by the cryptomb provider", corev1.TLSPrivateKeyKey)
+		}
+		pollDelay := defaultCryptoMBPollDelay
+		if provider.CryptoMB != nil && provider.CryptoMB.PollDelay != nil {
+			pollDelay = provider.CryptoMB.PollDelay.Duration
+		}
+		// TypedStruct values follow the protobuf JSON mapping, which encodes
+		// bytes fields as base64.
+		return cryptoMBConfigTypeURL, map[string]any{
+			"private_key": map[string]any{
+				"inline_bytes": base64.StdEncoding.EncodeToString(privateKey),
+			},
+			"poll_delay": strconv.FormatFloat(pollDelay.Seconds(), 'f', -1, 64) + "s",
+		}, nil
+	}
+
+	fields := map[string]any{}
+	if provider.Config != nil && len(provider.Config.Raw) > 0 {
+		if err := json.Unmarshal(provider.Config.Raw, &fields); err != nil {
+			return "", nil, fmt.Errorf("privateKeyProvider.config must be a JSON object: %w", err)
+		}
+	}
+	return provider.TypeURL, fields, nil
+}
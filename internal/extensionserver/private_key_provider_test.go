@@ -0,0 +1,233 @@
+package extensionserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	xdstypev3 "github.com/cncf/xds/go/xds/type/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+func TestValidatePrivateKeyProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider *v1beta1.PrivateKeyProvider
+		wantErr  bool
+	}{
+		{
+			name:     "cryptomb without options",
+			provider: &v1beta1.PrivateKeyProvider{ProviderName: "cryptomb"},
+		},
+		{
+			name: "cryptomb with poll delay",
+			provider: &v1beta1.PrivateKeyProvider{
+				ProviderName: "cryptomb",
+				CryptoMB:     &v1beta1.CryptoMBPrivateKeyProvider{PollDelay: &metav1.Duration{Duration: time.Millisecond}},
+			},
+		},
+		{
+			name:     "custom provider with type URL",
+			provider: &v1beta1.PrivateKeyProvider{ProviderName: "pkcs11", TypeURL: "type.googleapis.com/example.Config"},
+		},
+		{
+			name:     "empty provider name",
+			provider: &v1beta1.PrivateKeyProvider{},
+			wantErr:  true,
+		},
+		{
+			name: "cryptomb with non-positive poll delay",
+			provider: &v1beta1.PrivateKeyProvider{
+				ProviderName: "cryptomb",
+				CryptoMB:     &v1beta1.CryptoMBPrivateKeyProvider{PollDelay: &metav1.Duration{}},
+			},
+			wantErr: true,
+		},
+		{
+			name:     "cryptomb with type URL",
+			provider: &v1beta1.PrivateKeyProvider{ProviderName: "cryptomb", TypeURL: "type.googleapis.com/example.Config"},
+			wantErr:  true,
+		},
+		{
+			name:     "custom provider without type URL",
+			provider: &v1beta1.PrivateKeyProvider{ProviderName: "pkcs11"},
+			wantErr:  true,
+		},
+		{
+			name: "custom provider with cryptomb block",
+			provider: &v1beta1.PrivateKeyProvider{
+				ProviderName: "pkcs11",
+				TypeURL:      "type.googleapis.com/example.Config",
+				CryptoMB:     &v1beta1.CryptoMBPrivateKeyProvider{},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePrivateKeyProvider(tt.provider)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePrivateKeyProvider() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewPrivateKeyProviderCryptoMB(t *testing.T) {
+	provider, err := newPrivateKeyProvider(&v1beta1.PrivateKeyProvider{
+		ProviderName: "cryptomb",
+		Fallback:     true,
+	}, []byte("key"))
+	if err != nil {
+		t.Fatalf("newPrivateKeyProvider() error = %v", err)
+	}
+
+	if provider.GetProviderName() != "cryptomb" {
+		t.Errorf("ProviderName = %q, want %q", provider.GetProviderName(), "cryptomb")
+	}
+	if !provider.GetFallback() {
+		t.Error("Fallback = false, want true")
+	}
+
+	typedStruct := unmarshalTypedStruct(t, provider)
+	if typedStruct.GetTypeUrl() != cryptoMBConfigTypeURL {
+		t.Errorf("TypeUrl = %q, want %q", typedStruct.GetTypeUrl(), cryptoMBConfigTypeURL)
+	}
+	fields := typedStruct.GetValue().GetFields()
+	if got := fields["poll_delay"].GetStringValue(); got != "0.02s" {
+		t.Errorf("poll_delay = %q, want %q", got, "0.02s")
+	}
+	if got := fields["private_key"].GetStructValue().GetFields()["inline_bytes"].GetStringValue(); got != "a2V5" {
+		t.Errorf("private_key.inline_bytes = %q, want %q", got, "a2V5")
+	}
+}
+
+func TestNewPrivateKeyProviderCryptoMBSubMillisecondPollDelay(t *testing.T) {
+	provider, err := newPrivateKeyProvider(&v1beta1.PrivateKeyProvider{
+		ProviderName: "cryptomb",
+		CryptoMB:     &v1beta1.CryptoMBPrivateKeyProvider{PollDelay: &metav1.Duration{Duration: 50 * time.Microsecond}},
+	}, []byte("key"))
+	if err != nil {
+		t.Fatalf("newPrivateKeyProvider() error = %v", err)
+	}
+
+	// The protobuf JSON mapping of Duration rejects exponent notation.
+	fields := unmarshalTypedStruct(t, provider).GetValue().GetFields()
+	if got := fields["poll_delay"].GetStringValue(); got != "0.00005s" {
+		t.Errorf("poll_delay = %q, want %q", got, "0.00005s")
+	}
+}
+
+func TestNewPrivateKeyProviderCryptoMBWithoutKey(t *testing.T) {
+	if _, err := newPrivateKeyProvider(&v1beta1.PrivateKeyProvider{ProviderName: "cryptomb"}, nil); err == nil {
+		t.Error("newPrivateKeyProvider() expected an error for a missing private key")
+	}
+}
+
+func TestNewPrivateKeyProviderCustom(t *testing.T) {
+	provider, err := newPrivateKeyProvider(&v1beta1.PrivateKeyProvider{
+		ProviderName: "pkcs11",
+		TypeURL:      "type.googleapis.com/example.Config",
+		Config:       &runtime.RawExtension{Raw: []byte(`{"slot":1,"label":"gateway"}`)},
+	}, nil)
+	if err != nil {
+		t.Fatalf("newPrivateKeyProvider() error = %v", err)
+	}
+
+	typedStruct := unmarshalTypedStruct(t, provider)
+	if typedStruct.GetTypeUrl() != "type.googleapis.com/example.Config" {
+		t.Errorf("TypeUrl = %q, want %q", typedStruct.GetTypeUrl(), "type.googleapis.com/example.Config")
+	}
+	fields := typedStruct.GetValue().GetFields()
+	if got := fields["label"].GetStringValue(); got != "gateway" {
+		t.Errorf("label = %q, want %q", got, "gateway")
+	}
+	if got := fields["slot"].GetNumberValue(); got != 1 {
+		t.Errorf("slot = %v, want 1", got)
+	}
+}
+
+func TestNewPrivateKeyProviderInvalidConfig(t *testing.T) {
+	_, err := newPrivateKeyProvider(&v1beta1.PrivateKeyProvider{
+		ProviderName: "pkcs11",
+		TypeURL:      "type.googleapis.com/example.Config",
+		Config:       &runtime.RawExtension{Raw: []byte(`["not", "an", "object"]`)},
+	}, nil)
+	if err == nil {
+		t.Error("newPrivateKeyProvider() expected an error for a non-object config")
+	}
+}
+
+func TestFetchAndConvertSecretWithPrivateKeyProvider(t *testing.T) {
+	keylessSecret := createTLSSecret("secret-1", nil)
+	delete(keylessSecret.Data, corev1.TLSPrivateKeyKey)
+
+	tests := []struct {
+		name     string
+		secret   *corev1.Secret
+		provider *v1beta1.PrivateKeyProvider
+		wantErr  bool
+	}{
+		{
+			name:     "cryptomb uses the key from the secret",
+			secret:   createTLSSecret("secret-1", nil),
+			provider: &v1beta1.PrivateKeyProvider{ProviderName: "cryptomb"},
+		},
+		{
+			name:     "cryptomb requires a private key",
+			secret:   keylessSecret,
+			provider: &v1beta1.PrivateKeyProvider{ProviderName: "cryptomb"},
+			wantErr:  true,
+		},
+		{
+			name:     "custom provider does not need a private key",
+			secret:   keylessSecret,
+			provider: &v1beta1.PrivateKeyProvider{ProviderName: "pkcs11", TypeURL: "type.googleapis.com/example.Config"},
+		},
+		{
+			name:     "invalid provider",
+			secret:   createTLSSecret("secret-1", nil),
+			provider: &v1beta1.PrivateKeyProvider{ProviderName: "pkcs11"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServerWithObjects(tt.secret)
+			policy := createPolicy("secret-1")
+			policy.Spec.PrivateKeyProvider = tt.provider
+
+			secrets, err := server.fetchAndConvertSecret(context.Background(), policy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fetchAndConvertSecret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			tlsCert := secrets[0].GetTlsCertificate()
+			if tlsCert.GetPrivateKey() != nil {
+				t.Error("PrivateKey is set, want it to be replaced by the private key provider")
+			}
+			if tlsCert.GetPrivateKeyProvider().GetProviderName() != tt.provider.ProviderName {
+				t.Errorf("PrivateKeyProvider.ProviderName = %q, want %q", tlsCert.GetPrivateKeyProvider().GetProviderName(), tt.provider.ProviderName)
+			}
+		})
+	}
+}
+
+func unmarshalTypedStruct(t *testing.T, provider *tlsv3.PrivateKeyProvider) *xdstypev3.TypedStruct {
+	t.Helper()
+	typedStruct := &xdstypev3.TypedStruct{}
+	if err := provider.GetTypedConfig().UnmarshalTo(typedStruct); err != nil {
+		t.Fatalf("failed to unmarshal typed config: %v", err)
+	}
+	return typedStruct
+}
@@ -1,10 +1,37 @@
 package extensionserver
 
 import (
+	"fmt"
+
 	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+
+	"github.com/giantswarm/envoy-extension-server-app/internal/ticketkeys"
 )
 
+// caCertKey is the key under which cert-manager and most issuers store the
+// issuing CA certificate in a TLS Secret.
+const caCertKey = "ca.crt"
+
+// SecretName returns the Envoy secret name used for the TLS certificate stored
+// in the given Kubernetes Secret. The namespace/name format keeps it unique.
+func SecretName(namespace, name string) string {
+	return fmt.Sprintf("%s/%s", namespace, name)
+}
+
+// ValidationContextSecretName returns the Envoy secret name used for the CA
+// certificate stored in the given Kubernetes Secret. Listener and cluster TLS
+// contexts can reference it to validate client or upstream certificates.
+func ValidationContextSecretName(namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", namespace, name, caCertKey)
+}
+
+// SessionTicketKeysSecretName returns the Envoy secret name used for the
+// session ticket keys stored in the given Kubernetes Secret.
+func SessionTicketKeysSecretName(namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", namespace, name, ticketkeys.DataKey)
+}
+
 // NewSdsSecretConfig creates a new SDS secret configuration with the given name.
 func NewSdsSecretConfig(name string) *tlsv3.SdsSecretConfig {
 	return &tlsv3.SdsSecretConfig{
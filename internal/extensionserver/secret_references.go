@@ -0,0 +1,104 @@
+package extensionserver
+
+import (
+	"fmt"
+	"slices"
+
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+// policySecretNames returns the names of the Envoy secrets the listener hook
+// may reference for the policies, with the policy each belongs to: those of
+// their certificates, fallback certificates and session ticket keys. A
+// secret shared by several policies belongs to the first.
+func (s *Server) policySecretNames(policies []v1beta1.CertificatePolicy) map[string]*v1beta1.CertificatePolicy {
+	names := map[string]*v1beta1.CertificatePolicy{}
+	add := func(name string, policy *v1beta1.CertificatePolicy) {
+		if _, ok := names[name]; !ok {
+			names[name] = policy
+		}
+	}
+	for i := range policies {
+		policy := &policies[i]
+		add(SecretName(policy.Namespace, policy.Spec.SecretRef.Name), policy)
+		for _, ref := range policy.Spec.AdditionalSecretRefs {
+			add(SecretName(policy.Namespace, ref.Name), policy)
+		}
+		for _, fallback := range s.fallbackSecrets(*policy) {
+			add(SecretName(fallback.Namespace, fallback.Name), policy)
+		}
+		if ref := policy.Spec.SessionTicketKeysSecretRef; ref != nil {
+			add(SessionTicketKeysSecretName(policy.Namespace, ref.Name), policy)
+		}
+	}
+	return names
+}
+
+// removeUnservedReferences removes the references to secrets of the policies
+// that are missing from secrets from the TLS filter chains of the listeners,
+// as Envoy would wait for them forever. The listener hook references the
+// secrets of policies before the translation serves them, and the
+// translation may leave some out, e.g. when a Secret cannot be read. The
+// listeners are modified in place.
+func (s *Server) removeUnservedReferences(listeners []*listenerv3.Listener, secrets []*tlsv3.Secret, policies []v1beta1.CertificatePolicy) error {
+	served := make(map[string]struct{}, len(secrets))
+	for _, secret := range secrets {
+		served[secret.GetName()] = struct{}{}
+	}
+	policySecrets := s.policySecretNames(policies)
+	unserved := func(config *tlsv3.SdsSecretConfig) bool {
+		_, isPolicySecret := policySecrets[config.GetName()]
+		_, ok := served[config.GetName()]
+		return isPolicySecret && !ok
+	}
+
+	return mutateTLSContexts(listeners, func(_ *listenerv3.FilterChain, tlsContext *tlsv3.DownstreamTlsContext) {
+		if commonTlsContext := tlsContext.GetCommonTlsContext(); commonTlsContext != nil {
+			commonTlsContext.TlsCertificateSdsSecretConfigs = slices.DeleteFunc(commonTlsContext.TlsCertificateSdsSecretConfigs, unserved)
+		}
+		if config := tlsContext.GetSessionTicketKeysSdsSecretConfig(); config != nil && unserved(config) {
+			tlsContext.SessionTicketKeysType = nil
+		}
+	})
+}
+
+// mutateTLSContexts calls mutate with the TLS context of every TLS and QUIC
+// filter chain of the listeners, and writes back the ones it changed.
+func mutateTLSContexts(listeners []*listenerv3.Listener, mutate func(*listenerv3.FilterChain, *tlsv3.DownstreamTlsContext)) error {
+	for _, listener := range listeners {
+		for _, filterChain := range listener.GetFilterChains() {
+			tlsContext, err := filterChainTLSContext(filterChain)
+			if err != nil {
+				return invalidRequest(fmt.Errorf("failed to decode TLS context of filter chain %q of listener %q: %w",
+					filterChain.GetName(), listener.GetName(), err))
+			}
+			if tlsContext == nil {
+				continue
+			}
+			original := proto.Clone(tlsContext)
+			mutate(filterChain, tlsContext)
+			if proto.Equal(original, tlsContext) {
+				continue
+			}
+			if err := updateTransportSocket(filterChain.GetTransportSocket(), tlsContext); err != nil {
+				return fmt.Errorf("failed to encode TLS context of filter chain %q of listener %q: %w",
+					filterChain.GetName(), listener.GetName(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// cloneListeners returns deep copies of the listeners.
+func cloneListeners(listeners []*listenerv3.Listener) []*listenerv3.Listener {
+	clones := make([]*listenerv3.Listener, 0, len(listeners))
+	for _, listener := range listeners {
+		clone, _ := proto.Clone(listener).(*listenerv3.Listener)
+		clones = append(clones, clone)
+	}
+	return clones
+}
@@ -0,0 +1,59 @@
+package extensionserver
+
+import (
+	"slices"
+	"testing"
+
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+func TestRemoveUnservedReferences(t *testing.T) {
+	policy := createPolicy("secret-1")
+	policy.Spec.AdditionalSecretRefs = []v1beta1.SecretReference{{Name: "secret-2"}}
+	policy.Spec.SessionTicketKeysSecretRef = &v1beta1.SecretReference{Name: "ticket-keys"}
+
+	tests := []struct {
+		name           string
+		secrets        []string
+		wantReferences []string
+	}{
+		{
+			name:           "served secrets are kept",
+			secrets:        []string{"default/gateway-cert", "default/secret-1", "default/secret-2", "default/ticket-keys/ticket.keys"},
+			wantReferences: []string{"default/gateway-cert", "default/secret-1", "default/secret-2", "default/ticket-keys/ticket.keys"},
+		},
+		{
+			name:           "unserved policy secrets are removed",
+			secrets:        []string{"default/secret-2"},
+			wantReferences: []string{"default/gateway-cert", "default/secret-2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener := newHTTPSListenerServing(t, "default/gateway-1/https", "default/gateway-cert", "default/secret-1", "default/secret-2")
+			tlsContext, _ := extractDownstreamTlsContext(listener.FilterChains[0].TransportSocket)
+			tlsContext.SessionTicketKeysType = &tlsv3.DownstreamTlsContext_SessionTicketKeysSdsSecretConfig{
+				SessionTicketKeysSdsSecretConfig: NewSdsSecretConfig("default/ticket-keys/ticket.keys"),
+			}
+			if err := updateTransportSocket(listener.FilterChains[0].TransportSocket, tlsContext); err != nil {
+				t.Fatalf("failed to update transport socket: %v", err)
+			}
+
+			var secrets []*tlsv3.Secret
+			for _, name := range tt.secrets {
+				secrets = append(secrets, &tlsv3.Secret{Name: name})
+			}
+			err := newTestServer().removeUnservedReferences([]*listenerv3.Listener{listener}, secrets, []v1beta1.CertificatePolicy{policy})
+			if err != nil {
+				t.Fatalf("removeUnservedReferences() error = %v", err)
+			}
+			if got := listenerSecretReferences(listener); !slices.Equal(got, tt.wantReferences) {
+				t.Errorf("SDS references = %v, want %v", got, tt.wantReferences)
+			}
+		})
+	}
+}
@@ -1,22 +1,157 @@
 package extensionserver
 
 import (
+	"context"
 	"log/slog"
 
 	pb "github.com/envoyproxy/gateway/proto/extension"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/envoy-extension-server-app/internal/leader"
+	"github.com/giantswarm/envoy-extension-server-app/internal/logging"
 )
 
 type Server struct {
 	pb.UnimplementedEnvoyGatewayExtensionServer
 
-	log    *slog.Logger
-	client client.Client
+	log      *slog.Logger
+	client   client.Client
+	recorder record.EventRecorder
+	tracer   trace.Tracer
+	leader   leader.Gate
+
+	fallbackSecret types.NamespacedName
+	strict         bool
+	validationMode ValidationMode
+
+	fetchConcurrency int
+	partialResults   bool
+
+	enabledHooks []string
+	policyKinds  []string
+	mutators     []Mutator
+}
+
+// Option configures optional behavior of the Server.
+type Option func(*Server)
+
+// WithFallbackSecret sets the Secret served in place of a policy's Secret
+// when that one is missing or invalid and the policy has no fallback itself.
+func WithFallbackSecret(secret types.NamespacedName) Option {
+	return func(s *Server) {
+		s.fallbackSecret = secret
+	}
+}
+
+// WithStrictMode makes the Server omit the SDS reference of a policy whose
+// Secret is missing or invalid instead of serving a fallback certificate.
+func WithStrictMode(strict bool) Option {
+	return func(s *Server) {
+		s.strict = strict
+	}
+}
+
+// WithEventRecorder makes the Server report policy processing outcomes as
+// Kubernetes Events. The recorder is expected to deduplicate repeated events,
+// as every translation reports the same outcomes again.
+func WithEventRecorder(recorder record.EventRecorder) Option {
+	return func(s *Server) {
+		s.recorder = recorder
+	}
+}
+
+// WithLeader gates the status updates of the hooks, so that only one replica
+// writes them. Defaults to leader.Always.
+func WithLeader(gate leader.Gate) Option {
+	return func(s *Server) {
+		s.leader = gate
+	}
+}
+
+// WithValidationMode sets what the hooks return when the xDS they produced
+// fails validation. Defaults to ValidationFailOpen.
+func WithValidationMode(mode ValidationMode) Option {
+	return func(s *Server) {
+		s.validationMode = mode
+	}
+}
+
+// WithFetchConcurrency sets the number of Secrets a hook fetches
+// concurrently. Defaults to DefaultFetchConcurrency.
+func WithFetchConcurrency(concurrency int) Option {
+	return func(s *Server) {
+		if concurrency > 0 {
+			s.fetchConcurrency = concurrency
+		}
+	}
+}
+
+// WithPartialResults makes the hooks leave out the policies whose Secrets
+// were not read when their time budget runs out, instead of failing.
+func WithPartialResults(partial bool) Option {
+	return func(s *Server) {
+		s.partialResults = partial
+	}
+}
+
+// WithEnabledHooks restricts the hooks that modify the resources they
+// receive. The other hooks return them unchanged. All hooks are enabled by
+// default.
+func WithEnabledHooks(hooks []string) Option {
+	return func(s *Server) {
+		s.enabledHooks = hooks
+	}
+}
+
+// WithPolicyKinds restricts the kinds of extension resources the hooks
+// process. Extension resources of other kinds are ignored. All kinds in
+// PolicyKinds are processed by default.
+func WithPolicyKinds(kinds []string) Option {
+	return func(s *Server) {
+		s.policyKinds = kinds
+	}
 }
 
-func New(logger *slog.Logger, client client.Client) *Server {
-	return &Server{
-		log:    logger,
-		client: client,
+// WithMutators sets the Mutators the hooks run, in order. The Mutators of
+// DefaultRegistry run by default.
+func WithMutators(mutators ...Mutator) Option {
+	return func(s *Server) {
+		s.mutators = mutators
 	}
 }
+
+// WithTracerProvider makes the Server trace its hooks, policy extraction,
+// Kubernetes lookups and the encoding of TLS contexts. Tracing is disabled by
+// default.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(s *Server) {
+		s.tracer = provider.Tracer(tracerName)
+	}
+}
+
+func New(logger *slog.Logger, client client.Client, opts ...Option) *Server {
+	s := &Server{
+		log:            logger,
+		client:         client,
+		tracer:         noop.NewTracerProvider().Tracer(tracerName),
+		leader:         leader.Always,
+		validationMode: ValidationFailOpen,
+		mutators:       DefaultRegistry().All(),
+
+		fetchConcurrency: DefaultFetchConcurrency,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// logger returns the request-scoped logger carried by ctx, falling back to
+// the Server's logger.
+func (s *Server) logger(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.log)
+}
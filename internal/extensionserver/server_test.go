@@ -1 +1,303 @@
 package extensionserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"net"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	pb "github.com/envoyproxy/gateway/proto/extension"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// fakeEnvoyGateway drives an extension server over an in-process gRPC
+// connection the way Envoy Gateway does during a translation.
+type fakeEnvoyGateway struct {
+	client pb.EnvoyGatewayExtensionClient
+}
+
+// startFakeEnvoyGateway serves the extension server on a bufconn listener and
+// returns a fake Envoy Gateway connected to it.
+func startFakeEnvoyGateway(t *testing.T, server *Server, opts ...grpc.ServerOption) *fakeEnvoyGateway {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer(opts...)
+	pb.RegisterEnvoyGatewayExtensionServer(grpcServer, server)
+	go func() {
+		_ = grpcServer.Serve(lis)
+	}()
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to connect to the extension server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &fakeEnvoyGateway{client: pb.NewEnvoyGatewayExtensionClient(conn)}
+}
+
+// xdsSnapshot is the xDS configuration Envoy Gateway would send to Envoy
+// after a translation.
+type xdsSnapshot struct {
+	listeners []*listenerv3.Listener
+	secrets   map[string]*tlsv3.Secret
+}
+
+// translate runs the listener hook for each listener and then the translate
+// hook, as Envoy Gateway does, and returns the resulting snapshot.
+func (g *fakeEnvoyGateway) translate(ctx context.Context, listeners []*listenerv3.Listener, secrets []*tlsv3.Secret, policies []*pb.ExtensionResource) (*xdsSnapshot, error) {
+	snapshot := &xdsSnapshot{secrets: map[string]*tlsv3.Secret{}}
+	for _, listener := range listeners {
+		resp, err := g.client.PostHTTPListenerModify(ctx, &pb.PostHTTPListenerModifyRequest{
+			Listener:            listener,
+			PostListenerContext: &pb.PostHTTPListenerExtensionContext{ExtensionResources: policies},
+		})
+		if err != nil {
+			return nil, err
+		}
+		snapshot.listeners = append(snapshot.listeners, resp.GetListener())
+	}
+
+	resp, err := g.client.PostTranslateModify(ctx, &pb.PostTranslateModifyRequest{
+		Listeners:            snapshot.listeners,
+		Secrets:              secrets,
+		PostTranslateContext: &pb.PostTranslateExtensionContext{ExtensionResources: policies},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.GetListeners()) > 0 {
+		snapshot.listeners = resp.GetListeners()
+	}
+	for _, secret := range resp.GetSecrets() {
+		snapshot.secrets[secret.GetName()] = secret
+	}
+	return snapshot, nil
+}
+
+// sdsReferences returns the names of the TLS certificate secrets referenced by
+// the listeners of the snapshot.
+func (s *xdsSnapshot) sdsReferences(t *testing.T) []string {
+	t.Helper()
+	var names []string
+	for _, listener := range s.listeners {
+		for _, filterChain := range listener.GetFilterChains() {
+			if filterChain.GetTransportSocket() == nil {
+				continue
+			}
+			tlsContext, err := extractDownstreamTlsContext(filterChain.GetTransportSocket())
+			if err != nil {
+				t.Fatalf("failed to decode TLS context of listener %s: %v", listener.GetName(), err)
+			}
+			for _, config := range tlsContext.GetCommonTlsContext().GetTlsCertificateSdsSecretConfigs() {
+				names = append(names, config.GetName())
+			}
+		}
+	}
+	return names
+}
+
+// assertConsistent fails the test if a listener references a secret missing
+// from the snapshot, which would leave Envoy waiting for it forever.
+func (s *xdsSnapshot) assertConsistent(t *testing.T) {
+	t.Helper()
+	for _, name := range s.sdsReferences(t) {
+		if _, ok := s.secrets[name]; !ok {
+			t.Errorf("listeners reference secret %q which is not part of the snapshot", name)
+		}
+	}
+}
+
+func newHTTPSListener(t *testing.T, name string) *listenerv3.Listener {
+	t.Helper()
+	return &listenerv3.Listener{
+		Name: name,
+		FilterChains: []*listenerv3.FilterChain{{
+			Name:            name,
+			TransportSocket: createTransportSocketWithTLS(t),
+		}},
+	}
+}
+
+// newHTTPSListenerServing returns an HTTPS listener whose filter chain
+// references the given certificates, as Envoy Gateway configures them.
+func newHTTPSListenerServing(t *testing.T, name string, secretNames ...string) *listenerv3.Listener {
+	t.Helper()
+	listener := newHTTPSListener(t, name)
+	tlsContext := &tlsv3.DownstreamTlsContext{CommonTlsContext: &tlsv3.CommonTlsContext{}}
+	appendSdsSecretConfigs(tlsContext, secretNames)
+	if err := updateTransportSocket(listener.FilterChains[0].TransportSocket, tlsContext); err != nil {
+		t.Fatalf("failed to update transport socket: %v", err)
+	}
+	return listener
+}
+
+func TestEndToEndTranslation(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           []Option
+		wantReferences []string
+		wantSecrets    []string
+		wantConsistent bool
+	}{
+		{
+			name:           "missing secret is not referenced without fallback",
+			wantReferences: []string{"default/secret-1"},
+			wantSecrets:    []string{"default/gateway-cert", "default/secret-1", "default/secret-1/ca.crt"},
+			wantConsistent: true,
+		},
+		{
+			name:           "missing secret is replaced by the fallback",
+			opts:           []Option{WithFallbackSecret(types.NamespacedName{Namespace: "default", Name: "fallback"})},
+			wantReferences: []string{"default/secret-1", "default/fallback"},
+			wantSecrets:    []string{"default/fallback", "default/gateway-cert", "default/secret-1", "default/secret-1/ca.crt"},
+			wantConsistent: true,
+		},
+		{
+			name:           "missing secret is omitted in strict mode",
+			opts:           []Option{WithStrictMode(true)},
+			wantReferences: []string{"default/secret-1"},
+			wantSecrets:    []string{"default/gateway-cert", "default/secret-1", "default/secret-1/ca.crt"},
+			wantConsistent: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServerWithOptions(tt.opts, createTLSSecret("secret-1", []byte("ca")), createTLSSecret("fallback", nil))
+			gateway := startFakeEnvoyGateway(t, server)
+
+			snapshot, err := gateway.translate(context.Background(),
+				[]*listenerv3.Listener{newHTTPSListener(t, "default/gateway-1/https")},
+				[]*tlsv3.Secret{{Name: "default/gateway-cert"}},
+				[]*pb.ExtensionResource{createExtensionResource(t, "secret-1"), createExtensionResource(t, "secret-2")},
+			)
+			if err != nil {
+				t.Fatalf("translation failed: %v", err)
+			}
+
+			if got := snapshot.sdsReferences(t); !slices.Equal(got, tt.wantReferences) {
+				t.Errorf("SDS references = %v, want %v", got, tt.wantReferences)
+			}
+			if gotSecrets := slices.Sorted(maps.Keys(snapshot.secrets)); !slices.Equal(gotSecrets, tt.wantSecrets) {
+				t.Errorf("secrets = %v, want %v", gotSecrets, tt.wantSecrets)
+			}
+			if tt.wantConsistent {
+				snapshot.assertConsistent(t)
+			}
+		})
+	}
+}
+
+func TestEndToEndUnimplementedHooks(t *testing.T) {
+	gateway := startFakeEnvoyGateway(t, newTestServerWithObjects())
+
+	_, err := gateway.client.PostRouteModify(context.Background(), &pb.PostRouteModifyRequest{})
+	if got := status.Code(err); got != codes.Unimplemented {
+		t.Errorf("PostRouteModify() code = %v, want %v", got, codes.Unimplemented)
+	}
+	_, err = gateway.client.PostClusterModify(context.Background(), &pb.PostClusterModifyRequest{})
+	if got := status.Code(err); got != codes.Unimplemented {
+		t.Errorf("PostClusterModify() code = %v, want %v", got, codes.Unimplemented)
+	}
+}
+
+func TestEndToEndUnavailable(t *testing.T) {
+	gateway := startFakeEnvoyGateway(t, newUnavailableServer())
+
+	_, err := gateway.translate(context.Background(),
+		[]*listenerv3.Listener{newHTTPSListener(t, "default/gateway-1/https")}, nil,
+		[]*pb.ExtensionResource{createExtensionResource(t, "secret-1")})
+	if got := status.Code(err); got != codes.Unavailable {
+		t.Errorf("translation code = %v, want %v (error %v)", got, codes.Unavailable, err)
+	}
+}
+
+// blockingClient blocks every read until the request context is done.
+type blockingClient struct {
+	client.Client
+
+	cancelled chan error
+}
+
+func (c *blockingClient) Get(ctx context.Context, _ client.ObjectKey, _ client.Object, _ ...client.GetOption) error {
+	<-ctx.Done()
+	select {
+	case c.cancelled <- ctx.Err():
+	default:
+	}
+	return ctx.Err()
+}
+
+func TestEndToEndDeadline(t *testing.T) {
+	k8sClient := &blockingClient{cancelled: make(chan error, 1)}
+	gateway := startFakeEnvoyGateway(t, New(slog.New(slog.NewTextHandler(os.Stdout, nil)), k8sClient))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := gateway.client.PostTranslateModify(ctx, &pb.PostTranslateModifyRequest{
+		PostTranslateContext: &pb.PostTranslateExtensionContext{
+			ExtensionResources: []*pb.ExtensionResource{createExtensionResource(t, "secret-1")},
+		},
+	})
+	if got := status.Code(err); got != codes.DeadlineExceeded {
+		t.Errorf("PostTranslateModify() code = %v, want %v", got, codes.DeadlineExceeded)
+	}
+
+	// The deadline is propagated to the Kubernetes API calls of the hook.
+	select {
+	case err := <-k8sClient.cancelled:
+		if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
+			t.Errorf("Kubernetes call ended with %v, want the context to be done", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the Kubernetes call was not cancelled with the request")
+	}
+}
+
+func TestEndToEndListenerWithoutTLS(t *testing.T) {
+	gateway := startFakeEnvoyGateway(t, newTestServerWithObjects(createTLSSecret("secret-1", nil)))
+
+	listener := &listenerv3.Listener{
+		Name: "default/gateway-1/http",
+		FilterChains: []*listenerv3.FilterChain{{
+			Filters: []*listenerv3.Filter{{Name: "envoy.filters.network.http_connection_manager"}},
+		}},
+		Address: &corev3.Address{Address: &corev3.Address_SocketAddress{SocketAddress: &corev3.SocketAddress{
+			Address:       "0.0.0.0",
+			PortSpecifier: &corev3.SocketAddress_PortValue{PortValue: 10080},
+		}}},
+	}
+	snapshot, err := gateway.translate(context.Background(), []*listenerv3.Listener{listener}, nil,
+		[]*pb.ExtensionResource{createExtensionResource(t, "secret-1")})
+	if err != nil {
+		t.Fatalf("translation failed: %v", err)
+	}
+	if got := snapshot.listeners[0].GetFilterChains()[0].GetTransportSocket(); got != nil {
+		t.Errorf("plain HTTP listener got a transport socket: %v", got)
+	}
+	if _, ok := snapshot.secrets["default/secret-1"]; !ok {
+		t.Error("the policy's secret is missing from the snapshot")
+	}
+}
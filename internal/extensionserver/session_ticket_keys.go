@@ -0,0 +1,71 @@
+package extensionserver
+
+import (
+	"context"
+	"fmt"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+	"github.com/giantswarm/envoy-extension-server-app/internal/ticketkeys"
+)
+
+// resolveSessionTicketKeys returns the Envoy secret holding the session
+// ticket keys of a policy, or nil if it has none. A Secret that is missing or
+// invalid is left out, so that Envoy falls back to per-proxy keys rather than
+// waiting for the secret. Transient errors are returned.
+func (s *Server) resolveSessionTicketKeys(ctx context.Context, policy v1beta1.CertificatePolicy) (*tlsv3.Secret, error) {
+	ref := policy.Spec.SessionTicketKeysSecretRef
+	if ref == nil {
+		return nil, nil
+	}
+
+	secret, err := s.fetchAndConvertSessionTicketKeys(ctx, types.NamespacedName{Namespace: policy.Namespace, Name: ref.Name})
+	if isTransient(err) {
+		return nil, err
+	}
+	if err != nil {
+		s.logger(ctx).Error("omitting session ticket keys of policy",
+			"policy", policy.Name,
+			"secretName", ref.Name,
+			"error", err,
+		)
+		s.recordSecretError(policy, ref.Name, err)
+		return nil, nil
+	}
+	return secret, nil
+}
+
+// fetchAndConvertSessionTicketKeys reads a Kubernetes Secret holding session
+// ticket keys and converts it into an Envoy secret.
+func (s *Server) fetchAndConvertSessionTicketKeys(ctx context.Context, secretKey types.NamespacedName) (_ *tlsv3.Secret, err error) {
+	ctx, span := s.startSpan(ctx, "fetchSecret",
+		attributeNamespace.String(secretKey.Namespace),
+		attributeSecretName.String(secretKey.Name),
+	)
+	defer func() { endSpan(span, err) }()
+
+	var k8sSecret corev1.Secret
+	if err := s.client.Get(ctx, secretKey, &k8sSecret); err != nil {
+		return nil, classifyAPIError(fmt.Errorf("failed to get secret %s/%s: %w", secretKey.Namespace, secretKey.Name, err))
+	}
+
+	keys, err := ticketkeys.Split(k8sSecret.Data[ticketkeys.DataKey])
+	if err != nil {
+		return nil, fmt.Errorf("secret %s/%s: %w", secretKey.Namespace, secretKey.Name, err)
+	}
+
+	dataSources := make([]*corev3.DataSource, 0, len(keys))
+	for _, key := range keys {
+		dataSources = append(dataSources, inlineBytes(key))
+	}
+	return &tlsv3.Secret{
+		Name: SessionTicketKeysSecretName(secretKey.Namespace, secretKey.Name),
+		Type: &tlsv3.Secret_SessionTicketKeys{
+			SessionTicketKeys: &tlsv3.TlsSessionTicketKeys{Keys: dataSources},
+		},
+	}, nil
+}
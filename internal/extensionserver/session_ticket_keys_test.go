@@ -0,0 +1,109 @@
+package extensionserver
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+
+	pb "github.com/envoyproxy/gateway/proto/extension"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+	"github.com/giantswarm/envoy-extension-server-app/internal/ticketkeys"
+)
+
+func createTicketKeysSecret(name string, data []byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Type:       corev1.SecretTypeOpaque,
+		Data:       map[string][]byte{ticketkeys.DataKey: data},
+	}
+}
+
+func TestSessionTicketKeys(t *testing.T) {
+	twoKeys := append(bytes.Repeat([]byte{1}, ticketkeys.KeyLength), bytes.Repeat([]byte{2}, ticketkeys.KeyLength)...)
+	ticketKeysName := SessionTicketKeysSecretName("default", "ticket-keys")
+
+	tests := []struct {
+		name       string
+		data       []byte
+		delivery   v1beta1.CertificateDeliveryMode
+		wantServed bool
+	}{
+		{name: "keys are served and referenced", data: twoKeys, wantServed: true},
+		{name: "keys are served in Filename mode", data: twoKeys, delivery: v1beta1.DeliveryFilename, wantServed: true},
+		{name: "invalid keys are left out", data: []byte("invalid")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := createTargetingPolicy("secret-1", gatewayTargetRef("gateway-1", ""))
+			policy.Spec.SessionTicketKeysSecretRef = &v1beta1.SecretReference{Name: "ticket-keys"}
+			if tt.delivery != "" {
+				policy.Spec.Delivery = &v1beta1.CertificateDelivery{Mode: tt.delivery, MountPath: "/etc/envoy/certs"}
+			}
+			server := newTestServerWithObjects(createTLSSecret("secret-1", nil), createTicketKeysSecret("ticket-keys", tt.data))
+
+			translateResp, err := server.PostTranslateModify(context.Background(), &pb.PostTranslateModifyRequest{
+				PostTranslateContext: &pb.PostTranslateExtensionContext{
+					ExtensionResources: []*pb.ExtensionResource{marshalExtensionResource(t, policy)},
+				},
+			})
+			if err != nil {
+				t.Fatalf("PostTranslateModify() error = %v", err)
+			}
+			var served *tlsv3.Secret
+			for _, secret := range translateResp.Secrets {
+				if secret.GetName() == ticketKeysName {
+					served = secret
+				}
+			}
+			if (served != nil) != tt.wantServed {
+				t.Fatalf("secrets = %v, want %s served: %v", secretNames(translateResp.Secrets), ticketKeysName, tt.wantServed)
+			}
+			if served != nil {
+				keys := served.GetSessionTicketKeys().GetKeys()
+				if len(keys) != 2 || !bytes.Equal(keys[0].GetInlineBytes(), twoKeys[:ticketkeys.KeyLength]) {
+					t.Errorf("session ticket keys = %v, want the two keys in order", keys)
+				}
+			}
+
+			listenerResp, err := server.PostHTTPListenerModify(context.Background(), &pb.PostHTTPListenerModifyRequest{
+				Listener: newHTTPSListener(t, "default/gateway-1/https"),
+				PostListenerContext: &pb.PostHTTPListenerExtensionContext{
+					ExtensionResources: []*pb.ExtensionResource{marshalExtensionResource(t, policy)},
+				},
+			})
+			if err != nil {
+				t.Fatalf("PostHTTPListenerModify() error = %v", err)
+			}
+			references := listenerSecretReferences(listenerResp.Listener)
+			if !slices.Contains(references, "default/secret-1") {
+				t.Errorf("SDS references = %v, want the certificate referenced", references)
+			}
+			if slices.Contains(references, ticketKeysName) != tt.wantServed {
+				t.Errorf("SDS references = %v, want %s referenced: %v", references, ticketKeysName, tt.wantServed)
+			}
+		})
+	}
+}
+
+func TestSessionTicketKeysMutatorPrefersLaterCertificates(t *testing.T) {
+	filterChain := &FilterChain{
+		TLSContext: &tlsv3.DownstreamTlsContext{},
+		Certificates: []ListenerCertificate{
+			{SecretName: "default/secret-1", SessionTicketKeys: "default/first/ticket.keys"},
+			{SecretName: "default/secret-2", SessionTicketKeys: "default/second/ticket.keys"},
+			{SecretName: "default/secret-3"},
+		},
+	}
+	if err := (sessionTicketKeysMutator{}).MutateFilterChain(context.Background(), filterChain); err != nil {
+		t.Fatalf("MutateFilterChain() error = %v", err)
+	}
+	if got := filterChain.TLSContext.GetSessionTicketKeysSdsSecretConfig().GetName(); got != "default/second/ticket.keys" {
+		t.Errorf("session ticket keys SDS reference = %q, want default/second/ticket.keys", got)
+	}
+}
@@ -0,0 +1,117 @@
+package extensionserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+const (
+	// DefaultHookTimeout is the default time budget of a hook. It is below
+	// the timeout Envoy Gateway applies to extension server calls, so that
+	// the hooks can still respond in time.
+	DefaultHookTimeout = 5 * time.Second
+
+	// DefaultFetchConcurrency is the default number of Secrets fetched
+	// concurrently by a hook.
+	DefaultFetchConcurrency = 8
+
+	// eventReasonTimedOut is used when a policy is left out of a translation
+	// because its Secret could not be read within the hook's time budget.
+	eventReasonTimedOut = "TimedOut"
+)
+
+// TimeoutInterceptor bounds the time each hook may take. timeouts holds the
+// budgets of individual hooks by hook name, e.g. "PostTranslateModify", all
+// other hooks get defaultTimeout. A budget of zero disables the bound. A
+// shorter deadline set by Envoy Gateway takes precedence.
+func TimeoutInterceptor(defaultTimeout time.Duration, timeouts map[string]time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		timeout, ok := timeouts[path.Base(info.FullMethod)]
+		if !ok {
+			timeout = defaultTimeout
+		}
+		if timeout <= 0 {
+			return handler(ctx, req)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// ParseHookTimeouts parses hook time budgets given as "<hook>=<duration>",
+// e.g. "PostTranslateModify=10s".
+func ParseHookTimeouts(values []string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration, len(values))
+	for _, value := range values {
+		hook, duration, ok := strings.Cut(value, "=")
+		if !ok || hook == "" {
+			return nil, fmt.Errorf("invalid hook timeout %q, must be <hook>=<duration>", value)
+		}
+		timeout, err := time.ParseDuration(duration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout of hook %s: %w", hook, err)
+		}
+		timeouts[hook] = timeout
+	}
+	return timeouts, nil
+}
+
+// policySecrets is the outcome of resolving the Secrets of a policy.
+type policySecrets struct {
+	secrets []*tlsv3.Secret
+	err     error
+}
+
+// resolveAll calls resolve for every policy with at most fetchConcurrency
+// calls in flight. The results are in the order of the policies. Once ctx is
+// done, the remaining policies are not resolved.
+func (s *Server) resolveAll(ctx context.Context, policies []v1beta1.CertificatePolicy, resolve func(context.Context, v1beta1.CertificatePolicy) ([]*tlsv3.Secret, error)) []policySecrets {
+	results := make([]policySecrets, len(policies))
+
+	var group errgroup.Group
+	group.SetLimit(s.fetchConcurrency)
+	for i, policy := range policies {
+		group.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				results[i] = policySecrets{err: transient(err)}
+				return nil
+			}
+			secrets, err := resolve(ctx, policy)
+			results[i] = policySecrets{secrets: secrets, err: err}
+			return nil
+		})
+	}
+	// The results carry the errors.
+	_ = group.Wait()
+
+	return results
+}
+
+// skipOnTimeout reports whether a policy is left out of the response because
+// the hook's time budget ran out while resolving it. This only happens with
+// partial results enabled, otherwise the hook fails.
+func (s *Server) skipOnTimeout(ctx context.Context, policy v1beta1.CertificatePolicy, err error) bool {
+	if !s.partialResults || ctx.Err() == nil || !errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	s.logger(ctx).Warn("leaving policy out of the response, the time budget of the hook ran out",
+		"policy", policy.Name,
+		"secretName", policy.Spec.SecretRef.Name,
+	)
+	s.recordPolicyEvent(policy, corev1.EventTypeWarning, eventReasonTimedOut,
+		"Secret %s was not read within the time budget of the hook and is left out of this translation", policy.Spec.SecretRef.Name)
+	return true
+}
@@ -0,0 +1,286 @@
+package extensionserver
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/envoyproxy/gateway/proto/extension"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+func TestTimeoutInterceptor(t *testing.T) {
+	interceptor := TimeoutInterceptor(time.Minute, map[string]time.Duration{
+		"PostTranslateModify":    time.Second,
+		"PostHTTPListenerModify": 0,
+	})
+
+	tests := []struct {
+		name      string
+		ctx       func() (context.Context, context.CancelFunc)
+		method    string
+		wantLimit time.Duration
+	}{
+		{
+			name:      "hook budget",
+			method:    pb.EnvoyGatewayExtension_PostTranslateModify_FullMethodName,
+			wantLimit: time.Second,
+		},
+		{
+			name:      "default budget",
+			method:    pb.EnvoyGatewayExtension_PostRouteModify_FullMethodName,
+			wantLimit: time.Minute,
+		},
+		{
+			name:   "disabled budget",
+			method: pb.EnvoyGatewayExtension_PostHTTPListenerModify_FullMethodName,
+		},
+		{
+			name: "shorter deadline of the caller",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 100*time.Millisecond)
+			},
+			method:    pb.EnvoyGatewayExtension_PostTranslateModify_FullMethodName,
+			wantLimit: 100 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.Background(), context.CancelFunc(func() {})
+			if tt.ctx != nil {
+				ctx, cancel = tt.ctx()
+			}
+			defer cancel()
+
+			handler := func(ctx context.Context, _ any) (any, error) {
+				deadline, ok := ctx.Deadline()
+				if tt.wantLimit == 0 {
+					if ok {
+						t.Errorf("handler got deadline %v, want none", deadline)
+					}
+					return nil, nil
+				}
+				if !ok {
+					t.Fatal("handler got no deadline")
+				}
+				if budget := time.Until(deadline); budget > tt.wantLimit || budget < tt.wantLimit/2 {
+					t.Errorf("handler got a budget of %v, want %v", budget, tt.wantLimit)
+				}
+				return nil, nil
+			}
+			if _, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler); err != nil {
+				t.Fatalf("interceptor error = %v", err)
+			}
+		})
+	}
+}
+
+func TestParseHookTimeouts(t *testing.T) {
+	timeouts, err := ParseHookTimeouts([]string{"PostTranslateModify=10s", "PostHTTPListenerModify=0"})
+	if err != nil {
+		t.Fatalf("ParseHookTimeouts() error = %v", err)
+	}
+	if timeouts["PostTranslateModify"] != 10*time.Second || timeouts["PostHTTPListenerModify"] != 0 || len(timeouts) != 2 {
+		t.Errorf("ParseHookTimeouts() = %v", timeouts)
+	}
+
+	for _, value := range []string{"PostTranslateModify", "=10s", "PostTranslateModify=soon"} {
+		if _, err := ParseHookTimeouts([]string{value}); err == nil {
+			t.Errorf("ParseHookTimeouts(%q) expected an error", value)
+		}
+	}
+}
+
+// newSlowServer returns a Server whose reads of Secrets named "slow-*" block
+// until the request context is done.
+func newSlowServer(opts []Option, objs ...client.Object) *Server {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1beta1.AddToScheme(scheme))
+
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				if strings.HasPrefix(key.Name, "slow-") {
+					<-ctx.Done()
+					return ctx.Err()
+				}
+				return c.Get(ctx, key, obj, opts...)
+			},
+		}).
+		Build()
+	return New(slog.New(slog.NewTextHandler(os.Stdout, nil)), k8sClient, opts...)
+}
+
+func TestPartialResults(t *testing.T) {
+	policies := []*pb.ExtensionResource{
+		createExtensionResource(t, "secret-1"),
+		createExtensionResource(t, "slow-secret"),
+	}
+
+	tests := []struct {
+		name     string
+		partial  bool
+		wantCode codes.Code
+	}{
+		{
+			name:     "the hook fails when the budget runs out",
+			wantCode: codes.DeadlineExceeded,
+		},
+		{
+			name:     "slow policies are left out with partial results",
+			partial:  true,
+			wantCode: codes.OK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			server := newSlowServer([]Option{WithPartialResults(tt.partial), WithEventRecorder(recorder)}, createTLSSecret("secret-1", nil))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			// The listener hook referenced the secrets of both policies.
+			resp, err := server.PostTranslateModify(ctx, &pb.PostTranslateModifyRequest{
+				Listeners: []*listenerv3.Listener{
+					newHTTPSListenerServing(t, "default/gateway-1/https", "default/secret-1", "default/slow-secret"),
+				},
+				PostTranslateContext: &pb.PostTranslateExtensionContext{ExtensionResources: policies},
+			})
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("PostTranslateModify() code = %v, want %v (error %v)", got, tt.wantCode, err)
+			}
+			if tt.wantCode != codes.OK {
+				return
+			}
+			if len(resp.Secrets) != 1 || resp.Secrets[0].GetName() != "default/secret-1" {
+				t.Errorf("secrets = %v, want only default/secret-1", resp.Secrets)
+			}
+			if got := listenerSecretReferences(resp.Listeners[0]); !slices.Equal(got, []string{"default/secret-1"}) {
+				t.Errorf("SDS references = %v, want only default/secret-1", got)
+			}
+			var timedOut bool
+			for _, event := range drainEvents(recorder) {
+				timedOut = timedOut || strings.HasPrefix(event, "Warning TimedOut Secret slow-secret")
+			}
+			if !timedOut {
+				t.Error("no TimedOut event was recorded for the slow policy")
+			}
+		})
+	}
+}
+
+func TestPartialResultsForListeners(t *testing.T) {
+	server := newSlowServer([]Option{WithPartialResults(true), WithStrictMode(true)}, createTLSSecret("secret-1", nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	resp, err := server.PostHTTPListenerModify(ctx, &pb.PostHTTPListenerModifyRequest{
+		Listener: newHTTPSListener(t, "default/gateway-1/https"),
+		PostListenerContext: &pb.PostHTTPListenerExtensionContext{ExtensionResources: []*pb.ExtensionResource{
+			createExtensionResource(t, "slow-secret"),
+			createExtensionResource(t, "secret-1"),
+		}},
+	})
+	if err != nil {
+		t.Fatalf("PostHTTPListenerModify() error = %v", err)
+	}
+	if got := listenerSecretReferences(resp.Listener); len(got) != 1 || got[0] != "default/secret-1" {
+		t.Errorf("SDS references = %v, want only default/secret-1", got)
+	}
+}
+
+func TestResolveAllBoundsConcurrency(t *testing.T) {
+	const concurrency = 3
+	server := newTestServerWithOptions([]Option{WithFetchConcurrency(concurrency)})
+
+	var (
+		mu          sync.Mutex
+		inFlight    int
+		maxInFlight int
+		fullOnce    sync.Once
+	)
+	full := make(chan struct{})
+	release := make(chan struct{})
+	resolve := func(_ context.Context, policy v1beta1.CertificatePolicy) ([]*tlsv3.Secret, error) {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		if inFlight == concurrency {
+			fullOnce.Do(func() { close(full) })
+		}
+		mu.Unlock()
+
+		<-release
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return []*tlsv3.Secret{{Name: policy.Spec.SecretRef.Name}}, nil
+	}
+
+	var policies []v1beta1.CertificatePolicy
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		policies = append(policies, createPolicy(name))
+	}
+
+	done := make(chan []policySecrets)
+	go func() {
+		done <- server.resolveAll(context.Background(), policies, resolve)
+	}()
+
+	<-full
+	close(release)
+	results := <-done
+
+	if maxInFlight != concurrency {
+		t.Errorf("max concurrent fetches = %d, want %d", maxInFlight, concurrency)
+	}
+	for i, result := range results {
+		if result.err != nil || result.secrets[0].Name != policies[i].Spec.SecretRef.Name {
+			t.Errorf("results[%d] = %v, want the secret of policy %s", i, result, policies[i].Spec.SecretRef.Name)
+		}
+	}
+}
+
+func TestResolveAllStopsWhenContextIsDone(t *testing.T) {
+	server := newTestServerWithOptions([]Option{WithFetchConcurrency(1)})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	called := false
+	results := server.resolveAll(ctx, []v1beta1.CertificatePolicy{createPolicy("a")}, func(context.Context, v1beta1.CertificatePolicy) ([]*tlsv3.Secret, error) {
+		called = true
+		return nil, nil
+	})
+	if called {
+		t.Error("resolve was called after the context was done")
+	}
+	if !isTransient(results[0].err) {
+		t.Errorf("results[0].err = %v, want a transient error", results[0].err)
+	}
+}
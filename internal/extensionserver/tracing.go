@@ -0,0 +1,36 @@
+package extensionserver
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans of the hooks.
+const tracerName = "github.com/giantswarm/envoy-extension-server-app/internal/extensionserver"
+
+// Attributes of the spans of the hooks.
+var (
+	attributeListener         = attribute.Key("envoy.listener.name")
+	attributePolicyCount      = attribute.Key("certificatepolicy.count")
+	attributeNamespace        = attribute.Key("k8s.namespace.name")
+	attributeSecretName       = attribute.Key("k8s.secret.name")
+	attributeGatewayName      = attribute.Key("k8s.gateway.name")
+	attributeEnvoySecretCount = attribute.Key("envoy.secret.count")
+)
+
+// startSpan starts a span of a hook or one of its steps.
+func (s *Server) startSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// endSpan records err on span, if any, and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
+	}
+	span.End()
+}
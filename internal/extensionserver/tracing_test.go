@@ -0,0 +1,162 @@
+package extensionserver
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/envoyproxy/gateway/proto/extension"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/giantswarm/envoy-extension-server-app/internal/tracing"
+)
+
+// newTracedServer returns a Server exporting its spans to an in-memory
+// exporter.
+func newTracedServer(t *testing.T) (*Server, *tracetest.InMemoryExporter, *sdktrace.TracerProvider) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	server := newTestServerWithOptions([]Option{WithTracerProvider(provider)}, createTLSSecret("secret-1", nil))
+	return server, exporter, provider
+}
+
+// spansByName returns the ended spans by name, failing the test if a name
+// is not unique.
+func spansByName(t *testing.T, spans tracetest.SpanStubs) map[string]tracetest.SpanStub {
+	t.Helper()
+	byName := map[string]tracetest.SpanStub{}
+	for _, span := range spans {
+		if _, ok := byName[span.Name]; ok {
+			t.Fatalf("span %q was recorded more than once", span.Name)
+		}
+		byName[span.Name] = span
+	}
+	return byName
+}
+
+func TestPostTranslateModifySpans(t *testing.T) {
+	server, exporter, _ := newTracedServer(t)
+
+	_, err := server.PostTranslateModify(context.Background(), &pb.PostTranslateModifyRequest{
+		PostTranslateContext: &pb.PostTranslateExtensionContext{ExtensionResources: []*pb.ExtensionResource{
+			createExtensionResource(t, "secret-1"),
+			createExtensionResource(t, "secret-2"),
+		}},
+	})
+	if err != nil {
+		t.Fatalf("PostTranslateModify() error = %v", err)
+	}
+
+	var hook tracetest.SpanStub
+	var fetches []tracetest.SpanStub
+	names := map[string]int{}
+	for _, span := range exporter.GetSpans() {
+		names[span.Name]++
+		switch span.Name {
+		case "PostTranslateModify":
+			hook = span
+		case "fetchSecret":
+			fetches = append(fetches, span)
+		}
+	}
+	if names["PostTranslateModify"] != 1 || names["extractCertificatePolicies"] != 1 || names["fetchSecret"] != 2 {
+		t.Fatalf("recorded spans = %v", names)
+	}
+
+	for _, span := range exporter.GetSpans() {
+		if span.Name != "PostTranslateModify" && span.Parent.SpanID() != hook.SpanContext.SpanID() {
+			t.Errorf("span %q is not a child of the hook span", span.Name)
+		}
+	}
+
+	statuses := map[string]otelcodes.Code{}
+	for _, span := range fetches {
+		for _, attr := range span.Attributes {
+			if attr.Key == attributeSecretName {
+				statuses[attr.Value.AsString()] = span.Status.Code
+			}
+		}
+	}
+	if statuses["secret-1"] != otelcodes.Unset || statuses["secret-2"] != otelcodes.Error {
+		t.Errorf("fetchSecret statuses = %v, want secret-2 to have failed", statuses)
+	}
+}
+
+func TestPostHTTPListenerModifySpans(t *testing.T) {
+	server, exporter, _ := newTracedServer(t)
+
+	_, err := server.PostHTTPListenerModify(context.Background(), &pb.PostHTTPListenerModifyRequest{
+		Listener: newHTTPSListener(t, "default/gateway-1/https"),
+		PostListenerContext: &pb.PostHTTPListenerExtensionContext{ExtensionResources: []*pb.ExtensionResource{
+			createExtensionResource(t, "secret-1"),
+		}},
+	})
+	if err != nil {
+		t.Fatalf("PostHTTPListenerModify() error = %v", err)
+	}
+
+	spans := spansByName(t, exporter.GetSpans())
+	hook, ok := spans["PostHTTPListenerModify"]
+	if !ok {
+		t.Fatalf("no hook span was recorded, got %v", spans)
+	}
+	for _, name := range []string{"extractCertificatePolicies", "unmarshalTLSContext", "marshalTLSContext"} {
+		if span, ok := spans[name]; !ok || span.Parent.SpanID() != hook.SpanContext.SpanID() {
+			t.Errorf("span %q missing or not a child of the hook span", name)
+		}
+	}
+}
+
+func TestPostHTTPListenerModifySpanRecordsErrors(t *testing.T) {
+	server, exporter, _ := newTracedServer(t)
+
+	_, err := server.PostHTTPListenerModify(context.Background(), &pb.PostHTTPListenerModifyRequest{})
+	if err == nil {
+		t.Fatal("PostHTTPListenerModify() expected an error without listener")
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Status.Code != otelcodes.Error || len(spans[0].Events) != 1 {
+		t.Errorf("spans = %v, want a failed hook span with the error recorded", spans)
+	}
+}
+
+func TestTracePropagation(t *testing.T) {
+	server, exporter, provider := newTracedServer(t)
+	gateway := startFakeEnvoyGateway(t, server, grpc.StatsHandler(tracing.ServerHandler(provider)))
+
+	// Envoy Gateway's translation span, propagated with the hook call.
+	ctx, parent := sdktrace.NewTracerProvider().Tracer("envoy-gateway").Start(context.Background(), "translate")
+	carrier := propagation.MapCarrier{}
+	tracing.Propagator().Inject(ctx, carrier)
+	ctx = metadata.NewOutgoingContext(context.Background(), metadata.New(carrier))
+
+	_, err := gateway.client.PostTranslateModify(ctx, &pb.PostTranslateModifyRequest{
+		PostTranslateContext: &pb.PostTranslateExtensionContext{},
+	})
+	parent.End()
+	if err != nil {
+		t.Fatalf("PostTranslateModify() error = %v", err)
+	}
+
+	spans := spansByName(t, exporter.GetSpans())
+	rpc, ok := spans[pb.EnvoyGatewayExtension_ServiceDesc.ServiceName+"/PostTranslateModify"]
+	if !ok {
+		t.Fatalf("no gRPC server span was recorded, got %v", spans)
+	}
+	if rpc.SpanKind != trace.SpanKindServer || rpc.Parent.SpanID() != parent.SpanContext().SpanID() || !rpc.Parent.IsRemote() {
+		t.Errorf("gRPC server span does not continue the propagated trace: %+v", rpc.Parent)
+	}
+	if hook := spans["PostTranslateModify"]; hook.Parent.SpanID() != rpc.SpanContext.SpanID() ||
+		hook.SpanContext.TraceID() != parent.SpanContext().TraceID() {
+		t.Errorf("hook span is not part of the propagated trace")
+	}
+}
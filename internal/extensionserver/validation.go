@@ -0,0 +1,231 @@
+package extensionserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+// ValidationMode controls what a hook returns when the xDS it produced is
+// invalid.
+type ValidationMode string
+
+const (
+	// ValidationFailOpen returns the resources as they were received, so that
+	// Envoy is configured without the policies' changes. References the
+	// listener hook added to secrets of the policies are removed from the
+	// listeners.
+	ValidationFailOpen ValidationMode = "fail-open"
+
+	// ValidationFailClosed fails the hook, so that Envoy Gateway does not
+	// apply the translation at all.
+	ValidationFailClosed ValidationMode = "fail-closed"
+)
+
+// ValidationModes are the supported validation modes.
+var ValidationModes = []ValidationMode{ValidationFailOpen, ValidationFailClosed}
+
+// ParseValidationMode parses a validation mode.
+func ParseValidationMode(value string) (ValidationMode, error) {
+	mode := ValidationMode(value)
+	if !slices.Contains(ValidationModes, mode) {
+		return "", fmt.Errorf("unsupported validation mode %q, must be one of %v", value, ValidationModes)
+	}
+	return mode, nil
+}
+
+// eventReasonInvalidXDS is used when a hook produced xDS that failed
+// validation.
+const eventReasonInvalidXDS = "InvalidXDS"
+
+// checkOutput compares the validation errors of the resources a hook received
+// and produced. Only errors introduced by the hook count, as Envoy Gateway is
+// responsible for the rest. It returns an error if the hook must fail, and
+// whether it must return the resources it received instead.
+func (s *Server) checkOutput(ctx context.Context, hook string, policies []v1beta1.CertificatePolicy, received, produced func() []error) (bool, error) {
+	producedErrs := produced()
+	if len(producedErrs) == 0 {
+		return false, nil
+	}
+	introduced := introducedErrors(received(), producedErrs)
+	if len(introduced) == 0 {
+		return false, nil
+	}
+
+	err := errors.Join(introduced...)
+	s.logger(ctx).Error("hook produced invalid xDS",
+		"validationMode", s.validationMode,
+		"error", err,
+	)
+	for _, policy := range policies {
+		s.recordPolicyEvent(policy, corev1.EventTypeWarning, eventReasonInvalidXDS,
+			"%s produced invalid xDS, changes were not applied: %v", hook, err)
+	}
+
+	if s.validationMode == ValidationFailClosed {
+		return false, fmt.Errorf("%s produced invalid xDS: %w", hook, err)
+	}
+	return true, nil
+}
+
+// introducedErrors returns the errors of produced that are not in received.
+func introducedErrors(received, produced []error) []error {
+	known := make(map[string]struct{}, len(received))
+	for _, err := range received {
+		known[err.Error()] = struct{}{}
+	}
+	var introduced []error
+	for _, err := range produced {
+		if _, ok := known[err.Error()]; !ok {
+			introduced = append(introduced, err)
+		}
+	}
+	return introduced
+}
+
+// listenerErrors validates a listener and the TLS contexts of its filter
+// chains, which are opaque to the listener's own validation.
+func listenerErrors(listener *listenerv3.Listener) []error {
+	if listener == nil {
+		return nil
+	}
+
+	var errs []error
+	if err := listener.ValidateAll(); err != nil {
+		errs = append(errs, fmt.Errorf("listener %q: %w", listener.GetName(), err))
+	}
+	for _, filterChain := range listener.GetFilterChains() {
+		tlsContext, err := filterChainTLSContext(filterChain)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("listener %q filter chain %q: %w", listener.GetName(), filterChain.GetName(), err))
+			continue
+		}
+		if tlsContext == nil {
+			continue
+		}
+		if err := tlsContext.ValidateAll(); err != nil {
+			errs = append(errs, fmt.Errorf("listener %q filter chain %q: %w", listener.GetName(), filterChain.GetName(), err))
+		}
+		for _, config := range tlsContext.GetCommonTlsContext().GetTlsCertificateSdsSecretConfigs() {
+			if config.GetName() == "" || config.GetSdsConfig() == nil {
+				errs = append(errs, fmt.Errorf("listener %q filter chain %q: SDS secret config %q has no name or config source",
+					listener.GetName(), filterChain.GetName(), config.GetName()))
+			}
+		}
+	}
+	return errs
+}
+
+// translationErrors validates the resources of a translation and the
+// references between them.
+func translationErrors(listeners []*listenerv3.Listener, routes []*routev3.RouteConfiguration, clusters []*clusterv3.Cluster, secrets []*tlsv3.Secret) []error {
+	var errs []error
+
+	secretNames := map[string]struct{}{}
+	for _, secret := range secrets {
+		if err := secret.ValidateAll(); err != nil {
+			errs = append(errs, fmt.Errorf("secret %q: %w", secret.GetName(), err))
+		}
+		if _, ok := secretNames[secret.GetName()]; ok {
+			errs = append(errs, fmt.Errorf("secret %q is defined more than once", secret.GetName()))
+		}
+		secretNames[secret.GetName()] = struct{}{}
+	}
+
+	clusterNames := map[string]struct{}{}
+	for _, cluster := range clusters {
+		if err := cluster.ValidateAll(); err != nil {
+			errs = append(errs, fmt.Errorf("cluster %q: %w", cluster.GetName(), err))
+		}
+		clusterNames[cluster.GetName()] = struct{}{}
+	}
+
+	for _, listener := range listeners {
+		errs = append(errs, listenerErrors(listener)...)
+		for _, name := range listenerSecretReferences(listener) {
+			if _, ok := secretNames[name]; !ok {
+				errs = append(errs, fmt.Errorf("listener %q references unknown secret %q", listener.GetName(), name))
+			}
+		}
+	}
+
+	// Only check cluster references when clusters are part of the translation.
+	for _, route := range routes {
+		if err := route.ValidateAll(); err != nil {
+			errs = append(errs, fmt.Errorf("route configuration %q: %w", route.GetName(), err))
+		}
+		if len(clusters) == 0 {
+			continue
+		}
+		for _, name := range routeClusterReferences(route) {
+			if _, ok := clusterNames[name]; !ok {
+				errs = append(errs, fmt.Errorf("route configuration %q references unknown cluster %q", route.GetName(), name))
+			}
+		}
+	}
+
+	return errs
+}
+
+// filterChainTLSContext returns the downstream TLS context of a filter chain,
+// including the one of a QUIC transport, or nil if it does not terminate TLS.
+func filterChainTLSContext(filterChain *listenerv3.FilterChain) (*tlsv3.DownstreamTlsContext, error) {
+	typedConfig := filterChain.GetTransportSocket().GetTypedConfig()
+	if typedConfig == nil || !typedConfig.MessageIs(&tlsv3.DownstreamTlsContext{}) && !isQUIC(filterChain.GetTransportSocket()) {
+		return nil, nil
+	}
+	return extractDownstreamTlsContext(filterChain.GetTransportSocket())
+}
+
+// listenerSecretReferences returns the names of the SDS secrets the TLS
+// contexts of a listener reference.
+func listenerSecretReferences(listener *listenerv3.Listener) []string {
+	var names []string
+	for _, filterChain := range listener.GetFilterChains() {
+		tlsContext, err := filterChainTLSContext(filterChain)
+		if err != nil || tlsContext == nil {
+			continue
+		}
+		commonTlsContext := tlsContext.GetCommonTlsContext()
+		for _, config := range commonTlsContext.GetTlsCertificateSdsSecretConfigs() {
+			names = append(names, config.GetName())
+		}
+		if config := commonTlsContext.GetValidationContextSdsSecretConfig(); config != nil {
+			names = append(names, config.GetName())
+		}
+		if config := commonTlsContext.GetCombinedValidationContext().GetValidationContextSdsSecretConfig(); config != nil {
+			names = append(names, config.GetName())
+		}
+		if config := tlsContext.GetSessionTicketKeysSdsSecretConfig(); config != nil {
+			names = append(names, config.GetName())
+		}
+	}
+	return names
+}
+
+// routeClusterReferences returns the names of the clusters the routes of a
+// route configuration forward to.
+func routeClusterReferences(route *routev3.RouteConfiguration) []string {
+	var names []string
+	for _, virtualHost := range route.GetVirtualHosts() {
+		for _, r := range virtualHost.GetRoutes() {
+			action := r.GetRoute()
+			if name := action.GetCluster(); name != "" {
+				names = append(names, name)
+			}
+			for _, weighted := range action.GetWeightedClusters().GetClusters() {
+				names = append(names, weighted.GetName())
+			}
+		}
+	}
+	return names
+}
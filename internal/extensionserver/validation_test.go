@@ -0,0 +1,342 @@
+package extensionserver
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	pb "github.com/envoyproxy/gateway/proto/extension"
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+func TestParseValidationMode(t *testing.T) {
+	for _, mode := range ValidationModes {
+		if got, err := ParseValidationMode(string(mode)); err != nil || got != mode {
+			t.Errorf("ParseValidationMode(%q) = %q, %v", mode, got, err)
+		}
+	}
+	if _, err := ParseValidationMode("fail-sometimes"); err == nil {
+		t.Error("ParseValidationMode() expected an error for an unknown mode")
+	}
+}
+
+func TestListenerErrors(t *testing.T) {
+	withSdsConfigs := func(configs ...*tlsv3.SdsSecretConfig) *listenerv3.Listener {
+		return &listenerv3.Listener{
+			Name: "listener",
+			FilterChains: []*listenerv3.FilterChain{{
+				Name: "chain",
+				TransportSocket: &corev3.TransportSocket{
+					Name: "envoy.transport_sockets.tls",
+					ConfigType: &corev3.TransportSocket_TypedConfig{TypedConfig: mustAny(t, &tlsv3.DownstreamTlsContext{
+						CommonTlsContext: &tlsv3.CommonTlsContext{TlsCertificateSdsSecretConfigs: configs},
+					})},
+				},
+			}},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		listener *listenerv3.Listener
+		wantErr  string
+	}{
+		{
+			name:     "nil listener",
+			listener: nil,
+		},
+		{
+			name:     "valid listener",
+			listener: withSdsConfigs(NewSdsSecretConfig("default/secret-1")),
+		},
+		{
+			name:     "listener without TLS",
+			listener: &listenerv3.Listener{Name: "listener", FilterChains: []*listenerv3.FilterChain{{}}},
+		},
+		{
+			name:     "SDS secret config without name",
+			listener: withSdsConfigs(&tlsv3.SdsSecretConfig{}),
+			wantErr:  "has no name or config source",
+		},
+		{
+			name: "invalid TLS context",
+			listener: withSdsConfigs(&tlsv3.SdsSecretConfig{
+				Name:      "default/secret-1",
+				SdsConfig: &corev3.ConfigSource{ConfigSourceSpecifier: &corev3.ConfigSource_Ads{}, ResourceApiVersion: corev3.ApiVersion(42)},
+			}),
+			wantErr: "ResourceApiVersion",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := listenerErrors(tt.listener)
+			if tt.wantErr == "" {
+				if len(errs) != 0 {
+					t.Errorf("listenerErrors() = %v, want none", errs)
+				}
+				return
+			}
+			if err := errors.Join(errs...); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("listenerErrors() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTranslationErrors(t *testing.T) {
+	listener := newHTTPSListener(t, "default/gateway-1/https")
+	tlsContext, _ := extractDownstreamTlsContext(listener.FilterChains[0].TransportSocket)
+	appendSdsSecretConfigs(tlsContext, []string{"default/secret-1"})
+	tlsContext.CommonTlsContext.ValidationContextType = &tlsv3.CommonTlsContext_ValidationContextSdsSecretConfig{
+		ValidationContextSdsSecretConfig: NewSdsSecretConfig("default/secret-1/ca.crt"),
+	}
+	if err := updateTransportSocket(listener.FilterChains[0].TransportSocket, tlsContext); err != nil {
+		t.Fatalf("failed to update transport socket: %v", err)
+	}
+	route := &routev3.RouteConfiguration{
+		Name: "default/gateway-1/https",
+		VirtualHosts: []*routev3.VirtualHost{{
+			Name:    "www",
+			Domains: []string{"*"},
+			Routes: []*routev3.Route{
+				{
+					Match:  &routev3.RouteMatch{PathSpecifier: &routev3.RouteMatch_Prefix{Prefix: "/"}},
+					Action: &routev3.Route_Route{Route: &routev3.RouteAction{ClusterSpecifier: &routev3.RouteAction_Cluster{Cluster: "backend"}}},
+				},
+				{
+					Match: &routev3.RouteMatch{PathSpecifier: &routev3.RouteMatch_Prefix{Prefix: "/canary"}},
+					Action: &routev3.Route_Route{Route: &routev3.RouteAction{ClusterSpecifier: &routev3.RouteAction_WeightedClusters{
+						WeightedClusters: &routev3.WeightedCluster{Clusters: []*routev3.WeightedCluster_ClusterWeight{{Name: "canary"}}},
+					}}},
+				},
+			},
+		}},
+	}
+	clusters := []*clusterv3.Cluster{{Name: "backend"}, {Name: "canary"}}
+	secrets := []*tlsv3.Secret{{Name: "default/secret-1"}, {Name: "default/secret-1/ca.crt"}}
+
+	tests := []struct {
+		name     string
+		routes   []*routev3.RouteConfiguration
+		clusters []*clusterv3.Cluster
+		secrets  []*tlsv3.Secret
+		wantErrs []string
+	}{
+		{
+			name:     "consistent translation",
+			routes:   []*routev3.RouteConfiguration{route},
+			clusters: clusters,
+			secrets:  secrets,
+		},
+		{
+			name:    "clusters not part of the translation",
+			routes:  []*routev3.RouteConfiguration{route},
+			secrets: secrets,
+		},
+		{
+			name:     "unknown secret",
+			secrets:  secrets[:1],
+			wantErrs: []string{`references unknown secret "default/secret-1/ca.crt"`},
+		},
+		{
+			name:     "unknown cluster",
+			routes:   []*routev3.RouteConfiguration{route},
+			clusters: clusters[:1],
+			secrets:  secrets,
+			wantErrs: []string{`references unknown cluster "canary"`},
+		},
+		{
+			name:     "duplicate secret",
+			secrets:  append([]*tlsv3.Secret{secrets[0]}, secrets...),
+			wantErrs: []string{`secret "default/secret-1" is defined more than once`},
+		},
+		{
+			name: "invalid secret",
+			secrets: append([]*tlsv3.Secret{{
+				Name: "default/invalid",
+				Type: &tlsv3.Secret_TlsCertificate{TlsCertificate: &tlsv3.TlsCertificate{
+					PrivateKeyProvider: &tlsv3.PrivateKeyProvider{},
+				}},
+			}}, secrets...),
+			wantErrs: []string{`secret "default/invalid"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := translationErrors([]*listenerv3.Listener{listener}, tt.routes, tt.clusters, tt.secrets)
+			if len(errs) != len(tt.wantErrs) {
+				t.Fatalf("translationErrors() = %v, want %d errors", errs, len(tt.wantErrs))
+			}
+			for i, want := range tt.wantErrs {
+				if !strings.Contains(errs[i].Error(), want) {
+					t.Errorf("error[%d] = %v, want it to contain %q", i, errs[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckOutput(t *testing.T) {
+	received := func() []error { return []error{errors.New("pre-existing")} }
+	introducing := func() []error { return []error{errors.New("pre-existing"), errors.New("introduced")} }
+
+	tests := []struct {
+		name            string
+		mode            ValidationMode
+		produced        func() []error
+		wantUseOriginal bool
+		wantErr         bool
+		wantEvents      int
+	}{
+		{
+			name:     "valid output",
+			mode:     ValidationFailClosed,
+			produced: func() []error { return nil },
+		},
+		{
+			name:     "only pre-existing errors",
+			mode:     ValidationFailClosed,
+			produced: received,
+		},
+		{
+			name:            "fail open",
+			mode:            ValidationFailOpen,
+			produced:        introducing,
+			wantUseOriginal: true,
+			wantEvents:      1,
+		},
+		{
+			name:       "fail closed",
+			mode:       ValidationFailClosed,
+			produced:   introducing,
+			wantErr:    true,
+			wantEvents: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			server := newTestServerWithOptions([]Option{WithValidationMode(tt.mode), WithEventRecorder(recorder)})
+
+			useOriginal, err := server.checkOutput(context.Background(), "PostTranslateModify", []v1beta1.CertificatePolicy{createPolicy("secret-1")}, received, tt.produced)
+			if useOriginal != tt.wantUseOriginal || (err != nil) != tt.wantErr {
+				t.Fatalf("checkOutput() = %v, %v, want %v and error %v", useOriginal, err, tt.wantUseOriginal, tt.wantErr)
+			}
+			if err != nil && (!strings.Contains(err.Error(), "introduced") || strings.Contains(err.Error(), "pre-existing")) {
+				t.Errorf("checkOutput() error = %v, want only the introduced error", err)
+			}
+
+			events := drainEvents(recorder)
+			if len(events) != tt.wantEvents {
+				t.Fatalf("got events %v, want %d", events, tt.wantEvents)
+			}
+			for _, event := range events {
+				if !strings.HasPrefix(event, "Warning InvalidXDS") {
+					t.Errorf("event = %q, want an InvalidXDS warning", event)
+				}
+			}
+		})
+	}
+}
+
+func TestPostHTTPListenerModifyKeepsPreExistingErrors(t *testing.T) {
+	// Envoy Gateway sent a listener the validation rejects already. The
+	// policies' changes are still applied, as they do not make it worse.
+	listener := newHTTPSListener(t, "default/gateway-1/https")
+	tlsContext, _ := extractDownstreamTlsContext(listener.FilterChains[0].TransportSocket)
+	tlsContext.CommonTlsContext.TlsCertificateSdsSecretConfigs = []*tlsv3.SdsSecretConfig{{}}
+	if err := updateTransportSocket(listener.FilterChains[0].TransportSocket, tlsContext); err != nil {
+		t.Fatalf("failed to update transport socket: %v", err)
+	}
+
+	server := newTestServerWithOptions([]Option{WithValidationMode(ValidationFailClosed)})
+	resp, err := server.PostHTTPListenerModify(context.Background(), &pb.PostHTTPListenerModifyRequest{
+		Listener: listener,
+		PostListenerContext: &pb.PostHTTPListenerExtensionContext{
+			ExtensionResources: []*pb.ExtensionResource{createExtensionResource(t, "secret-1")},
+		},
+	})
+	if err != nil {
+		t.Fatalf("PostHTTPListenerModify() error = %v", err)
+	}
+	if got := listenerSecretReferences(resp.Listener); len(got) != 2 || got[1] != "default/secret-1" {
+		t.Errorf("SDS references = %v, want the policy's secret to be added", got)
+	}
+}
+
+func TestPostTranslateModifyValidatesOutput(t *testing.T) {
+	server := newTestServerWithOptions([]Option{WithValidationMode(ValidationFailClosed)}, createTLSSecret("secret-1", nil))
+	listener := newHTTPSListener(t, "default/gateway-1/https")
+	tlsContext, _ := extractDownstreamTlsContext(listener.FilterChains[0].TransportSocket)
+	appendSdsSecretConfigs(tlsContext, []string{"default/secret-1"})
+	if err := updateTransportSocket(listener.FilterChains[0].TransportSocket, tlsContext); err != nil {
+		t.Fatalf("failed to update transport socket: %v", err)
+	}
+
+	resp, err := server.PostTranslateModify(context.Background(), &pb.PostTranslateModifyRequest{
+		Listeners: []*listenerv3.Listener{listener},
+		PostTranslateContext: &pb.PostTranslateExtensionContext{
+			ExtensionResources: []*pb.ExtensionResource{createExtensionResource(t, "secret-1")},
+		},
+	})
+	if err != nil {
+		t.Fatalf("PostTranslateModify() error = %v", err)
+	}
+	if len(resp.Secrets) != 1 || resp.Secrets[0].GetName() != "default/secret-1" {
+		t.Errorf("secrets = %v, want default/secret-1", resp.Secrets)
+	}
+}
+
+// invalidSecretMutator adds a secret the validation rejects.
+type invalidSecretMutator struct{}
+
+func (invalidSecretMutator) Name() string { return "invalid-secret" }
+
+func (invalidSecretMutator) MutateTranslation(_ context.Context, translation *Translation) error {
+	translation.Secrets = append(translation.Secrets, &tlsv3.Secret{
+		Name: "default/invalid",
+		Type: &tlsv3.Secret_TlsCertificate{TlsCertificate: &tlsv3.TlsCertificate{
+			PrivateKeyProvider: &tlsv3.PrivateKeyProvider{},
+		}},
+	})
+	return nil
+}
+
+func TestFailOpenKeepsListenersConsistent(t *testing.T) {
+	server := newTestServerWithOptions([]Option{
+		WithValidationMode(ValidationFailOpen),
+		WithMutators(certificatesMutator{}, invalidSecretMutator{}),
+	}, createTLSSecret("secret-1", nil))
+	gateway := startFakeEnvoyGateway(t, server)
+
+	snapshot, err := gateway.translate(context.Background(),
+		[]*listenerv3.Listener{newHTTPSListenerServing(t, "default/gateway-1/https", "default/gateway-cert")},
+		[]*tlsv3.Secret{{Name: "default/gateway-cert"}},
+		[]*pb.ExtensionResource{createExtensionResource(t, "secret-1")},
+	)
+	if err != nil {
+		t.Fatalf("translation failed: %v", err)
+	}
+
+	// The translation is rejected, so the listener must not reference the
+	// policy's secret the listener hook added.
+	if got := snapshot.sdsReferences(t); !slices.Equal(got, []string{"default/gateway-cert"}) {
+		t.Errorf("SDS references = %v, want only default/gateway-cert", got)
+	}
+	if _, ok := snapshot.secrets["default/invalid"]; ok {
+		t.Error("the invalid secret is part of the snapshot")
+	}
+	snapshot.assertConsistent(t)
+}
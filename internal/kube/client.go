@@ -0,0 +1,190 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+// Options configures the client returned by NewClient.
+type Options struct {
+	// Cache reads Secrets, CertificatePolicies and Gateways from an
+	// informer cache instead of the API server. Other objects are always
+	// read from the API server.
+	Cache bool
+
+	// Namespaces restricts the cache to these namespaces. Reading a cached
+	// object of another namespace fails with a Forbidden error. All
+	// namespaces are cached when empty.
+	Namespaces []string
+
+	// SecretSelector restricts the cached Secrets to those matching it.
+	// Other Secrets are reported as not found. All Secrets are cached when
+	// nil.
+	SecretSelector labels.Selector
+
+	// PolicySelector restricts the cached CertificatePolicies to those
+	// matching it. All CertificatePolicies are cached when nil.
+	PolicySelector labels.Selector
+}
+
+// NewClient returns a client for the given cluster. When objects are cached,
+// it starts the cache and waits for it to be synced; the cache stops when
+// ctx is done.
+func NewClient(ctx context.Context, cfg *rest.Config, scheme *runtime.Scheme, opts Options) (client.Client, error) {
+	apiClient, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+	if !opts.Cache {
+		return apiClient, nil
+	}
+
+	objectCache, err := cache.New(cfg, cacheOptions(scheme, opts))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache: %w", err)
+	}
+	// Informers are created lazily, so create them before waiting for the
+	// sync.
+	for _, obj := range []client.Object{&corev1.Secret{}, &v1beta1.CertificatePolicy{}, &gwapiv1.Gateway{}} {
+		if _, err := objectCache.GetInformer(ctx, obj); err != nil {
+			return nil, fmt.Errorf("failed to create %T informer: %w", obj, err)
+		}
+	}
+	go func() { _ = objectCache.Start(ctx) }()
+	if !objectCache.WaitForCacheSync(ctx) {
+		return nil, fmt.Errorf("failed to sync cache")
+	}
+	return newCachingClient(apiClient, objectCache, opts.Namespaces), nil
+}
+
+// APIReader returns a reader of c that bypasses the cache, for lookups that
+// must see objects outside the cache scope.
+func APIReader(c client.Client) client.Reader {
+	if cached, ok := c.(*cachingClient); ok {
+		return cached.Client
+	}
+	return c
+}
+
+// MatchingLabels returns labels that match selector, so that objects the
+// server creates are visible through a cache restricted by it. Selectors
+// with Gt or Lt requirements cannot be matched and return an error.
+func MatchingLabels(selector labels.Selector) (map[string]string, error) {
+	if selector == nil {
+		return nil, nil
+	}
+	requirements, _ := selector.Requirements()
+	set := labels.Set{}
+	for _, requirement := range requirements {
+		switch requirement.Operator() {
+		case selection.Equals, selection.DoubleEquals, selection.In:
+			set[requirement.Key()] = requirement.ValuesUnsorted()[0]
+		case selection.Exists:
+			set[requirement.Key()] = ""
+		case selection.NotEquals, selection.NotIn, selection.DoesNotExist:
+			// Satisfied by leaving the label out.
+		default:
+			return nil, fmt.Errorf("label selector %q cannot be matched by fixed labels", selector)
+		}
+	}
+	if !selector.Matches(set) {
+		return nil, fmt.Errorf("label selector %q cannot be matched by fixed labels", selector)
+	}
+	return set, nil
+}
+
+// cacheOptions returns the options of a cache scoped to the namespaces and
+// selectors of opts.
+func cacheOptions(scheme *runtime.Scheme, opts Options) cache.Options {
+	cacheOpts := cache.Options{
+		Scheme: scheme,
+		ByObject: map[client.Object]cache.ByObject{
+			&corev1.Secret{}:             {Label: opts.SecretSelector},
+			&v1beta1.CertificatePolicy{}: {Label: opts.PolicySelector},
+		},
+	}
+	if len(opts.Namespaces) > 0 {
+		cacheOpts.DefaultNamespaces = make(map[string]cache.Config, len(opts.Namespaces))
+		for _, namespace := range opts.Namespaces {
+			cacheOpts.DefaultNamespaces[namespace] = cache.Config{}
+		}
+	}
+	return cacheOpts
+}
+
+// cachingClient reads Secrets, CertificatePolicies and Gateways from a cache and
+// everything else through the embedded client. Writes always go through the
+// embedded client.
+type cachingClient struct {
+	client.Client
+
+	cache      client.Reader
+	namespaces []string
+}
+
+func newCachingClient(apiClient client.Client, objectCache client.Reader, namespaces []string) *cachingClient {
+	return &cachingClient{Client: apiClient, cache: objectCache, namespaces: namespaces}
+}
+
+// Get reads cached objects from the cache.
+func (c *cachingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	resource, ok := cachedResource(obj)
+	if !ok {
+		return c.Client.Get(ctx, key, obj, opts...)
+	}
+	if !c.inScope(key.Namespace) {
+		return outOfScope(resource, key.Namespace, key.Name)
+	}
+	return c.cache.Get(ctx, key, obj, opts...)
+}
+
+// List reads cached objects from the cache.
+func (c *cachingClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	resource, ok := cachedResource(list)
+	if !ok {
+		return c.Client.List(ctx, list, opts...)
+	}
+	listOpts := (&client.ListOptions{}).ApplyOptions(opts)
+	if listOpts.Namespace != "" && !c.inScope(listOpts.Namespace) {
+		return outOfScope(resource, listOpts.Namespace, "")
+	}
+	return c.cache.List(ctx, list, opts...)
+}
+
+func (c *cachingClient) inScope(namespace string) bool {
+	return len(c.namespaces) == 0 || slices.Contains(c.namespaces, namespace)
+}
+
+// cachedResource returns the resource of obj if it is read from the cache.
+func cachedResource(obj runtime.Object) (schema.GroupResource, bool) {
+	switch obj.(type) {
+	case *corev1.Secret, *corev1.SecretList:
+		return corev1.Resource("secrets"), true
+	case *v1beta1.CertificatePolicy, *v1beta1.CertificatePolicyList:
+		return schema.GroupResource{Group: v1beta1.GroupName, Resource: "certificatepolicies"}, true
+	case *gwapiv1.Gateway, *gwapiv1.GatewayList:
+		return schema.GroupResource{Group: gwapiv1.GroupName, Resource: "gateways"}, true
+	}
+	return schema.GroupResource{}, false
+}
+
+// outOfScope returns a Forbidden error, so that reads outside the cache scope
+// fail permanently instead of being retried.
+func outOfScope(resource schema.GroupResource, namespace, name string) error {
+	return apierrors.NewForbidden(resource, name,
+		fmt.Errorf("namespace %s is outside the cache scope", namespace))
+}
@@ -0,0 +1,190 @@
+package kube
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+func newSecret(namespace, name, value string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		StringData: map[string]string{"source": value},
+	}
+}
+
+func TestCachingClient(t *testing.T) {
+	apiClient := fake.NewClientBuilder().WithObjects(
+		newSecret("default", "tls", "api"),
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "config"}},
+	).Build()
+	secretCache := fake.NewClientBuilder().WithObjects(
+		newSecret("default", "tls", "cache"),
+		newSecret("other", "tls", "cache"),
+	).Build()
+	c := newCachingClient(apiClient, secretCache, []string{"default"})
+	ctx := context.Background()
+
+	var secret corev1.Secret
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "tls"}, &secret); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got := secret.StringData["source"]; got != "cache" {
+		t.Errorf("Secret read from %q, want the cache", got)
+	}
+
+	err := c.Get(ctx, client.ObjectKey{Namespace: "other", Name: "tls"}, &secret)
+	if !apierrors.IsForbidden(err) {
+		t.Errorf("Get() error = %v, want Forbidden outside the cache scope", err)
+	}
+
+	var secrets corev1.SecretList
+	if err := c.List(ctx, &secrets, client.InNamespace("default")); err != nil || len(secrets.Items) != 1 {
+		t.Errorf("List() = %v, %v, want the cached Secret", secrets.Items, err)
+	}
+	if err := c.List(ctx, &secrets, client.InNamespace("other")); !apierrors.IsForbidden(err) {
+		t.Errorf("List() error = %v, want Forbidden outside the cache scope", err)
+	}
+
+	var configMap corev1.ConfigMap
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "config"}, &configMap); err != nil {
+		t.Errorf("Get() error = %v, want other objects read from the API server", err)
+	}
+}
+
+func TestAPIReader(t *testing.T) {
+	apiClient := fake.NewClientBuilder().WithObjects(newSecret("other", "tls", "api")).Build()
+	c := newCachingClient(apiClient, fake.NewClientBuilder().Build(), []string{"default"})
+
+	var secret corev1.Secret
+	if err := APIReader(c).Get(context.Background(), client.ObjectKey{Namespace: "other", Name: "tls"}, &secret); err != nil {
+		t.Fatalf("Get() error = %v, want Secrets outside the cache scope read from the API server", err)
+	}
+	if reader := APIReader(apiClient); reader != apiClient {
+		t.Error("APIReader() of an uncached client must return the client itself")
+	}
+}
+
+func TestCachingClientWithoutScope(t *testing.T) {
+	secretCache := fake.NewClientBuilder().WithObjects(newSecret("other", "tls", "cache")).Build()
+	c := newCachingClient(fake.NewClientBuilder().Build(), secretCache, nil)
+
+	var secret corev1.Secret
+	if err := c.Get(context.Background(), client.ObjectKey{Namespace: "other", Name: "tls"}, &secret); err != nil {
+		t.Errorf("Get() error = %v, want every namespace cached", err)
+	}
+}
+
+func TestCachingClientPolicies(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1beta1.AddToScheme(scheme))
+	newPolicy := func(namespace string) *v1beta1.CertificatePolicy {
+		return &v1beta1.CertificatePolicy{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "policy"}}
+	}
+	objectCache := fake.NewClientBuilder().WithScheme(scheme).WithObjects(newPolicy("default")).Build()
+	apiClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(newPolicy("default"), newPolicy("other")).Build()
+	c := newCachingClient(apiClient, objectCache, []string{"default"})
+	ctx := context.Background()
+
+	var policies v1beta1.CertificatePolicyList
+	if err := c.List(ctx, &policies); err != nil || len(policies.Items) != 1 {
+		t.Errorf("List() = %v, %v, want only the cached policy", policies.Items, err)
+	}
+	var policy v1beta1.CertificatePolicy
+	err := c.Get(ctx, client.ObjectKey{Namespace: "other", Name: "policy"}, &policy)
+	if !apierrors.IsForbidden(err) {
+		t.Errorf("Get() error = %v, want Forbidden outside the cache scope", err)
+	}
+}
+
+func TestCachingClientGateways(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(gwapiv1.AddToScheme(scheme))
+	newGateway := func(namespace string) *gwapiv1.Gateway {
+		return &gwapiv1.Gateway{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "gateway"}}
+	}
+	objectCache := fake.NewClientBuilder().WithScheme(scheme).WithObjects(newGateway("default")).Build()
+	c := newCachingClient(fake.NewClientBuilder().WithScheme(scheme).Build(), objectCache, []string{"default"})
+	ctx := context.Background()
+
+	var gateway gwapiv1.Gateway
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "gateway"}, &gateway); err != nil {
+		t.Errorf("Get() error = %v, want the Gateway read from the cache", err)
+	}
+	err := c.Get(ctx, client.ObjectKey{Namespace: "other", Name: "gateway"}, &gateway)
+	if !apierrors.IsForbidden(err) {
+		t.Errorf("Get() error = %v, want Forbidden outside the cache scope", err)
+	}
+}
+
+func TestMatchingLabels(t *testing.T) {
+	for _, selector := range []string{
+		"gateway.giantswarm.io/managed=true",
+		"team in (a,b),env!=dev",
+		"managed,!legacy",
+	} {
+		parsed, err := labels.Parse(selector)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", selector, err)
+		}
+		set, err := MatchingLabels(parsed)
+		if err != nil {
+			t.Errorf("MatchingLabels(%q) error = %v", selector, err)
+			continue
+		}
+		if !parsed.Matches(labels.Set(set)) {
+			t.Errorf("MatchingLabels(%q) = %v, which does not match", selector, set)
+		}
+	}
+
+	parsed, _ := labels.Parse("version>1")
+	if _, err := MatchingLabels(parsed); err == nil {
+		t.Error("MatchingLabels() expected an error for a Gt requirement")
+	}
+	if set, err := MatchingLabels(nil); set != nil || err != nil {
+		t.Errorf("MatchingLabels(nil) = %v, %v, want no labels", set, err)
+	}
+}
+
+func TestCacheOptions(t *testing.T) {
+	selector := labels.SelectorFromSet(labels.Set{"gateway.giantswarm.io/managed": "true"})
+	opts := cacheOptions(runtime.NewScheme(), Options{
+		Namespaces:     []string{"default", "envoy-gateway-system"},
+		SecretSelector: selector,
+	})
+
+	if len(opts.DefaultNamespaces) != 2 {
+		t.Errorf("DefaultNamespaces = %v, want default and envoy-gateway-system", opts.DefaultNamespaces)
+	}
+	for obj, byObject := range opts.ByObject {
+		switch obj.(type) {
+		case *corev1.Secret:
+			if byObject.Label == nil || byObject.Label.String() != selector.String() {
+				t.Errorf("Secret selector = %v, want %v", byObject.Label, selector)
+			}
+		case *v1beta1.CertificatePolicy:
+			if byObject.Label != nil {
+				t.Errorf("CertificatePolicy selector = %v, want none", byObject.Label)
+			}
+		default:
+			t.Errorf("unexpected cached object %T", obj)
+		}
+	}
+
+	if opts := cacheOptions(runtime.NewScheme(), Options{}); opts.DefaultNamespaces != nil {
+		t.Errorf("DefaultNamespaces = %v, want every namespace cached", opts.DefaultNamespaces)
+	}
+}
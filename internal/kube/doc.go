@@ -0,0 +1,6 @@
+// Package kube creates the Kubernetes client of the extension server.
+// Secrets, CertificatePolicies and Gateways can be read from an informer
+// cache restricted to a set of namespaces and label selectors, so that hooks
+// do not call the API server for every policy and the server only needs
+// access to the resources it serves.
+package kube
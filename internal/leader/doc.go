@@ -0,0 +1,4 @@
+// Package leader elects one replica of the extension server through a
+// Kubernetes Lease. Only the leader writes to the API server, e.g. status
+// updates and Events, while every replica keeps serving the hooks.
+package leader
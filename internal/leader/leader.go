@@ -0,0 +1,181 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+const (
+	// DefaultLeaseName is the default name of the Lease.
+	DefaultLeaseName = "envoy-extension-server"
+
+	// DefaultLeaseDuration is the default time non-leaders wait before
+	// taking over a Lease that was not renewed.
+	DefaultLeaseDuration = 15 * time.Second
+
+	// DefaultRenewDeadline is the default time the leader retries renewing
+	// the Lease before giving up leadership.
+	DefaultRenewDeadline = 10 * time.Second
+
+	// DefaultRetryPeriod is the default time between two attempts to acquire
+	// or renew the Lease.
+	DefaultRetryPeriod = 2 * time.Second
+
+	// serviceAccountNamespaceFile holds the namespace of the Pod.
+	serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// Gate reports whether this replica may write to the API server.
+type Gate interface {
+	// IsLeader returns true while this replica holds the leadership.
+	IsLeader() bool
+}
+
+// Always is the Gate used when leader election is disabled.
+var Always Gate = always{}
+
+type always struct{}
+
+func (always) IsLeader() bool { return true }
+
+// Options configures an Elector.
+type Options struct {
+	// Name is the name of the Lease. Defaults to DefaultLeaseName.
+	Name string
+
+	// Namespace is the namespace of the Lease. Defaults to the namespace of
+	// the Pod.
+	Namespace string
+
+	// Identity identifies this replica in the Lease. Defaults to the host
+	// name.
+	Identity string
+
+	// LeaseDuration defaults to DefaultLeaseDuration.
+	LeaseDuration time.Duration
+
+	// RenewDeadline defaults to DefaultRenewDeadline.
+	RenewDeadline time.Duration
+
+	// RetryPeriod defaults to DefaultRetryPeriod.
+	RetryPeriod time.Duration
+
+	// OnChange is called whenever this replica gains or loses the
+	// leadership.
+	OnChange func(leading bool)
+}
+
+// Elector campaigns for a Lease until its context is cancelled.
+type Elector struct {
+	log     *slog.Logger
+	config  leaderelection.LeaderElectionConfig
+	leading atomic.Bool
+}
+
+var _ Gate = &Elector{}
+
+// New creates an Elector. It does not campaign before Run is called.
+func New(logger *slog.Logger, clientset kubernetes.Interface, opts Options) (*Elector, error) {
+	if opts.Name == "" {
+		opts.Name = DefaultLeaseName
+	}
+	if opts.Namespace == "" {
+		opts.Namespace = PodNamespace()
+	}
+	if opts.Identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine the leader election identity: %w", err)
+		}
+		opts.Identity = hostname
+	}
+	if opts.LeaseDuration <= 0 {
+		opts.LeaseDuration = DefaultLeaseDuration
+	}
+	if opts.RenewDeadline <= 0 {
+		opts.RenewDeadline = DefaultRenewDeadline
+	}
+	if opts.RetryPeriod <= 0 {
+		opts.RetryPeriod = DefaultRetryPeriod
+	}
+
+	e := &Elector{log: logger.With(slog.String("lease", opts.Namespace+"/"+opts.Name), slog.String("identity", opts.Identity))}
+	e.config = leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Namespace: opts.Namespace, Name: opts.Name},
+			Client:     clientset.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: opts.Identity},
+		},
+		LeaseDuration:   opts.LeaseDuration,
+		RenewDeadline:   opts.RenewDeadline,
+		RetryPeriod:     opts.RetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            opts.Name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) { e.setLeading(true, opts.OnChange) },
+			OnStoppedLeading: func() { e.setLeading(false, opts.OnChange) },
+		},
+	}
+	// Validates the durations.
+	if _, err := leaderelection.NewLeaderElector(e.config); err != nil {
+		return nil, fmt.Errorf("invalid leader election configuration: %w", err)
+	}
+	return e, nil
+}
+
+// IsLeader returns true while this replica holds the Lease.
+func (e *Elector) IsLeader() bool {
+	return e.leading.Load()
+}
+
+// Run campaigns for the Lease until ctx is cancelled. A replica that loses
+// the Lease campaigns again. The Lease is released on return.
+func (e *Elector) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		elector, err := leaderelection.NewLeaderElector(e.config)
+		if err != nil {
+			// Unreachable, the configuration was validated by New.
+			e.log.Error("failed to create leader elector", slog.String("error", err.Error()))
+			return
+		}
+		elector.Run(ctx)
+	}
+}
+
+func (e *Elector) setLeading(leading bool, onChange func(bool)) {
+	if e.leading.Swap(leading) == leading {
+		return
+	}
+	if leading {
+		e.log.Info("Started leading")
+	} else {
+		e.log.Info("Stopped leading")
+	}
+	if onChange != nil {
+		onChange(leading)
+	}
+}
+
+// PodNamespace returns the namespace of the Pod from the POD_NAMESPACE
+// environment variable or the service account, and "default" outside a Pod.
+func PodNamespace() string {
+	if namespace := os.Getenv("POD_NAMESPACE"); namespace != "" {
+		return namespace
+	}
+	if data, err := os.ReadFile(serviceAccountNamespaceFile); err == nil {
+		if namespace := strings.TrimSpace(string(data)); namespace != "" {
+			return namespace
+		}
+	}
+	return "default"
+}
@@ -0,0 +1,181 @@
+package leader
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+)
+
+func testOptions(identity string, onChange func(bool)) Options {
+	return Options{
+		Namespace:     "extension-server",
+		Identity:      identity,
+		LeaseDuration: time.Hour,
+		RenewDeadline: time.Minute,
+		RetryPeriod:   10 * time.Millisecond,
+		OnChange:      onChange,
+	}
+}
+
+func waitFor[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out")
+	}
+	var zero T
+	return zero
+}
+
+func TestElectorAcquiresAndReleasesLease(t *testing.T) {
+	clientset := fake.NewClientset()
+	changes := make(chan bool, 2)
+	elector, err := New(slog.New(slog.DiscardHandler), clientset, testOptions("replica-1", func(leading bool) { changes <- leading }))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if elector.IsLeader() {
+		t.Fatal("IsLeader() = true before Run")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		elector.Run(ctx)
+		close(done)
+	}()
+
+	if !waitFor(t, changes) || !elector.IsLeader() {
+		t.Fatal("elector did not become the leader")
+	}
+	lease, err := clientset.CoordinationV1().Leases("extension-server").Get(context.Background(), DefaultLeaseName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get lease: %v", err)
+	}
+	if got := ptr.Deref(lease.Spec.HolderIdentity, ""); got != "replica-1" {
+		t.Errorf("lease holder = %q, want replica-1", got)
+	}
+
+	cancel()
+	waitFor(t, done)
+	if elector.IsLeader() {
+		t.Error("IsLeader() = true after Run returned")
+	}
+	lease, err = clientset.CoordinationV1().Leases("extension-server").Get(context.Background(), DefaultLeaseName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get lease: %v", err)
+	}
+	if got := ptr.Deref(lease.Spec.HolderIdentity, ""); got != "" {
+		t.Errorf("lease holder = %q after release, want none", got)
+	}
+}
+
+func TestElectorFollowsHeldLease(t *testing.T) {
+	now := metav1.NewMicroTime(time.Now())
+	clientset := fake.NewClientset(&coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "extension-server", Name: DefaultLeaseName},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       ptr.To("replica-2"),
+			LeaseDurationSeconds: ptr.To[int32](3600),
+			AcquireTime:          &now,
+			RenewTime:            &now,
+		},
+	})
+	gets := make(chan struct{}, 10)
+	clientset.PrependReactor("get", "leases", func(k8stesting.Action) (bool, runtime.Object, error) {
+		select {
+		case gets <- struct{}{}:
+		default:
+		}
+		return false, nil, nil
+	})
+
+	changes := make(chan bool, 1)
+	elector, err := New(slog.New(slog.DiscardHandler), clientset, testOptions("replica-1", func(leading bool) { changes <- leading }))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go elector.Run(ctx)
+
+	// The second read of the Lease happens after the first attempt to
+	// acquire it failed.
+	waitFor(t, gets)
+	waitFor(t, gets)
+	if elector.IsLeader() {
+		t.Error("IsLeader() = true while another replica holds the lease")
+	}
+	select {
+	case leading := <-changes:
+		t.Errorf("OnChange(%v) called while another replica holds the lease", leading)
+	default:
+	}
+}
+
+func TestNewRejectsInvalidDurations(t *testing.T) {
+	opts := testOptions("replica-1", nil)
+	opts.RenewDeadline = 2 * opts.LeaseDuration
+	if _, err := New(slog.New(slog.DiscardHandler), fake.NewClientset(), opts); err == nil {
+		t.Error("New() expected an error for a renew deadline longer than the lease duration")
+	}
+}
+
+type fakeGate struct {
+	leading bool
+}
+
+func (g *fakeGate) IsLeader() bool { return g.leading }
+
+func TestRecorder(t *testing.T) {
+	gate := &fakeGate{}
+	fakeRecorder := record.NewFakeRecorder(10)
+	r := Recorder(fakeRecorder, gate)
+	object := &corev1.Secret{}
+
+	r.Event(object, corev1.EventTypeNormal, "Follower", "dropped")
+	gate.leading = true
+	r.Event(object, corev1.EventTypeNormal, "Leader", "emitted")
+	r.Eventf(object, corev1.EventTypeNormal, "Leader", "emitted %d", 2)
+	r.AnnotatedEventf(object, nil, corev1.EventTypeNormal, "Leader", "emitted %d", 3)
+	close(fakeRecorder.Events)
+
+	var got []string
+	for event := range fakeRecorder.Events {
+		got = append(got, event)
+	}
+	want := []string{"Normal Leader emitted", "Normal Leader emitted 2", "Normal Leader emitted 3"}
+	if len(got) != len(want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("events[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestPodNamespace(t *testing.T) {
+	t.Setenv("POD_NAMESPACE", "extension-server")
+	if got := PodNamespace(); got != "extension-server" {
+		t.Errorf("PodNamespace() = %q, want extension-server", got)
+	}
+}
+
+func TestAlways(t *testing.T) {
+	if !Always.IsLeader() {
+		t.Error("Always.IsLeader() = false")
+	}
+}
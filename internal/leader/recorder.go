@@ -0,0 +1,35 @@
+package leader
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// recorder drops the events of replicas that are not the leader.
+type recorder struct {
+	recorder record.EventRecorder
+	gate     Gate
+}
+
+// Recorder wraps the given recorder so that only the leader emits events.
+func Recorder(r record.EventRecorder, gate Gate) record.EventRecorder {
+	return &recorder{recorder: r, gate: gate}
+}
+
+func (r *recorder) Event(object runtime.Object, eventtype, reason, message string) {
+	if r.gate.IsLeader() {
+		r.recorder.Event(object, eventtype, reason, message)
+	}
+}
+
+func (r *recorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...any) {
+	if r.gate.IsLeader() {
+		r.recorder.Eventf(object, eventtype, reason, messageFmt, args...)
+	}
+}
+
+func (r *recorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...any) {
+	if r.gate.IsLeader() {
+		r.recorder.AnnotatedEventf(object, annotations, eventtype, reason, messageFmt, args...)
+	}
+}
@@ -0,0 +1,5 @@
+// Package logging sets up the structured logger of the extension server. It
+// redacts private keys and other secret material from logged protobuf
+// messages and carries a request-scoped logger with a correlation ID through
+// the context of each hook call.
+package logging
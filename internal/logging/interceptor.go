@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"path"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// CorrelationIDHeader is the gRPC metadata key a caller can set to have its
+// own correlation ID used for a request.
+const CorrelationIDHeader = "x-request-id"
+
+// UnaryServerInterceptor stores a logger carrying the hook name and a
+// correlation ID in the context of every request. The correlation ID is taken
+// from the CorrelationIDHeader metadata when present and generated otherwise.
+func UnaryServerInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		requestLogger := logger.With(
+			"hook", path.Base(info.FullMethod),
+			"correlationID", correlationID(ctx),
+		)
+		return handler(NewContext(ctx, requestLogger), req)
+	}
+}
+
+// correlationID returns the correlation ID sent by the caller, or a new one.
+func correlationID(ctx context.Context) string {
+	if values := metadata.ValueFromIncomingContext(ctx, CorrelationIDHeader); len(values) > 0 && values[0] != "" {
+		return values[0]
+	}
+	return newCorrelationID()
+}
+
+// newCorrelationID returns a random 16 byte hex encoded ID.
+func newCorrelationID() string {
+	id := make([]byte, 16)
+	// crypto/rand.Read never returns an error.
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,61 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		wantID string
+	}{
+		{
+			name: "generated correlation ID",
+			ctx:  context.Background(),
+		},
+		{
+			name:   "correlation ID of the caller",
+			ctx:    metadata.NewIncomingContext(context.Background(), metadata.Pairs(CorrelationIDHeader, "request-1")),
+			wantID: "request-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			interceptor := UnaryServerInterceptor(New(&buf, FormatJSON, slog.LevelInfo))
+			info := &grpc.UnaryServerInfo{FullMethod: "/envoygateway.extension.EnvoyGatewayExtension/PostTranslateModify"}
+
+			_, err := interceptor(tt.ctx, nil, info, func(ctx context.Context, _ any) (any, error) {
+				FromContext(ctx, nil).Info("hello")
+				return nil, nil
+			})
+			if err != nil {
+				t.Fatalf("interceptor error = %v", err)
+			}
+
+			var record map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatalf("log output %q is not JSON: %v", buf.String(), err)
+			}
+			if record["hook"] != "PostTranslateModify" {
+				t.Errorf("hook = %v, want PostTranslateModify", record["hook"])
+			}
+			id, _ := record["correlationID"].(string)
+			if tt.wantID != "" && id != tt.wantID {
+				t.Errorf("correlationID = %q, want %q", id, tt.wantID)
+			}
+			if tt.wantID == "" && len(id) != 32 {
+				t.Errorf("correlationID = %q, want a generated ID", id)
+			}
+		})
+	}
+}
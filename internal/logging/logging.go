@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// Format is the encoding of log records.
+type Format string
+
+const (
+	// FormatText writes log records as key=value pairs.
+	FormatText Format = "text"
+
+	// FormatJSON writes log records as JSON objects, one per line.
+	FormatJSON Format = "json"
+)
+
+// Formats lists the supported log formats.
+var Formats = []Format{FormatText, FormatJSON}
+
+// ParseFormat parses a log format name.
+func ParseFormat(value string) (Format, error) {
+	for _, format := range Formats {
+		if string(format) == value {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown log format %q, must be one of %v", value, Formats)
+}
+
+// ParseLevel parses a log level name such as "info" or "DEBUG".
+func ParseLevel(value string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return level, fmt.Errorf("invalid log level: %w", err)
+	}
+	return level, nil
+}
+
+// New returns a logger writing records of at least the given level to w in
+// the given format. Secret material in logged protobuf messages is redacted.
+func New(w io.Writer, format Format, level slog.Leveler) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch format {
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		handler = slog.NewTextHandler(w, opts)
+	}
+	return slog.New(NewRedactingHandler(handler))
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying logger.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, or fallback if there is none.
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return fallback
+}
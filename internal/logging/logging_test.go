@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	for _, format := range Formats {
+		if got, err := ParseFormat(string(format)); err != nil || got != format {
+			t.Errorf("ParseFormat(%q) = %q, %v", format, got, err)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("ParseFormat() expected an error for an unknown format")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	if level, err := ParseLevel("Debug"); err != nil || level != slog.LevelDebug {
+		t.Errorf("ParseLevel(Debug) = %v, %v", level, err)
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("ParseLevel() expected an error for an unknown level")
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		New(&buf, FormatJSON, slog.LevelInfo).Info("hello", "key", "value")
+
+		var record map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+			t.Fatalf("log output %q is not JSON: %v", buf.String(), err)
+		}
+		if record["msg"] != "hello" || record["key"] != "value" {
+			t.Errorf("record = %v", record)
+		}
+	})
+
+	t.Run("text", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&buf, FormatText, slog.LevelWarn)
+		logger.Info("dropped")
+		logger.Warn("hello", "key", "value")
+
+		if got := buf.String(); strings.Contains(got, "dropped") || !strings.Contains(got, "msg=hello key=value") {
+			t.Errorf("log output = %q", got)
+		}
+	})
+}
+
+func TestFromContext(t *testing.T) {
+	fallback := slog.New(slog.DiscardHandler)
+	if got := FromContext(context.Background(), fallback); got != fallback {
+		t.Error("FromContext() did not return the fallback for a context without logger")
+	}
+
+	logger := slog.New(slog.DiscardHandler).With("key", "value")
+	if got := FromContext(NewContext(context.Background(), logger), fallback); got != logger {
+		t.Error("FromContext() did not return the logger carried by the context")
+	}
+}
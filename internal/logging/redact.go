@@ -0,0 +1,118 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/giantswarm/envoy-extension-server-app/internal/redact"
+)
+
+// RedactingHandler is a slog.Handler that replaces protobuf messages in
+// attributes, including slices of them, with redacted copies before passing
+// records on. Messages are written as protojson by JSON handlers and as
+// single-line prototext by text handlers.
+type RedactingHandler struct {
+	next slog.Handler
+}
+
+// NewRedactingHandler returns a RedactingHandler passing records on to next.
+func NewRedactingHandler(next slog.Handler) *RedactingHandler {
+	return &RedactingHandler{next: next}
+}
+
+// Enabled implements slog.Handler.
+func (h *RedactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *RedactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(attr))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *RedactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = redactAttr(attr)
+	}
+	return &RedactingHandler{next: h.next.WithAttrs(redacted)}
+}
+
+// WithGroup implements slog.Handler.
+func (h *RedactingHandler) WithGroup(name string) slog.Handler {
+	return &RedactingHandler{next: h.next.WithGroup(name)}
+}
+
+func redactAttr(attr slog.Attr) slog.Attr {
+	attr.Value = attr.Value.Resolve()
+	switch attr.Value.Kind() {
+	case slog.KindGroup:
+		group := attr.Value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, member := range group {
+			redacted[i] = redactAttr(member)
+		}
+		attr.Value = slog.GroupValue(redacted...)
+	case slog.KindAny:
+		attr.Value = redactValue(attr.Value.Any())
+	}
+	return attr
+}
+
+func redactValue(value any) slog.Value {
+	if message, ok := value.(proto.Message); ok {
+		return slog.AnyValue(newRedactedMessage(message))
+	}
+
+	list := reflect.ValueOf(value)
+	if list.Kind() != reflect.Slice || !list.Type().Elem().Implements(reflect.TypeFor[proto.Message]()) {
+		return slog.AnyValue(value)
+	}
+	messages := make([]redactedMessage, list.Len())
+	for i := range messages {
+		messages[i] = newRedactedMessage(list.Index(i).Interface().(proto.Message))
+	}
+	return slog.AnyValue(messages)
+}
+
+// redactedMessage is a redacted protobuf message that encodes itself as
+// protojson or prototext depending on the handler.
+type redactedMessage struct {
+	message proto.Message
+}
+
+func newRedactedMessage(message proto.Message) redactedMessage {
+	if message == nil || !message.ProtoReflect().IsValid() {
+		// Nil messages hold nothing to redact.
+		return redactedMessage{message: message}
+	}
+	return redactedMessage{message: redact.Message(message)}
+}
+
+func (m redactedMessage) MarshalJSON() ([]byte, error) {
+	return protojson.Marshal(m.message)
+}
+
+func (m redactedMessage) MarshalText() ([]byte, error) {
+	return prototext.Marshal(m.message)
+}
+
+// String is used by text handlers for messages inside slices.
+func (m redactedMessage) String() string {
+	text, err := m.MarshalText()
+	if err != nil {
+		return err.Error()
+	}
+	return string(text)
+}
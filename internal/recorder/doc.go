@@ -0,0 +1,5 @@
+// Package recorder captures the hook requests Envoy Gateway sends, together
+// with the responses of the extension server, so that production issues can
+// be reproduced offline. Private keys and other secret material are redacted
+// before anything is written to disk.
+package recorder
@@ -0,0 +1,226 @@
+package recorder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"k8s.io/utils/clock"
+
+	"github.com/giantswarm/envoy-extension-server-app/internal/redact"
+)
+
+const (
+	// DefaultMaxFiles is the default number of recordings kept.
+	DefaultMaxFiles = 100
+
+	// recordingSuffix is the file name suffix of recordings.
+	recordingSuffix = ".json"
+
+	// timeFormat sorts lexically in chronological order.
+	timeFormat = "20060102T150405.000000000Z"
+)
+
+// Recording is a recorded hook call as it is stored on disk.
+type Recording struct {
+	// Method is the full gRPC method name of the hook.
+	Method string `json:"method"`
+
+	// Time is when the call was received.
+	Time time.Time `json:"time"`
+
+	// Request is the protojson encoded, redacted request.
+	Request json.RawMessage `json:"request"`
+
+	// Response is the protojson encoded, redacted response. It is empty
+	// when the hook failed.
+	Response json.RawMessage `json:"response,omitempty"`
+
+	// Error is the error returned by the hook.
+	Error string `json:"error,omitempty"`
+}
+
+// Hook returns the name of the recorded hook, e.g. "PostTranslateModify".
+func (r *Recording) Hook() string {
+	return path.Base(r.Method)
+}
+
+// ReadRecording reads a recording written by a Recorder.
+func ReadRecording(data []byte) (*Recording, error) {
+	var recording Recording
+	if err := json.Unmarshal(data, &recording); err != nil {
+		return nil, fmt.Errorf("failed to decode recording: %w", err)
+	}
+	if recording.Method == "" || len(recording.Request) == 0 {
+		return nil, errors.New("recording has no method or request")
+	}
+	return &recording, nil
+}
+
+// Options configures a Recorder.
+type Options struct {
+	// Dir is the directory recordings are written to. It is created if it
+	// does not exist.
+	Dir string
+
+	// MaxFiles is the number of recordings kept. Older recordings are
+	// deleted. Defaults to DefaultMaxFiles.
+	MaxFiles int
+
+	// Clock is used to timestamp recordings. Defaults to the real clock.
+	Clock clock.PassiveClock
+}
+
+// Recorder writes hook requests and responses to a directory, keeping only
+// the most recent ones.
+type Recorder struct {
+	log      *slog.Logger
+	dir      string
+	maxFiles int
+	clock    clock.PassiveClock
+
+	mu  sync.Mutex
+	seq uint64
+}
+
+// New creates a Recorder writing to opts.Dir.
+func New(logger *slog.Logger, opts Options) (*Recorder, error) {
+	if opts.Dir == "" {
+		return nil, errors.New("recording directory must not be empty")
+	}
+	if err := os.MkdirAll(opts.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create recording directory: %w", err)
+	}
+
+	r := &Recorder{
+		log:      logger,
+		dir:      opts.Dir,
+		maxFiles: opts.MaxFiles,
+		clock:    opts.Clock,
+	}
+	if r.maxFiles <= 0 {
+		r.maxFiles = DefaultMaxFiles
+	}
+	if r.clock == nil {
+		r.clock = clock.RealClock{}
+	}
+	return r, nil
+}
+
+// UnaryServerInterceptor records every unary call handled by the server.
+// Failing to record is logged and does not affect the call.
+func (r *Recorder) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		received := r.clock.Now()
+		resp, err := handler(ctx, req)
+		if recordErr := r.record(info.FullMethod, received, req, resp, err); recordErr != nil {
+			r.log.Error("failed to record hook call", "method", info.FullMethod, "error", recordErr)
+		}
+		return resp, err
+	}
+}
+
+// record writes a single recording and rotates old ones out.
+func (r *Recorder) record(method string, received time.Time, req, resp any, callErr error) error {
+	recording := Recording{Method: method, Time: received.UTC()}
+
+	var err error
+	if recording.Request, err = marshalRedacted(req); err != nil {
+		return fmt.Errorf("request: %w", err)
+	}
+	if callErr != nil {
+		recording.Error = callErr.Error()
+	} else if recording.Response, err = marshalRedacted(resp); err != nil {
+		return fmt.Errorf("response: %w", err)
+	}
+
+	data, err := json.MarshalIndent(recording, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode recording: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seq++
+	name := fmt.Sprintf("%s-%06d-%s%s", recording.Time.Format(timeFormat), r.seq%1000000, recording.Hook(), recordingSuffix)
+	if err := writeFileAtomic(filepath.Join(r.dir, name), data); err != nil {
+		return err
+	}
+	return r.rotate()
+}
+
+// rotate deletes the oldest recordings beyond maxFiles.
+func (r *Recorder) rotate() error {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return fmt.Errorf("failed to list recordings: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), recordingSuffix) {
+			names = append(names, entry.Name())
+		}
+	}
+	if len(names) <= r.maxFiles {
+		return nil
+	}
+
+	slices.Sort(names)
+	var errs []error
+	for _, name := range names[:len(names)-r.maxFiles] {
+		if err := os.Remove(filepath.Join(r.dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// marshalRedacted encodes a protobuf message as protojson after redacting
+// its secret material.
+func marshalRedacted(value any) (json.RawMessage, error) {
+	message, ok := value.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a protobuf message", value)
+	}
+	data, err := protojson.Marshal(redact.Message(message))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %T: %w", value, err)
+	}
+	return data, nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place,
+// so that readers never see partial recordings.
+func writeFileAtomic(name string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".recording-*")
+	if err != nil {
+		return fmt.Errorf("failed to create recording: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	return nil
+}
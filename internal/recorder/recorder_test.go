@@ -0,0 +1,171 @@
+package recorder
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/envoyproxy/gateway/proto/extension"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/giantswarm/envoy-extension-server-app/internal/redact"
+)
+
+var start = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestRecorder(t *testing.T, maxFiles int) (*Recorder, *clocktesting.FakePassiveClock) {
+	t.Helper()
+	fakeClock := clocktesting.NewFakePassiveClock(start)
+	r, err := New(slog.New(slog.DiscardHandler), Options{Dir: t.TempDir(), MaxFiles: maxFiles, Clock: fakeClock})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return r, fakeClock
+}
+
+func translateRequest() *pb.PostTranslateModifyRequest {
+	return &pb.PostTranslateModifyRequest{
+		Secrets: []*tlsv3.Secret{{
+			Name: "default/secret-1",
+			Type: &tlsv3.Secret_TlsCertificate{TlsCertificate: &tlsv3.TlsCertificate{
+				CertificateChain: inline("cert"),
+				PrivateKey:       inline("private-key"),
+			}},
+		}},
+	}
+}
+
+func invoke(t *testing.T, r *Recorder, method string, req any, resp any, err error) {
+	t.Helper()
+	handler := func(context.Context, any) (any, error) { return resp, err }
+	gotResp, gotErr := r.UnaryServerInterceptor()(context.Background(), req, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+	if gotResp != resp || !errors.Is(gotErr, err) {
+		t.Fatalf("interceptor changed the call result: %v, %v", gotResp, gotErr)
+	}
+}
+
+func readRecordings(t *testing.T, dir string) map[string]*Recording {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to list recordings: %v", err)
+	}
+	recordings := map[string]*Recording{}
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatalf("failed to read recording: %v", err)
+		}
+		recording, err := ReadRecording(data)
+		if err != nil {
+			t.Fatalf("ReadRecording(%s) error = %v", entry.Name(), err)
+		}
+		recordings[entry.Name()] = recording
+	}
+	return recordings
+}
+
+func TestUnaryServerInterceptorRecordsCalls(t *testing.T) {
+	r, _ := newTestRecorder(t, 0)
+	req := translateRequest()
+	resp := &pb.PostTranslateModifyResponse{Secrets: req.Secrets}
+
+	invoke(t, r, pb.EnvoyGatewayExtension_PostTranslateModify_FullMethodName, req, resp, nil)
+
+	recordings := readRecordings(t, r.dir)
+	if len(recordings) != 1 {
+		t.Fatalf("got %d recordings, want 1", len(recordings))
+	}
+	for name, recording := range recordings {
+		if !strings.HasSuffix(name, "-PostTranslateModify.json") {
+			t.Errorf("recording name = %q", name)
+		}
+		if recording.Hook() != "PostTranslateModify" {
+			t.Errorf("Hook() = %q, want PostTranslateModify", recording.Hook())
+		}
+		if !recording.Time.Equal(start) {
+			t.Errorf("Time = %v, want %v", recording.Time, start)
+		}
+
+		recordedReq := &pb.PostTranslateModifyRequest{}
+		if err := protojson.Unmarshal(recording.Request, recordedReq); err != nil {
+			t.Fatalf("failed to decode recorded request: %v", err)
+		}
+		tlsCert := recordedReq.Secrets[0].GetTlsCertificate()
+		if got := tlsCert.GetPrivateKey().GetInlineString(); got != redact.Placeholder {
+			t.Errorf("recorded private key = %q, want it redacted", got)
+		}
+		if got := string(tlsCert.GetCertificateChain().GetInlineBytes()); got != "cert" {
+			t.Errorf("recorded certificate chain = %q, want %q", got, "cert")
+		}
+		if strings.Contains(string(recording.Response), "cHJpdmF0ZS1rZXk") {
+			t.Error("recorded response contains the private key")
+		}
+	}
+
+	// The caller's messages must not be redacted.
+	if got := string(req.Secrets[0].GetTlsCertificate().GetPrivateKey().GetInlineBytes()); got != "private-key" {
+		t.Errorf("request private key = %q, want it untouched", got)
+	}
+}
+
+func TestUnaryServerInterceptorRecordsErrors(t *testing.T) {
+	r, _ := newTestRecorder(t, 0)
+
+	invoke(t, r, pb.EnvoyGatewayExtension_PostTranslateModify_FullMethodName, translateRequest(), nil, errors.New("boom"))
+
+	for _, recording := range readRecordings(t, r.dir) {
+		if recording.Error != "boom" {
+			t.Errorf("Error = %q, want %q", recording.Error, "boom")
+		}
+		if len(recording.Response) != 0 {
+			t.Errorf("Response = %s, want none", recording.Response)
+		}
+	}
+}
+
+func TestRecorderRotates(t *testing.T) {
+	r, fakeClock := newTestRecorder(t, 2)
+
+	for range 3 {
+		invoke(t, r, pb.EnvoyGatewayExtension_PostTranslateModify_FullMethodName, translateRequest(), &pb.PostTranslateModifyResponse{}, nil)
+		fakeClock.SetTime(fakeClock.Now().Add(time.Second))
+	}
+
+	recordings := readRecordings(t, r.dir)
+	if len(recordings) != 2 {
+		t.Fatalf("got %d recordings, want 2", len(recordings))
+	}
+	for _, recording := range recordings {
+		if recording.Time.Equal(start) {
+			t.Error("the oldest recording was not deleted")
+		}
+	}
+}
+
+func TestNewRequiresDir(t *testing.T) {
+	if _, err := New(slog.New(slog.DiscardHandler), Options{}); err == nil {
+		t.Error("New() expected an error for an empty directory")
+	}
+}
+
+func TestReadRecordingErrors(t *testing.T) {
+	for _, data := range []string{"not json", `{"method":"/x/PostTranslateModify"}`} {
+		if _, err := ReadRecording([]byte(data)); err == nil {
+			t.Errorf("ReadRecording(%q) expected an error", data)
+		}
+	}
+}
+
+func inline(value string) *corev3.DataSource {
+	return &corev3.DataSource{Specifier: &corev3.DataSource_InlineBytes{InlineBytes: []byte(value)}}
+}
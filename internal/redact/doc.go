@@ -0,0 +1,3 @@
+// Package redact removes private keys and other secret material from Envoy
+// xDS protobuf messages, so that they can be written to recordings and logs.
+package redact
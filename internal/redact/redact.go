@@ -0,0 +1,88 @@
+package redact
+
+import (
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Placeholder replaces redacted secret material.
+const Placeholder = "[redacted]"
+
+// Message returns a copy of message with private keys, passwords, session
+// ticket keys and generic secrets replaced, including inside Any fields.
+func Message(message proto.Message) proto.Message {
+	clone := proto.Clone(message)
+	redactMessage(clone.ProtoReflect())
+	return clone
+}
+
+func redactMessage(message protoreflect.Message) {
+	switch m := message.Interface().(type) {
+	case *tlsv3.TlsCertificate:
+		if m.PrivateKey != nil {
+			m.PrivateKey = redactedDataSource()
+		}
+		if m.Password != nil {
+			m.Password = redactedDataSource()
+		}
+		if m.PrivateKeyProvider != nil {
+			// Provider configs such as CryptoMB embed the private key.
+			m.PrivateKeyProvider.ConfigType = nil
+		}
+	case *tlsv3.TlsSessionTicketKeys:
+		for i := range m.Keys {
+			m.Keys[i] = redactedDataSource()
+		}
+	case *tlsv3.GenericSecret:
+		if m.Secret != nil {
+			m.Secret = redactedDataSource()
+		}
+	case *anypb.Any:
+		redactAny(m)
+		return
+	}
+
+	message.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		switch {
+		case field.IsList() && field.Message() != nil:
+			list := value.List()
+			for i := 0; i < list.Len(); i++ {
+				redactMessage(list.Get(i).Message())
+			}
+		case field.IsMap() && field.MapValue().Message() != nil:
+			value.Map().Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+				redactMessage(v.Message())
+				return true
+			})
+		case field.Message() != nil && !field.IsMap():
+			redactMessage(value.Message())
+		}
+		return true
+	})
+}
+
+// redactAny redacts the message packed into an Any. Types that are not linked
+// into the binary cannot contain the TLS types above and are left untouched.
+func redactAny(packed *anypb.Any) {
+	inner, err := packed.UnmarshalNew()
+	if err != nil {
+		return
+	}
+	original := proto.Clone(inner)
+	redactMessage(inner.ProtoReflect())
+	if proto.Equal(original, inner) {
+		return
+	}
+	if repacked, err := anypb.New(inner); err == nil {
+		packed.Value = repacked.Value
+	}
+}
+
+func redactedDataSource() *corev3.DataSource {
+	return &corev3.DataSource{
+		Specifier: &corev3.DataSource_InlineString{InlineString: Placeholder},
+	}
+}
@@ -0,0 +1,71 @@
+package redact
+
+import (
+	"testing"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestMessage(t *testing.T) {
+	tlsContext, err := anypb.New(&tlsv3.DownstreamTlsContext{
+		CommonTlsContext: &tlsv3.CommonTlsContext{
+			TlsCertificates: []*tlsv3.TlsCertificate{{
+				CertificateChain: inline("cert"),
+				PrivateKey:       inline("key"),
+				Password:         inline("password"),
+				PrivateKeyProvider: &tlsv3.PrivateKeyProvider{
+					ProviderName: "cryptomb",
+					ConfigType:   &tlsv3.PrivateKeyProvider_TypedConfig{TypedConfig: &anypb.Any{TypeUrl: "example", Value: []byte("key")}},
+				},
+			}},
+		},
+		SessionTicketKeysType: &tlsv3.DownstreamTlsContext_SessionTicketKeys{
+			SessionTicketKeys: &tlsv3.TlsSessionTicketKeys{Keys: []*corev3.DataSource{inline("ticket-key")}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to pack TLS context: %v", err)
+	}
+	message := &corev3.TransportSocket{
+		Name:       "envoy.transport_sockets.tls",
+		ConfigType: &corev3.TransportSocket_TypedConfig{TypedConfig: tlsContext},
+	}
+
+	redacted := Message(message).(*corev3.TransportSocket)
+
+	got := &tlsv3.DownstreamTlsContext{}
+	if err := redacted.GetTypedConfig().UnmarshalTo(got); err != nil {
+		t.Fatalf("failed to unpack TLS context: %v", err)
+	}
+	cert := got.GetCommonTlsContext().GetTlsCertificates()[0]
+	if cert.GetPrivateKey().GetInlineString() != Placeholder || cert.GetPassword().GetInlineString() != Placeholder {
+		t.Errorf("private key or password not redacted: %v", cert)
+	}
+	if cert.GetPrivateKeyProvider().GetTypedConfig() != nil {
+		t.Error("private key provider config not redacted")
+	}
+	if cert.GetPrivateKeyProvider().GetProviderName() != "cryptomb" {
+		t.Error("private key provider name was removed")
+	}
+	if string(cert.GetCertificateChain().GetInlineBytes()) != "cert" {
+		t.Error("certificate chain was modified")
+	}
+	if got.GetSessionTicketKeys().GetKeys()[0].GetInlineString() != Placeholder {
+		t.Error("session ticket key not redacted")
+	}
+
+	// The original message is left untouched.
+	original := &tlsv3.DownstreamTlsContext{}
+	if err := message.GetTypedConfig().UnmarshalTo(original); err != nil {
+		t.Fatalf("failed to unpack TLS context: %v", err)
+	}
+	if string(original.GetCommonTlsContext().GetTlsCertificates()[0].GetPrivateKey().GetInlineBytes()) != "key" {
+		t.Error("Message() modified its input")
+	}
+}
+
+func inline(value string) *corev3.DataSource {
+	return &corev3.DataSource{Specifier: &corev3.DataSource_InlineBytes{InlineBytes: []byte(value)}}
+}
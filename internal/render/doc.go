@@ -0,0 +1,4 @@
+// Package render runs the extension server hooks against requests read from
+// files, with CertificatePolicies and Secrets loaded from manifests instead
+// of a live cluster. It backs the render subcommand and the golden file tests.
+package render
@@ -0,0 +1,119 @@
+package render
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	pb "github.com/envoyproxy/gateway/proto/extension"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1alpha1"
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+// certificatePolicyKind is the kind of the policy extension resources.
+const certificatePolicyKind = "CertificatePolicy"
+
+// scheme contains the kinds manifests may hold.
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(gwapiv1.AddToScheme(scheme))
+	utilruntime.Must(v1alpha1.AddToScheme(scheme))
+	utilruntime.Must(v1beta1.AddToScheme(scheme))
+}
+
+// manifests are the objects loaded from YAML manifests.
+type manifests struct {
+	// policies are the CertificatePolicies as extension resources, in the
+	// form Envoy Gateway passes them to the hooks.
+	policies []*pb.ExtensionResource
+
+	// objects are all other objects. They are served by the fake cluster
+	// the hooks read from.
+	objects []client.Object
+}
+
+// loadManifests decodes the objects of multi-document YAML manifests.
+func loadManifests(files [][]byte) (*manifests, error) {
+	decoder := serializer.NewCodecFactory(scheme).UniversalDeserializer()
+
+	result := &manifests{}
+	for i, file := range files {
+		reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(file)))
+		for doc := 1; ; doc++ {
+			data, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("manifest %d: failed to read document %d: %w", i, doc, err)
+			}
+			if err := result.add(decoder, data); err != nil {
+				return nil, fmt.Errorf("manifest %d document %d: %w", i, doc, err)
+			}
+		}
+	}
+	return result, nil
+}
+
+// add decodes a single YAML document. Empty documents are skipped.
+func (m *manifests) add(decoder runtime.Decoder, data []byte) error {
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return fmt.Errorf("invalid YAML: %w", err)
+	}
+	if bytes.Equal(bytes.TrimSpace(jsonData), []byte("null")) {
+		return nil
+	}
+
+	var typeMeta metav1.TypeMeta
+	if err := yaml.Unmarshal(jsonData, &typeMeta); err != nil {
+		return fmt.Errorf("invalid object: %w", err)
+	}
+	if typeMeta.GroupVersionKind().Group == v1beta1.GroupName && typeMeta.Kind == certificatePolicyKind {
+		m.policies = append(m.policies, &pb.ExtensionResource{UnstructuredBytes: jsonData})
+		return nil
+	}
+
+	obj, _, err := decoder.Decode(jsonData, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %w", typeMeta.GroupVersionKind(), err)
+	}
+	clientObj, ok := obj.(client.Object)
+	if !ok {
+		return fmt.Errorf("%s is not a Kubernetes object", typeMeta.GroupVersionKind())
+	}
+	if secret, ok := clientObj.(*corev1.Secret); ok {
+		mergeStringData(secret)
+	}
+	m.objects = append(m.objects, clientObj)
+	return nil
+}
+
+// mergeStringData moves stringData into data, as the API server does on write.
+func mergeStringData(secret *corev1.Secret) {
+	if len(secret.StringData) == 0 {
+		return
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	for key, value := range secret.StringData {
+		secret.Data[key] = []byte(value)
+	}
+	secret.StringData = nil
+}
@@ -0,0 +1,146 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	pb "github.com/envoyproxy/gateway/proto/extension"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/envoy-extension-server-app/internal/extensionserver"
+)
+
+// Hook is the name of an extension server hook.
+type Hook string
+
+const (
+	// PostTranslateModify is the hook adding the policies' Secrets.
+	PostTranslateModify Hook = "PostTranslateModify"
+
+	// PostHTTPListenerModify is the hook referencing the policies' Secrets
+	// from HTTP listeners.
+	PostHTTPListenerModify Hook = "PostHTTPListenerModify"
+)
+
+// Hooks are the hooks that can be rendered.
+var Hooks = []Hook{PostTranslateModify, PostHTTPListenerModify}
+
+// Format is the encoding of the rendered response.
+type Format string
+
+const (
+	// FormatJSON renders the response as indented protojson.
+	FormatJSON Format = "json"
+
+	// FormatYAML renders the response as the YAML form of its protojson.
+	FormatYAML Format = "yaml"
+)
+
+// Options configures a render run.
+type Options struct {
+	// Hook is the hook to run.
+	Hook Hook
+
+	// Request is the hook request in its protojson form, encoded as JSON or
+	// YAML.
+	Request []byte
+
+	// Manifests are multi-document YAML manifests. CertificatePolicies are
+	// added to the extension resources of the request, all other objects
+	// are served to the hooks in place of a cluster.
+	Manifests [][]byte
+
+	// Format is the encoding of the result. Defaults to FormatJSON.
+	Format Format
+
+	// Logger receives the hook logs. Defaults to discarding them.
+	Logger *slog.Logger
+
+	// ServerOptions configure the extension server running the hook.
+	ServerOptions []extensionserver.Option
+}
+
+// Render runs a hook against a request and returns the encoded response.
+func Render(ctx context.Context, opts Options) ([]byte, error) {
+	loaded, err := loadManifests(opts.Manifests)
+	if err != nil {
+		return nil, err
+	}
+
+	requestJSON, err := yaml.YAMLToJSON(opts.Request)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(loaded.objects...).Build()
+	server := extensionserver.New(logger, k8sClient, opts.ServerOptions...)
+
+	var response proto.Message
+	switch opts.Hook {
+	case PostTranslateModify:
+		req := &pb.PostTranslateModifyRequest{}
+		if err := protojson.Unmarshal(requestJSON, req); err != nil {
+			return nil, fmt.Errorf("failed to decode %s request: %w", opts.Hook, err)
+		}
+		if req.PostTranslateContext == nil {
+			req.PostTranslateContext = &pb.PostTranslateExtensionContext{}
+		}
+		req.PostTranslateContext.ExtensionResources = append(req.PostTranslateContext.ExtensionResources, loaded.policies...)
+		response, err = server.PostTranslateModify(ctx, req)
+	case PostHTTPListenerModify:
+		req := &pb.PostHTTPListenerModifyRequest{}
+		if err := protojson.Unmarshal(requestJSON, req); err != nil {
+			return nil, fmt.Errorf("failed to decode %s request: %w", opts.Hook, err)
+		}
+		if req.PostListenerContext == nil {
+			req.PostListenerContext = &pb.PostHTTPListenerExtensionContext{}
+		}
+		req.PostListenerContext.ExtensionResources = append(req.PostListenerContext.ExtensionResources, loaded.policies...)
+		response, err = server.PostHTTPListenerModify(ctx, req)
+	default:
+		return nil, fmt.Errorf("unsupported hook %q, must be one of %v", opts.Hook, Hooks)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", opts.Hook, err)
+	}
+
+	return encode(response, opts.Format)
+}
+
+// encode marshals a response in the given format. protojson deliberately
+// varies its whitespace between runs, so the output is re-indented to keep
+// it stable.
+func encode(message proto.Message, format Format) ([]byte, error) {
+	data, err := protojson.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode response: %w", err)
+	}
+
+	switch format {
+	case "", FormatJSON:
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, data, "", "  "); err != nil {
+			return nil, fmt.Errorf("failed to indent response: %w", err)
+		}
+		indented.WriteByte('\n')
+		return indented.Bytes(), nil
+	case FormatYAML:
+		out, err := yaml.JSONToYAML(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert response to YAML: %w", err)
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q, must be %q or %q", format, FormatJSON, FormatYAML)
+	}
+}
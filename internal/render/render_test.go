@@ -0,0 +1,236 @@
+package render
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pb "github.com/envoyproxy/gateway/proto/extension"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/envoy-extension-server-app/internal/recorder"
+)
+
+const secretManifest = `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: secret-1
+  namespace: default
+type: kubernetes.io/tls
+stringData:
+  tls.crt: cert
+  tls.key: key
+`
+
+const policyManifest = `
+apiVersion: gateway.giantswarm.io/v1beta1
+kind: CertificatePolicy
+metadata:
+  name: policy-1
+  namespace: default
+spec:
+  secretRef:
+    name: secret-1
+  targetRefs:
+  - group: gateway.networking.k8s.io
+    kind: Gateway
+    name: gateway-1
+`
+
+const listenerRequest = `{
+  "listener": {
+    "name": "default/gateway-1/https",
+    "filterChains": [{
+      "transportSocket": {
+        "name": "envoy.transport_sockets.tls",
+        "typedConfig": {
+          "@type": "type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.DownstreamTlsContext",
+          "commonTlsContext": {}
+        }
+      }
+    }]
+  }
+}`
+
+func TestRenderPostTranslateModify(t *testing.T) {
+	out, err := Render(context.Background(), Options{
+		Hook:      PostTranslateModify,
+		Request:   []byte("secrets:\n- name: existing\n"),
+		Manifests: [][]byte{[]byte(secretManifest + "---\n" + policyManifest)},
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	resp := &pb.PostTranslateModifyResponse{}
+	if err := protojson.Unmarshal(out, resp); err != nil {
+		t.Fatalf("failed to decode output: %v", err)
+	}
+	if len(resp.Secrets) != 2 {
+		t.Fatalf("got %d secrets, want 2", len(resp.Secrets))
+	}
+	if resp.Secrets[1].GetName() != "default/secret-1" {
+		t.Errorf("secret[1].Name = %q, want %q", resp.Secrets[1].GetName(), "default/secret-1")
+	}
+	if got := string(resp.Secrets[1].GetTlsCertificate().GetCertificateChain().GetInlineBytes()); got != "cert" {
+		t.Errorf("certificate chain = %q, want %q", got, "cert")
+	}
+}
+
+func TestRenderPostHTTPListenerModify(t *testing.T) {
+	alphaPolicy := strings.NewReplacer(
+		"v1beta1", "v1alpha1",
+		"secretRef:\n    name: secret-1", "secretName: secret-1",
+	).Replace(policyManifest)
+
+	out, err := Render(context.Background(), Options{
+		Hook:      PostHTTPListenerModify,
+		Request:   []byte(listenerRequest),
+		Manifests: [][]byte{[]byte(alphaPolicy)},
+		Format:    FormatYAML,
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	jsonOut, err := yaml.YAMLToJSON(out)
+	if err != nil {
+		t.Fatalf("output is not YAML: %v", err)
+	}
+	resp := &pb.PostHTTPListenerModifyResponse{}
+	if err := protojson.Unmarshal(jsonOut, resp); err != nil {
+		t.Fatalf("failed to decode output: %v", err)
+	}
+	tlsContext := &tlsv3.DownstreamTlsContext{}
+	if err := resp.Listener.FilterChains[0].TransportSocket.GetTypedConfig().UnmarshalTo(tlsContext); err != nil {
+		t.Fatalf("failed to decode TLS context: %v", err)
+	}
+	configs := tlsContext.GetCommonTlsContext().GetTlsCertificateSdsSecretConfigs()
+	if len(configs) != 1 || configs[0].GetName() != "default/secret-1" {
+		t.Errorf("SDS secret configs = %v, want default/secret-1", configs)
+	}
+}
+
+func TestRenderRecording(t *testing.T) {
+	dir := t.TempDir()
+	hookRecorder, err := recorder.New(slog.New(slog.DiscardHandler), recorder.Options{Dir: dir})
+	if err != nil {
+		t.Fatalf("recorder.New() error = %v", err)
+	}
+	req := &pb.PostHTTPListenerModifyRequest{}
+	if err := protojson.Unmarshal([]byte(listenerRequest), req); err != nil {
+		t.Fatalf("failed to decode request: %v", err)
+	}
+	handler := func(context.Context, any) (any, error) { return &pb.PostHTTPListenerModifyResponse{}, nil }
+	info := &grpc.UnaryServerInfo{FullMethod: pb.EnvoyGatewayExtension_PostHTTPListenerModify_FullMethodName}
+	if _, err := hookRecorder.UnaryServerInterceptor()(context.Background(), req, info, handler); err != nil {
+		t.Fatalf("interceptor error = %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("got recordings %v (%v), want 1", files, err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("failed to read recording: %v", err)
+	}
+	recording, err := recorder.ReadRecording(data)
+	if err != nil {
+		t.Fatalf("ReadRecording() error = %v", err)
+	}
+
+	out, err := Render(context.Background(), Options{
+		Hook:      Hook(recording.Hook()),
+		Request:   recording.Request,
+		Manifests: [][]byte{[]byte(policyManifest)},
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if !strings.Contains(string(out), `"default/secret-1"`) {
+		t.Errorf("replayed output does not reference the policy's Secret:\n%s", out)
+	}
+}
+
+func TestRenderIsStable(t *testing.T) {
+	opts := Options{
+		Hook:      PostTranslateModify,
+		Request:   []byte("{}"),
+		Manifests: [][]byte{[]byte(secretManifest), []byte(policyManifest)},
+	}
+	first, err := Render(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	for range 5 {
+		out, err := Render(context.Background(), opts)
+		if err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+		if string(out) != string(first) {
+			t.Fatalf("Render() output changed between runs:\n%s\n%s", first, out)
+		}
+	}
+	if !json.Valid(first) {
+		t.Errorf("output is not valid JSON: %s", first)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{
+			name: "unknown hook",
+			opts: Options{Hook: "PostRouteModify", Request: []byte("{}")},
+		},
+		{
+			name: "unknown format",
+			opts: Options{Hook: PostTranslateModify, Request: []byte("{}"), Format: "toml"},
+		},
+		{
+			name: "request with unknown fields",
+			opts: Options{Hook: PostTranslateModify, Request: []byte(`{"listener": {}}`)},
+		},
+		{
+			name: "invalid request",
+			opts: Options{Hook: PostTranslateModify, Request: []byte("secrets: [")},
+		},
+		{
+			name: "manifest of an unknown kind",
+			opts: Options{
+				Hook:      PostTranslateModify,
+				Request:   []byte("{}"),
+				Manifests: [][]byte{[]byte("apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: w\n")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Render(context.Background(), tt.opts); err == nil {
+				t.Error("Render() expected an error")
+			}
+		})
+	}
+}
+
+func TestLoadManifestsSkipsEmptyDocuments(t *testing.T) {
+	loaded, err := loadManifests([][]byte{[]byte("---\n# comment only\n---\n" + secretManifest + "---\n")})
+	if err != nil {
+		t.Fatalf("loadManifests() error = %v", err)
+	}
+	if len(loaded.objects) != 1 || len(loaded.policies) != 0 {
+		t.Fatalf("got %d objects and %d policies, want 1 object", len(loaded.objects), len(loaded.policies))
+	}
+}
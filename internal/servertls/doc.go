@@ -0,0 +1,4 @@
+// Package servertls builds the TLS configuration of the gRPC listener. The
+// serving certificate is re-read when its files change, so that rotated
+// certificates are served without a restart.
+package servertls
@@ -0,0 +1,90 @@
+package servertls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// NewConfig returns a TLS configuration serving the certificate in certFile
+// and keyFile. When clientCAFile is set, clients must present a certificate
+// issued by one of its CAs.
+func NewConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	reloader := &keyPairReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := reloader.GetCertificate(nil); err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+	if clientCAFile != "" {
+		bundle, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("client CA bundle %s contains no certificates", clientCAFile)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+// keyPairReloader loads a key pair again when the modification time of one
+// of its files changes.
+type keyPairReloader struct {
+	certFile string
+	keyFile  string
+
+	mu          sync.Mutex
+	certificate *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+// GetCertificate returns the current key pair. When the files changed but
+// cannot be loaded, for instance because only one of them was replaced yet,
+// the previous key pair is returned.
+func (r *keyPairReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	certModTime, certErr := modTime(r.certFile)
+	keyModTime, keyErr := modTime(r.keyFile)
+	if err := errors.Join(certErr, keyErr); err != nil {
+		if r.certificate != nil {
+			return r.certificate, nil
+		}
+		return nil, err
+	}
+	if r.certificate != nil && certModTime.Equal(r.certModTime) && keyModTime.Equal(r.keyModTime) {
+		return r.certificate, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		if r.certificate != nil {
+			return r.certificate, nil
+		}
+		return nil, fmt.Errorf("failed to load serving certificate: %w", err)
+	}
+	r.certificate = &certificate
+	r.certModTime, r.keyModTime = certModTime, keyModTime
+	return r.certificate, nil
+}
+
+func modTime(path string) (time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read serving certificate: %w", err)
+	}
+	return info.ModTime(), nil
+}
@@ -0,0 +1,139 @@
+package servertls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeKeyPair writes a self-signed key pair with the given common name and
+// sets the modification time of its files.
+func writeKeyPair(t *testing.T, dir, commonName string, modTime time.Time) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	certFile, keyFile = filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	for path, block := range map[string]*pem.Block{
+		certFile: {Type: "CERTIFICATE", Bytes: der},
+		keyFile:  {Type: "EC PRIVATE KEY", Bytes: keyDER},
+	} {
+		if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("failed to set the modification time of %s: %v", path, err)
+		}
+	}
+	return certFile, keyFile
+}
+
+func servedCommonName(t *testing.T, cfg *tls.Config) string {
+	t.Helper()
+	certificate, err := cfg.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+	parsed, err := x509.ParseCertificate(certificate.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse the served certificate: %v", err)
+	}
+	return parsed.Subject.CommonName
+}
+
+func TestNewConfigReloadsKeyPair(t *testing.T) {
+	dir := t.TempDir()
+	modTime := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	certFile, keyFile := writeKeyPair(t, dir, "first", modTime)
+
+	cfg, err := NewConfig(certFile, keyFile, "")
+	if err != nil {
+		t.Fatalf("NewConfig() error = %v", err)
+	}
+	if cfg.ClientAuth != tls.NoClientCert {
+		t.Errorf("ClientAuth = %v, want no client certificate", cfg.ClientAuth)
+	}
+	if got := servedCommonName(t, cfg); got != "first" {
+		t.Errorf("served certificate = %q, want first", got)
+	}
+
+	writeKeyPair(t, dir, "second", modTime.Add(time.Hour))
+	if got := servedCommonName(t, cfg); got != "second" {
+		t.Errorf("served certificate = %q, want the rotated certificate", got)
+	}
+
+	// A half-written key pair keeps the previous one served.
+	if err := os.WriteFile(keyFile, []byte("partial"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(keyFile, modTime.Add(2*time.Hour), modTime.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if got := servedCommonName(t, cfg); got != "second" {
+		t.Errorf("served certificate = %q, want the previous certificate", got)
+	}
+}
+
+func TestNewConfigWithClientCA(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeKeyPair(t, dir, "server", time.Now())
+
+	cfg, err := NewConfig(certFile, keyFile, certFile)
+	if err != nil {
+		t.Fatalf("NewConfig() error = %v", err)
+	}
+	if cfg.ClientAuth != tls.RequireAndVerifyClientCert || cfg.ClientCAs == nil {
+		t.Errorf("ClientAuth = %v, want client certificates verified", cfg.ClientAuth)
+	}
+}
+
+func TestNewConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeKeyPair(t, dir, "server", time.Now())
+	empty := filepath.Join(dir, "empty.crt")
+	if err := os.WriteFile(empty, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string][3]string{
+		"missing key pair":      {filepath.Join(dir, "missing.crt"), keyFile, ""},
+		"mismatched key pair":   {certFile, certFile, ""},
+		"missing client CA":     {certFile, keyFile, filepath.Join(dir, "missing-ca.crt")},
+		"client CA without CAs": {certFile, keyFile, empty},
+	}
+	for name, files := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewConfig(files[0], files[1], files[2]); err == nil {
+				t.Error("NewConfig() expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,4 @@
+// Package ticketkeys defines the Secrets holding the TLS session ticket keys
+// Envoy replicas share to resume each other's TLS sessions, and rotates the
+// keys of those Secrets on a schedule.
+package ticketkeys
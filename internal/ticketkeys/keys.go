@@ -0,0 +1,37 @@
+package ticketkeys
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+const (
+	// DataKey is the key of the Secret data holding the session ticket keys.
+	DataKey = "ticket.keys"
+
+	// KeyLength is the length of a session ticket key Envoy requires.
+	KeyLength = 80
+)
+
+// Split splits the session ticket keys stored in a Secret. The first key
+// encrypts new tickets, all of them decrypt.
+func Split(data []byte) ([][]byte, error) {
+	if len(data) == 0 || len(data)%KeyLength != 0 {
+		return nil, fmt.Errorf("%s must hold one or more keys of %d bytes, got %d bytes", DataKey, KeyLength, len(data))
+	}
+	keys := make([][]byte, 0, len(data)/KeyLength)
+	for len(data) > 0 {
+		keys = append(keys, data[:KeyLength:KeyLength])
+		data = data[KeyLength:]
+	}
+	return keys, nil
+}
+
+// Generate returns a new random session ticket key.
+func Generate() ([]byte, error) {
+	key := make([]byte, KeyLength)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate session ticket key: %w", err)
+	}
+	return key, nil
+}
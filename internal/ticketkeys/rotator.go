@@ -0,0 +1,246 @@
+package ticketkeys
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+	"github.com/giantswarm/envoy-extension-server-app/internal/leader"
+)
+
+const (
+	// DefaultInterval is the default time between two rotations of a
+	// Secret.
+	DefaultInterval = 24 * time.Hour
+
+	// DefaultKeys is the default number of keys kept in a Secret.
+	DefaultKeys = 3
+
+	// RotatedAtAnnotation records when the encryption key of a Secret was
+	// last replaced. Only Secrets carrying it are rotated; the Rotator sets
+	// it on the Secrets it creates.
+	RotatedAtAnnotation = "gateway.giantswarm.io/session-ticket-keys-rotated-at"
+)
+
+// Options configures a Rotator.
+type Options struct {
+	// Interval is the time between two rotations of a Secret. Defaults to
+	// DefaultInterval.
+	Interval time.Duration
+
+	// Keys is the number of keys kept in a Secret, so that tickets issued
+	// before the last rotations still decrypt. Defaults to DefaultKeys.
+	Keys int
+
+	// Clock is used to determine the current time. Defaults to the real clock.
+	Clock clock.WithTicker
+
+	// Leader gates the writes, so that only one replica rotates the keys.
+	// Defaults to leader.Always.
+	Leader leader.Gate
+
+	// Reader reads the Secrets. It must not be restricted by a cache, or
+	// Secrets outside the cache are created again on every rotation.
+	// Defaults to the client.
+	Reader client.Reader
+
+	// Labels are set on the Secrets the Rotator manages, e.g. to match the
+	// label selector of the Secret cache the hooks read them from.
+	Labels map[string]string
+}
+
+// Rotator creates the session ticket keys Secrets referenced by
+// CertificatePolicies and periodically replaces their encryption key.
+type Rotator struct {
+	log      *slog.Logger
+	client   client.Client
+	reader   client.Reader
+	labels   map[string]string
+	clock    clock.WithTicker
+	interval time.Duration
+	keys     int
+	leader   leader.Gate
+}
+
+// New creates a Rotator.
+func New(logger *slog.Logger, client client.Client, opts Options) *Rotator {
+	r := &Rotator{
+		log:      logger,
+		client:   client,
+		reader:   opts.Reader,
+		labels:   opts.Labels,
+		clock:    opts.Clock,
+		interval: opts.Interval,
+		keys:     opts.Keys,
+		leader:   opts.Leader,
+	}
+	if r.clock == nil {
+		r.clock = clock.RealClock{}
+	}
+	if r.leader == nil {
+		r.leader = leader.Always
+	}
+	if r.reader == nil {
+		r.reader = client
+	}
+	if r.interval <= 0 {
+		r.interval = DefaultInterval
+	}
+	if r.keys <= 0 {
+		r.keys = DefaultKeys
+	}
+	return r
+}
+
+// Run rotates immediately and then checks the Secrets four times per
+// interval until ctx is cancelled, so that no key is used much longer than
+// the interval.
+func (r *Rotator) Run(ctx context.Context) {
+	ticker := r.clock.NewTicker(r.interval / 4)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Rotate(ctx); err != nil {
+			r.log.Error("session ticket key rotation failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// Rotate creates the missing session ticket keys Secrets referenced by
+// CertificatePolicies and rotates those due. Errors for individual Secrets
+// do not stop the rotation and are returned joined. Replicas that are not
+// the leader do nothing.
+func (r *Rotator) Rotate(ctx context.Context) error {
+	if !r.leader.IsLeader() {
+		return nil
+	}
+
+	var policies v1beta1.CertificatePolicyList
+	if err := r.client.List(ctx, &policies); err != nil {
+		return fmt.Errorf("failed to list CertificatePolicies: %w", err)
+	}
+
+	var errs []error
+	seen := map[types.NamespacedName]struct{}{}
+	for _, policy := range policies.Items {
+		ref := policy.Spec.SessionTicketKeysSecretRef
+		if ref == nil {
+			continue
+		}
+		key := types.NamespacedName{Namespace: policy.Namespace, Name: ref.Name}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		if err := r.rotateSecret(ctx, key); err != nil {
+			errs = append(errs, fmt.Errorf("secret %s: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// rotateSecret creates the Secret with a single key when it does not exist,
+// or prepends a new encryption key when its last rotation is an interval
+// ago. Missing labels are added to the Secret. Secrets without the
+// RotatedAtAnnotation are managed by someone else and left alone.
+func (r *Rotator) rotateSecret(ctx context.Context, key types.NamespacedName) error {
+	now := r.clock.Now()
+
+	var secret corev1.Secret
+	err := r.reader.Get(ctx, key, &secret)
+	if apierrors.IsNotFound(err) {
+		newKey, err := Generate()
+		if err != nil {
+			return err
+		}
+		secret = corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        key.Name,
+				Namespace:   key.Namespace,
+				Labels:      maps.Clone(r.labels),
+				Annotations: map[string]string{RotatedAtAnnotation: now.UTC().Format(time.RFC3339)},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: map[string][]byte{DataKey: newKey},
+		}
+		if err := r.client.Create(ctx, &secret); err != nil {
+			return fmt.Errorf("failed to create secret: %w", err)
+		}
+		r.log.Info("created session ticket keys secret", "secret", key.String())
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get secret: %w", err)
+	}
+
+	rotatedAt, ok := secret.Annotations[RotatedAtAnnotation]
+	if !ok {
+		r.log.Debug("leaving session ticket keys secret without rotation annotation alone", "secret", key.String())
+		return nil
+	}
+	last, err := time.Parse(time.RFC3339, rotatedAt)
+	due := err != nil || now.Sub(last) >= r.interval
+	labeled := r.addLabels(&secret)
+	if !due && !labeled {
+		return nil
+	}
+
+	keys, _ := Split(secret.Data[DataKey])
+	if due {
+		newKey, err := Generate()
+		if err != nil {
+			return err
+		}
+		// Keys that cannot be read are replaced.
+		keys = append([][]byte{newKey}, keys...)
+		keys = keys[:min(len(keys), r.keys)]
+
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		secret.Data[DataKey] = bytes.Join(keys, nil)
+		secret.Annotations[RotatedAtAnnotation] = now.UTC().Format(time.RFC3339)
+	}
+	if err := r.client.Update(ctx, &secret); err != nil {
+		return fmt.Errorf("failed to update secret: %w", err)
+	}
+	if due {
+		r.log.Info("rotated session ticket keys", "secret", key.String(), "keys", len(keys))
+	}
+	return nil
+}
+
+// addLabels sets the configured labels missing on the Secret and reports
+// whether it changed.
+func (r *Rotator) addLabels(secret *corev1.Secret) bool {
+	changed := false
+	for key, value := range r.labels {
+		if current, ok := secret.Labels[key]; ok && current == value {
+			continue
+		}
+		if secret.Labels == nil {
+			secret.Labels = map[string]string{}
+		}
+		secret.Labels[key] = value
+		changed = true
+	}
+	return changed
+}
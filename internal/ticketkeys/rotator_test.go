@@ -0,0 +1,250 @@
+package ticketkeys
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clocktesting "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+var now = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// follower is a leader.Gate of a replica that is not the leader.
+type follower struct{}
+
+func (follower) IsLeader() bool { return false }
+
+func newFakeClient(t *testing.T, objs ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
+
+func createPolicy(name, ticketKeysSecret string) *v1beta1.CertificatePolicy {
+	return &v1beta1.CertificatePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: v1beta1.CertificatePolicySpec{
+			SecretRef:                  v1beta1.SecretReference{Name: "secret-1"},
+			SessionTicketKeysSecretRef: &v1beta1.SecretReference{Name: ticketKeysSecret},
+		},
+	}
+}
+
+func createTicketKeysSecret(name string, rotatedAt *time.Time, keys ...[]byte) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Data:       map[string][]byte{DataKey: bytes.Join(keys, nil)},
+	}
+	if rotatedAt != nil {
+		secret.Annotations = map[string]string{RotatedAtAnnotation: rotatedAt.Format(time.RFC3339)}
+	}
+	return secret
+}
+
+func key(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeyLength)
+}
+
+func TestSplit(t *testing.T) {
+	keys, err := Split(append(key(1), key(2)...))
+	if err != nil {
+		t.Fatalf("Split() error = %v", err)
+	}
+	if len(keys) != 2 || !bytes.Equal(keys[0], key(1)) || !bytes.Equal(keys[1], key(2)) {
+		t.Errorf("Split() = %v, want two keys", keys)
+	}
+
+	for _, data := range [][]byte{nil, key(1)[:40], append(key(1), 0)} {
+		if _, err := Split(data); err == nil {
+			t.Errorf("Split() of %d bytes expected an error", len(data))
+		}
+	}
+}
+
+func TestRotate(t *testing.T) {
+	recently := now.Add(-time.Hour)
+	longAgo := now.Add(-25 * time.Hour)
+
+	tests := []struct {
+		name     string
+		secret   *corev1.Secret
+		wantKeys [][]byte
+		// wantNewKey expects a new encryption key in front of wantKeys.
+		wantNewKey bool
+	}{
+		{
+			name:       "missing secret is created",
+			wantNewKey: true,
+		},
+		{
+			name:     "recently rotated secret is kept",
+			secret:   createTicketKeysSecret("ticket-keys", &recently, key(1)),
+			wantKeys: [][]byte{key(1)},
+		},
+		{
+			name:       "due secret is rotated",
+			secret:     createTicketKeysSecret("ticket-keys", &longAgo, key(1)),
+			wantKeys:   [][]byte{key(1)},
+			wantNewKey: true,
+		},
+		{
+			name:       "oldest keys are dropped",
+			secret:     createTicketKeysSecret("ticket-keys", &longAgo, key(1), key(2), key(3)),
+			wantKeys:   [][]byte{key(1), key(2)},
+			wantNewKey: true,
+		},
+		{
+			name:       "invalid keys are replaced",
+			secret:     createTicketKeysSecret("ticket-keys", &longAgo, []byte("invalid")),
+			wantNewKey: true,
+		},
+		{
+			name:     "secret without annotation is left alone",
+			secret:   createTicketKeysSecret("ticket-keys", nil, key(1)),
+			wantKeys: [][]byte{key(1)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := []client.Object{createPolicy("policy-1", "ticket-keys"), createPolicy("policy-2", "ticket-keys")}
+			if tt.secret != nil {
+				objs = append(objs, tt.secret)
+			}
+			k8sClient := newFakeClient(t, objs...)
+			rotator := New(slog.New(slog.NewTextHandler(os.Stdout, nil)), k8sClient, Options{
+				Clock: clocktesting.NewFakeClock(now),
+			})
+
+			if err := rotator.Rotate(context.Background()); err != nil {
+				t.Fatalf("Rotate() error = %v", err)
+			}
+
+			var secret corev1.Secret
+			if err := k8sClient.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "ticket-keys"}, &secret); err != nil {
+				t.Fatalf("failed to get secret: %v", err)
+			}
+			keys, err := Split(secret.Data[DataKey])
+			if err != nil {
+				t.Fatalf("secret holds invalid keys: %v", err)
+			}
+			if tt.wantNewKey {
+				if got := secret.Annotations[RotatedAtAnnotation]; got != now.Format(time.RFC3339) {
+					t.Errorf("%s = %q, want %q", RotatedAtAnnotation, got, now.Format(time.RFC3339))
+				}
+				keys = keys[1:]
+			}
+			if len(keys) != len(tt.wantKeys) {
+				t.Fatalf("secret holds %d previous keys, want %d", len(keys), len(tt.wantKeys))
+			}
+			for i, want := range tt.wantKeys {
+				if !bytes.Equal(keys[i], want) {
+					t.Errorf("key %d = %x, want %x", i, keys[i], want)
+				}
+			}
+		})
+	}
+}
+
+// selectorClient hides Secrets not matching a label selector from Get, like a
+// Secret cache restricted by cache.secretSelector.
+type selectorClient struct {
+	client.Client
+	selector labels.Selector
+}
+
+func (c selectorClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if err := c.Client.Get(ctx, key, obj, opts...); err != nil {
+		return err
+	}
+	if _, ok := obj.(*corev1.Secret); ok && !c.selector.Matches(labels.Set(obj.GetLabels())) {
+		return apierrors.NewNotFound(corev1.Resource("secrets"), key.Name)
+	}
+	return nil
+}
+
+func TestRotateWithSecretSelector(t *testing.T) {
+	selector := labels.SelectorFromSet(labels.Set{"gateway.giantswarm.io/managed": "true"})
+	longAgo := now.Add(-25 * time.Hour)
+
+	tests := []struct {
+		name   string
+		secret *corev1.Secret
+	}{
+		{name: "created secret is labeled"},
+		{name: "unlabeled secret is labeled and rotated", secret: createTicketKeysSecret("ticket-keys", &longAgo, key(1))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := []client.Object{createPolicy("policy-1", "ticket-keys")}
+			if tt.secret != nil {
+				objs = append(objs, tt.secret)
+			}
+			apiClient := newFakeClient(t, objs...)
+			cachedClient := selectorClient{Client: apiClient, selector: selector}
+			fakeClock := clocktesting.NewFakeClock(now)
+			rotator := New(slog.New(slog.NewTextHandler(os.Stdout, nil)), cachedClient, Options{
+				Clock:  fakeClock,
+				Reader: apiClient,
+				Labels: map[string]string{"gateway.giantswarm.io/managed": "true"},
+			})
+
+			// The second rotation must find the Secret the first one wrote.
+			for range 2 {
+				if err := rotator.Rotate(context.Background()); err != nil {
+					t.Fatalf("Rotate() error = %v", err)
+				}
+				fakeClock.Step(time.Hour)
+			}
+
+			var secret corev1.Secret
+			if err := cachedClient.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "ticket-keys"}, &secret); err != nil {
+				t.Fatalf("secret is not visible through the selector: %v", err)
+			}
+			if got := secret.Annotations[RotatedAtAnnotation]; got != now.Format(time.RFC3339) {
+				t.Errorf("%s = %q, want %q", RotatedAtAnnotation, got, now.Format(time.RFC3339))
+			}
+		})
+	}
+}
+
+func TestRotateLeavesWritesToTheLeader(t *testing.T) {
+	k8sClient := newFakeClient(t, createPolicy("policy-1", "ticket-keys"))
+	rotator := New(slog.New(slog.NewTextHandler(os.Stdout, nil)), k8sClient, Options{
+		Clock:  clocktesting.NewFakeClock(now),
+		Leader: follower{},
+	})
+
+	if err := rotator.Rotate(context.Background()); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	var secrets corev1.SecretList
+	if err := k8sClient.List(context.Background(), &secrets); err != nil {
+		t.Fatalf("failed to list secrets: %v", err)
+	}
+	if len(secrets.Items) != 0 {
+		t.Errorf("a follower created %d secrets", len(secrets.Items))
+	}
+}
@@ -0,0 +1,4 @@
+// Package tracing sets up OpenTelemetry tracing for the extension server. Spans
+// are exported over OTLP, and the gRPC server continues the traces Envoy
+// Gateway propagates with its hook calls.
+package tracing
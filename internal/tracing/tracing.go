@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/stats"
+)
+
+const (
+	// ServiceName is the service name spans are reported under.
+	ServiceName = "envoy-extension-server"
+
+	// DefaultSampleRatio is the default fraction of traces sampled when the
+	// caller did not make a sampling decision.
+	DefaultSampleRatio = 1.0
+)
+
+// Options configures the exporter of a TracerProvider.
+type Options struct {
+	// Endpoint is the host:port of the OTLP gRPC collector.
+	Endpoint string
+
+	// Insecure disables TLS towards the collector.
+	Insecure bool
+
+	// SampleRatio is the fraction of traces sampled when Envoy Gateway did
+	// not propagate a sampling decision.
+	SampleRatio float64
+
+	// Version is reported as the service version.
+	Version string
+}
+
+// NewTracerProvider returns a TracerProvider exporting spans in batches to
+// the OTLP collector at opts.Endpoint. The caller must shut it down to flush
+// the remaining spans.
+func NewTracerProvider(ctx context.Context, opts Options) (*sdktrace.TracerProvider, error) {
+	if opts.Endpoint == "" {
+		return nil, fmt.Errorf("no OTLP endpoint configured")
+	}
+	exporterOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(opts.Endpoint)}
+	if opts.Insecure {
+		exporterOpts = append(exporterOpts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, exporterOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceName(ServiceName),
+			semconv.ServiceVersion(opts.Version),
+		)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	), nil
+}
+
+// Propagator returns the propagator of the W3C trace context and baggage
+// metadata Envoy Gateway sends.
+func Propagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+}
+
+// ServerHandler returns a gRPC stats handler that starts a span for every
+// hook call, continuing the trace propagated in the call's metadata.
+func ServerHandler(provider trace.TracerProvider) stats.Handler {
+	return otelgrpc.NewServerHandler(
+		otelgrpc.WithTracerProvider(provider),
+		otelgrpc.WithPropagators(Propagator()),
+	)
+}
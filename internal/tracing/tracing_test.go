@@ -0,0 +1,36 @@
+package tracing
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestNewTracerProvider(t *testing.T) {
+	if _, err := NewTracerProvider(context.Background(), Options{}); err == nil {
+		t.Error("NewTracerProvider() expected an error without endpoint")
+	}
+
+	// The exporter connects lazily, no collector is needed.
+	provider, err := NewTracerProvider(context.Background(), Options{
+		Endpoint:    "localhost:4317",
+		Insecure:    true,
+		SampleRatio: DefaultSampleRatio,
+		Version:     "test",
+	})
+	if err != nil {
+		t.Fatalf("NewTracerProvider() error = %v", err)
+	}
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+}
+
+func TestPropagator(t *testing.T) {
+	fields := Propagator().Fields()
+	for _, want := range []string{"traceparent", "baggage"} {
+		if !slices.Contains(fields, want) {
+			t.Errorf("Propagator() fields = %v, want %q", fields, want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ConvertPath is the path the CertificatePolicy conversion webhook is
+	// served on.
+	ConvertPath = "/convert"
+
+	// CertificatePolicyCRDName is the name of the CertificatePolicy CRD.
+	CertificatePolicyCRDName = "certificatepolicies.gateway.giantswarm.io"
+)
+
+// ConfigureConversion points the conversion of the CertificatePolicy CRD at
+// the webhook server behind the given Service. The CRD is shipped without a
+// conversion webhook because its service reference depends on the release,
+// so the webhook server registers itself on startup.
+func ConfigureConversion(ctx context.Context, c client.Client, service types.NamespacedName, port int32, caBundle []byte) error {
+	var crd apiextensionsv1.CustomResourceDefinition
+	if err := c.Get(ctx, types.NamespacedName{Name: CertificatePolicyCRDName}, &crd); err != nil {
+		return fmt.Errorf("failed to get CRD %s: %w", CertificatePolicyCRDName, err)
+	}
+
+	patch := client.MergeFrom(crd.DeepCopy())
+	crd.Spec.Conversion = &apiextensionsv1.CustomResourceConversion{
+		Strategy: apiextensionsv1.WebhookConverter,
+		Webhook: &apiextensionsv1.WebhookConversion{
+			ClientConfig: &apiextensionsv1.WebhookClientConfig{
+				Service: &apiextensionsv1.ServiceReference{
+					Namespace: service.Namespace,
+					Name:      service.Name,
+					Path:      ptr.To(ConvertPath),
+					Port:      ptr.To(port),
+				},
+				CABundle: caBundle,
+			},
+			ConversionReviewVersions: []string{"v1"},
+		},
+	}
+	if err := c.Patch(ctx, &crd, patch); err != nil {
+		return fmt.Errorf("failed to configure conversion of CRD %s: %w", CertificatePolicyCRDName, err)
+	}
+	return nil
+}
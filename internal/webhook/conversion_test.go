@@ -0,0 +1,88 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+func TestNewServerConvertsPolicies(t *testing.T) {
+	server := NewServer(Options{Port: 9443}, newScheme(t), newFakeClient(t))
+
+	body := `{"apiVersion":"apiextensions.k8s.io/v1","kind":"ConversionReview","request":{"uid":"1",` +
+		`"desiredAPIVersion":"gateway.giantswarm.io/v1beta1","objects":[{"apiVersion":"gateway.giantswarm.io/v1alpha1",` +
+		`"kind":"CertificatePolicy","metadata":{"name":"p","namespace":"default"},"spec":{"secretName":"secret-1",` +
+		`"targetRefs":[{"group":"gateway.networking.k8s.io","kind":"Gateway","name":"gateway-1"}]}}]}}`
+	req := httptest.NewRequest("POST", ConvertPath, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	server.WebhookMux().ServeHTTP(rec, req)
+
+	var review apiextensionsv1.ConversionReview
+	if err := json.Unmarshal(rec.Body.Bytes(), &review); err != nil {
+		t.Fatalf("failed to decode response %s: %v", rec.Body.String(), err)
+	}
+	if review.Response == nil || review.Response.Result.Status != metav1.StatusSuccess {
+		t.Fatalf("conversion failed: %s", rec.Body.String())
+	}
+	if len(review.Response.ConvertedObjects) != 1 {
+		t.Fatalf("got %d converted objects, want 1", len(review.Response.ConvertedObjects))
+	}
+
+	var policy v1beta1.CertificatePolicy
+	if err := json.Unmarshal(review.Response.ConvertedObjects[0].Raw, &policy); err != nil {
+		t.Fatalf("failed to decode converted object: %v", err)
+	}
+	if policy.APIVersion != v1beta1.GroupVersion.String() {
+		t.Errorf("apiVersion = %q, want %q", policy.APIVersion, v1beta1.GroupVersion.String())
+	}
+	if policy.Spec.SecretRef.Name != "secret-1" {
+		t.Errorf("secretRef.name = %q, want %q", policy.Spec.SecretRef.Name, "secret-1")
+	}
+}
+
+func TestConfigureConversion(t *testing.T) {
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: CertificatePolicyCRDName},
+	}
+	c := newFakeClient(t, crd)
+	service := types.NamespacedName{Namespace: "envoy-gateway-system", Name: "extension-server"}
+
+	if err := ConfigureConversion(context.Background(), c, service, 443, []byte("ca")); err != nil {
+		t.Fatalf("ConfigureConversion() error = %v", err)
+	}
+
+	var got apiextensionsv1.CustomResourceDefinition
+	if err := c.Get(context.Background(), types.NamespacedName{Name: CertificatePolicyCRDName}, &got); err != nil {
+		t.Fatalf("failed to get CRD: %v", err)
+	}
+	conversion := got.Spec.Conversion
+	if conversion == nil || conversion.Strategy != apiextensionsv1.WebhookConverter {
+		t.Fatalf("conversion = %+v, want the webhook strategy", conversion)
+	}
+	clientConfig := conversion.Webhook.ClientConfig
+	if clientConfig.Service.Name != service.Name || clientConfig.Service.Namespace != service.Namespace {
+		t.Errorf("service = %s/%s, want %s", clientConfig.Service.Namespace, clientConfig.Service.Name, service)
+	}
+	if *clientConfig.Service.Path != ConvertPath || *clientConfig.Service.Port != 443 {
+		t.Errorf("service path and port = %s:%d, want %s:443", *clientConfig.Service.Path, *clientConfig.Service.Port, ConvertPath)
+	}
+	if string(clientConfig.CABundle) != "ca" {
+		t.Errorf("caBundle = %q, want %q", clientConfig.CABundle, "ca")
+	}
+}
+
+func TestConfigureConversionWithoutCRD(t *testing.T) {
+	err := ConfigureConversion(context.Background(), newFakeClient(t), types.NamespacedName{Name: "svc"}, 443, nil)
+	if err == nil {
+		t.Error("ConfigureConversion() expected an error for a missing CRD")
+	}
+}
@@ -0,0 +1,3 @@
+// Package webhook implements the admission webhooks for CertificatePolicies
+// served by the extension server binary.
+package webhook
@@ -0,0 +1,48 @@
+package webhook
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+// ValidateCertificatePolicyPath is the path the CertificatePolicy validating
+// webhook is served on.
+const ValidateCertificatePolicyPath = "/validate-gateway-giantswarm-io-v1beta1-certificatepolicy"
+
+// Options configures the webhook server.
+type Options struct {
+	// Port is the port the webhook server listens on.
+	Port int
+
+	// CertDir is the directory containing the serving certificate and key.
+	CertDir string
+
+	// CertName is the file name of the serving certificate in CertDir.
+	CertName string
+
+	// KeyName is the file name of the serving key in CertDir.
+	KeyName string
+}
+
+// NewServer creates a webhook server serving the CertificatePolicy admission
+// and conversion webhooks. Referenced Secrets are looked up with reader. The
+// server is started with its Start method.
+func NewServer(opts Options, scheme *runtime.Scheme, reader client.Reader) ctrlwebhook.Server {
+	server := ctrlwebhook.NewServer(ctrlwebhook.Options{
+		Port:     opts.Port,
+		CertDir:  opts.CertDir,
+		CertName: opts.CertName,
+		KeyName:  opts.KeyName,
+	})
+
+	validator := NewCertificatePolicyValidator(reader)
+	server.Register(ValidateCertificatePolicyPath, admission.WithCustomValidator(scheme, &v1beta1.CertificatePolicy{}, validator))
+	server.Register(ConvertPath, conversion.NewWebhookHandler(scheme))
+
+	return server
+}
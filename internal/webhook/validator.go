@@ -0,0 +1,284 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+	"github.com/giantswarm/envoy-extension-server-app/internal/extensionserver"
+)
+
+// gatewayKind is the only kind CertificatePolicies can target.
+const gatewayKind = "Gateway"
+
+// CertificatePolicyValidator rejects malformed CertificatePolicies and warns
+// about references to Secrets that do not exist yet.
+type CertificatePolicyValidator struct {
+	reader client.Reader
+}
+
+var _ admission.CustomValidator = &CertificatePolicyValidator{}
+
+// NewCertificatePolicyValidator creates a validator that looks up referenced
+// Secrets with the given reader. The reader must not be scoped by the cache,
+// as Secrets outside the scope would be reported as missing.
+func NewCertificatePolicyValidator(reader client.Reader) *CertificatePolicyValidator {
+	return &CertificatePolicyValidator{reader: reader}
+}
+
+// ValidateCreate validates a new CertificatePolicy.
+func (v *CertificatePolicyValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return v.validate(ctx, obj)
+}
+
+// ValidateUpdate validates an updated CertificatePolicy.
+func (v *CertificatePolicyValidator) ValidateUpdate(ctx context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	return v.validate(ctx, newObj)
+}
+
+// ValidateDelete allows every deletion.
+func (v *CertificatePolicyValidator) ValidateDelete(context.Context, runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *CertificatePolicyValidator) validate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	policy, ok := obj.(*v1beta1.CertificatePolicy)
+	if !ok {
+		return nil, fmt.Errorf("expected a CertificatePolicy but got %T", obj)
+	}
+
+	if errs := ValidateCertificatePolicySpec(&policy.Spec, field.NewPath("spec")); len(errs) > 0 {
+		return nil, apierrors.NewInvalid(v1beta1.GroupVersion.WithKind("CertificatePolicy").GroupKind(), policy.Name, errs)
+	}
+
+	return v.secretWarnings(ctx, policy), nil
+}
+
+// secretWarnings warns about referenced Secrets that do not exist yet. They
+// may legitimately be created later, e.g. by cert-manager.
+func (v *CertificatePolicyValidator) secretWarnings(ctx context.Context, policy *v1beta1.CertificatePolicy) admission.Warnings {
+	var warnings admission.Warnings
+	names := []string{policy.Spec.SecretRef.Name}
+	if policy.Spec.FallbackSecretRef != nil {
+		names = append(names, policy.Spec.FallbackSecretRef.Name)
+	}
+	for _, ref := range policy.Spec.AdditionalSecretRefs {
+		names = append(names, ref.Name)
+	}
+	if policy.Spec.SessionTicketKeysSecretRef != nil {
+		names = append(names, policy.Spec.SessionTicketKeysSecretRef.Name)
+	}
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		var secret corev1.Secret
+		err := v.reader.Get(ctx, types.NamespacedName{Namespace: policy.Namespace, Name: name}, &secret)
+		if apierrors.IsNotFound(err) {
+			warnings = append(warnings, fmt.Sprintf("Secret %s/%s does not exist yet", policy.Namespace, name))
+		}
+	}
+	return warnings
+}
+
+// ValidateCertificatePolicySpec returns the validation errors of a
+// CertificatePolicy spec.
+func ValidateCertificatePolicySpec(spec *v1beta1.CertificatePolicySpec, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	errs = append(errs, validateSecretName(spec.SecretRef.Name, path.Child("secretRef", "name"), true)...)
+	if spec.FallbackSecretRef != nil {
+		errs = append(errs, validateSecretName(spec.FallbackSecretRef.Name, path.Child("fallbackSecretRef", "name"), true)...)
+	}
+	errs = append(errs, validateAdditionalSecretRefs(spec, path.Child("additionalSecretRefs"))...)
+	if spec.SessionTicketKeysSecretRef != nil {
+		errs = append(errs, validateSecretName(spec.SessionTicketKeysSecretRef.Name, path.Child("sessionTicketKeysSecretRef", "name"), true)...)
+	}
+	errs = append(errs, validateTargetRefs(spec.TargetRefs, path.Child("targetRefs"))...)
+	errs = append(errs, validateHostnames(spec.Hostnames, path.Child("hostnames"))...)
+	if spec.TLSParams != nil {
+		errs = append(errs, validateTLSParams(spec.TLSParams, path.Child("tlsParams"))...)
+	}
+	errs = append(errs, validateALPNProtocols(spec.ALPNProtocols, path.Child("alpnProtocols"))...)
+
+	if spec.PrivateKeyProvider != nil {
+		if err := extensionserver.ValidatePrivateKeyProvider(spec.PrivateKeyProvider); err != nil {
+			errs = append(errs, field.Invalid(path.Child("privateKeyProvider"), spec.PrivateKeyProvider.ProviderName, err.Error()))
+		}
+	}
+
+	if spec.Delivery != nil {
+		errs = append(errs, validateDelivery(spec.Delivery, spec.PrivateKeyProvider != nil, path.Child("delivery"))...)
+	}
+
+	return errs
+}
+
+// validateDelivery checks that a mount path is set where the Secret is read
+// from files and that private key providers, which need the private key, are
+// only combined with the Inline mode.
+func validateDelivery(delivery *v1beta1.CertificateDelivery, hasPrivateKeyProvider bool, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	switch delivery.Mode {
+	case "", v1beta1.DeliveryInline, v1beta1.DeliveryReference:
+	case v1beta1.DeliveryFilename:
+		if delivery.MountPath == "" {
+			errs = append(errs, field.Required(path.Child("mountPath"), "required in Filename mode"))
+		}
+	default:
+		errs = append(errs, field.NotSupported(path.Child("mode"), delivery.Mode,
+			[]v1beta1.CertificateDeliveryMode{v1beta1.DeliveryInline, v1beta1.DeliveryReference, v1beta1.DeliveryFilename}))
+	}
+	if delivery.MountPath != "" && !strings.HasPrefix(delivery.MountPath, "/") {
+		errs = append(errs, field.Invalid(path.Child("mountPath"), delivery.MountPath, "must be an absolute path"))
+	}
+	if hasPrivateKeyProvider && delivery.Mode != "" && delivery.Mode != v1beta1.DeliveryInline {
+		errs = append(errs, field.Forbidden(path.Child("mode"), "privateKeyProvider requires the Inline delivery mode"))
+	}
+	return errs
+}
+
+// validateAdditionalSecretRefs checks that the additional Secrets of a
+// policy are distinct from each other and from its Secret, and that they are
+// only combined with the Inline mode, the only one that reads them.
+func validateAdditionalSecretRefs(spec *v1beta1.CertificatePolicySpec, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	seen := map[string]struct{}{spec.SecretRef.Name: {}}
+	for i, ref := range spec.AdditionalSecretRefs {
+		namePath := path.Index(i).Child("name")
+		errs = append(errs, validateSecretName(ref.Name, namePath, true)...)
+		if _, ok := seen[ref.Name]; ok && ref.Name != "" {
+			errs = append(errs, field.Duplicate(namePath, ref.Name))
+		}
+		seen[ref.Name] = struct{}{}
+	}
+	if len(spec.AdditionalSecretRefs) > 0 && spec.Delivery != nil && spec.Delivery.Mode != "" && spec.Delivery.Mode != v1beta1.DeliveryInline {
+		errs = append(errs, field.Forbidden(path, "additionalSecretRefs require the Inline delivery mode"))
+	}
+	return errs
+}
+
+// validateSecretName checks that name is a valid Secret name.
+func validateSecretName(name string, path *field.Path, required bool) field.ErrorList {
+	if name == "" {
+		if required {
+			return field.ErrorList{field.Required(path, "")}
+		}
+		return nil
+	}
+
+	var errs field.ErrorList
+	for _, msg := range validation.IsDNS1123Subdomain(name) {
+		errs = append(errs, field.Invalid(path, name, msg))
+	}
+	return errs
+}
+
+// validateHostnames checks that every hostname is a DNS name, optionally
+// prefixed with a "*." wildcard label.
+func validateHostnames(hostnames []gwapiv1.Hostname, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i, hostname := range hostnames {
+		name := strings.TrimPrefix(string(hostname), "*.")
+		for _, msg := range validation.IsDNS1123Subdomain(name) {
+			errs = append(errs, field.Invalid(path.Index(i), hostname, msg))
+		}
+	}
+	return errs
+}
+
+// validateTLSParams checks that the TLS version range is not empty.
+func validateTLSParams(params *v1beta1.TLSParameters, path *field.Path) field.ErrorList {
+	if params.MinVersion == nil || params.MaxVersion == nil ||
+		*params.MinVersion == v1beta1.TLSAuto || *params.MaxVersion == v1beta1.TLSAuto {
+		return nil
+	}
+	if *params.MinVersion > *params.MaxVersion {
+		return field.ErrorList{field.Invalid(path.Child("minVersion"), *params.MinVersion, "must not be greater than maxVersion")}
+	}
+	return nil
+}
+
+// validateALPNProtocols checks that only HTTP/2 and HTTP/1.1 are offered,
+// each once. HTTP/3 is negotiated on QUIC listeners only.
+func validateALPNProtocols(protocols []v1beta1.ALPNProtocol, path *field.Path) field.ErrorList {
+	supported := []v1beta1.ALPNProtocol{v1beta1.ALPNHTTP2, v1beta1.ALPNHTTP11}
+
+	var errs field.ErrorList
+	seen := map[v1beta1.ALPNProtocol]struct{}{}
+	for i, protocol := range protocols {
+		if !slices.Contains(supported, protocol) {
+			errs = append(errs, field.NotSupported(path.Index(i), protocol, supported))
+			continue
+		}
+		if _, ok := seen[protocol]; ok {
+			errs = append(errs, field.Duplicate(path.Index(i), protocol))
+		}
+		seen[protocol] = struct{}{}
+	}
+	return errs
+}
+
+// validateTargetRefs checks that the policy targets at least one Gateway, only
+// Gateways, and every target once.
+func validateTargetRefs(refs []gwapiv1.LocalPolicyTargetReferenceWithSectionName, path *field.Path) field.ErrorList {
+	if len(refs) == 0 {
+		return field.ErrorList{field.Required(path, "at least one Gateway must be targeted")}
+	}
+
+	var errs field.ErrorList
+	seen := map[targetKey]struct{}{}
+	for i, ref := range refs {
+		refPath := path.Index(i)
+
+		if ref.Group != gwapiv1.GroupName {
+			errs = append(errs, field.NotSupported(refPath.Child("group"), ref.Group, []string{gwapiv1.GroupName}))
+		}
+		if ref.Kind != gatewayKind {
+			errs = append(errs, field.NotSupported(refPath.Child("kind"), ref.Kind, []string{gatewayKind}))
+		}
+		if ref.Name == "" {
+			errs = append(errs, field.Required(refPath.Child("name"), ""))
+		}
+
+		key := newTargetKey(ref)
+		if _, ok := seen[key]; ok {
+			errs = append(errs, field.Duplicate(refPath, ref))
+			continue
+		}
+		seen[key] = struct{}{}
+	}
+	return errs
+}
+
+// targetKey identifies a targetRef, comparing the section name by value.
+type targetKey struct {
+	group       gwapiv1.Group
+	kind        gwapiv1.Kind
+	name        gwapiv1.ObjectName
+	sectionName gwapiv1.SectionName
+}
+
+func newTargetKey(ref gwapiv1.LocalPolicyTargetReferenceWithSectionName) targetKey {
+	key := targetKey{
+		group: ref.Group,
+		kind:  ref.Kind,
+		name:  ref.Name,
+	}
+	if ref.SectionName != nil {
+		key.sectionName = *ref.SectionName
+	}
+	return key
+}
@@ -0,0 +1,339 @@
+package webhook
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"github.com/giantswarm/envoy-extension-server-app/api/v1alpha1"
+	"github.com/giantswarm/envoy-extension-server-app/api/v1beta1"
+)
+
+func TestValidateCertificatePolicySpec(t *testing.T) {
+	tests := []struct {
+		name       string
+		mutate     func(spec *v1beta1.CertificatePolicySpec)
+		wantFields []string
+	}{
+		{
+			name:   "valid policy",
+			mutate: func(*v1beta1.CertificatePolicySpec) {},
+		},
+		{
+			name:       "empty secretRef name",
+			mutate:     func(spec *v1beta1.CertificatePolicySpec) { spec.SecretRef.Name = "" },
+			wantFields: []string{"spec.secretRef.name"},
+		},
+		{
+			name:       "invalid secretRef name",
+			mutate:     func(spec *v1beta1.CertificatePolicySpec) { spec.SecretRef.Name = "Not_A_Name" },
+			wantFields: []string{"spec.secretRef.name"},
+		},
+		{
+			name: "invalid fallbackSecretRef name",
+			mutate: func(spec *v1beta1.CertificatePolicySpec) {
+				spec.FallbackSecretRef = &v1beta1.SecretReference{Name: "Not_A_Name"}
+			},
+			wantFields: []string{"spec.fallbackSecretRef.name"},
+		},
+		{
+			name: "wildcard and exact hostnames",
+			mutate: func(spec *v1beta1.CertificatePolicySpec) {
+				spec.Hostnames = []gwapiv1.Hostname{"*.example.com", "www.example.org"}
+			},
+		},
+		{
+			name: "invalid hostname",
+			mutate: func(spec *v1beta1.CertificatePolicySpec) {
+				spec.Hostnames = []gwapiv1.Hostname{"example.com", "Not_A_Host"}
+			},
+			wantFields: []string{"spec.hostnames[1]"},
+		},
+		{
+			name: "TLS version range",
+			mutate: func(spec *v1beta1.CertificatePolicySpec) {
+				spec.TLSParams = &v1beta1.TLSParameters{MinVersion: ptr.To(v1beta1.TLSv12), MaxVersion: ptr.To(v1beta1.TLSv13)}
+			},
+		},
+		{
+			name: "inverted TLS version range",
+			mutate: func(spec *v1beta1.CertificatePolicySpec) {
+				spec.TLSParams = &v1beta1.TLSParameters{MinVersion: ptr.To(v1beta1.TLSv13), MaxVersion: ptr.To(v1beta1.TLSv12)}
+			},
+			wantFields: []string{"spec.tlsParams.minVersion"},
+		},
+		{
+			name:       "no targetRefs",
+			mutate:     func(spec *v1beta1.CertificatePolicySpec) { spec.TargetRefs = nil },
+			wantFields: []string{"spec.targetRefs"},
+		},
+		{
+			name: "targetRef to a non-Gateway kind",
+			mutate: func(spec *v1beta1.CertificatePolicySpec) {
+				spec.TargetRefs[0].Kind = "HTTPRoute"
+			},
+			wantFields: []string{"spec.targetRefs[0].kind"},
+		},
+		{
+			name: "targetRef to another group",
+			mutate: func(spec *v1beta1.CertificatePolicySpec) {
+				spec.TargetRefs[0].Group = "example.com"
+			},
+			wantFields: []string{"spec.targetRefs[0].group"},
+		},
+		{
+			name: "targetRef without name",
+			mutate: func(spec *v1beta1.CertificatePolicySpec) {
+				spec.TargetRefs[0].Name = ""
+			},
+			wantFields: []string{"spec.targetRefs[0].name"},
+		},
+		{
+			name: "duplicate targets",
+			mutate: func(spec *v1beta1.CertificatePolicySpec) {
+				spec.TargetRefs = append(spec.TargetRefs, targetRef("gateway-1", "https"))
+			},
+			wantFields: []string{"spec.targetRefs[2]"},
+		},
+		{
+			name: "same gateway with different sections",
+			mutate: func(spec *v1beta1.CertificatePolicySpec) {
+				spec.TargetRefs = append(spec.TargetRefs, targetRef("gateway-1", "http"))
+			},
+		},
+		{
+			name: "invalid private key provider",
+			mutate: func(spec *v1beta1.CertificatePolicySpec) {
+				spec.PrivateKeyProvider = &v1beta1.PrivateKeyProvider{ProviderName: "pkcs11"}
+			},
+			wantFields: []string{"spec.privateKeyProvider"},
+		},
+		{
+			name: "filename delivery",
+			mutate: func(spec *v1beta1.CertificatePolicySpec) {
+				spec.Delivery = &v1beta1.CertificateDelivery{Mode: v1beta1.DeliveryFilename, MountPath: "/etc/envoy/certs"}
+			},
+		},
+		{
+			name: "filename delivery without mount path",
+			mutate: func(spec *v1beta1.CertificatePolicySpec) {
+				spec.Delivery = &v1beta1.CertificateDelivery{Mode: v1beta1.DeliveryFilename}
+			},
+			wantFields: []string{"spec.delivery.mountPath"},
+		},
+		{
+			name: "relative mount path",
+			mutate: func(spec *v1beta1.CertificatePolicySpec) {
+				spec.Delivery = &v1beta1.CertificateDelivery{Mode: v1beta1.DeliveryReference, MountPath: "certs"}
+			},
+			wantFields: []string{"spec.delivery.mountPath"},
+		},
+		{
+			name: "reference delivery with a private key provider",
+			mutate: func(spec *v1beta1.CertificatePolicySpec) {
+				spec.PrivateKeyProvider = &v1beta1.PrivateKeyProvider{ProviderName: "cryptomb"}
+				spec.Delivery = &v1beta1.CertificateDelivery{Mode: v1beta1.DeliveryReference}
+			},
+			wantFields: []string{"spec.delivery.mode"},
+		},
+		{
+			name: "additional secrets",
+			mutate: func(spec *v1beta1.CertificatePolicySpec) {
+				spec.AdditionalSecretRefs = []v1beta1.SecretReference{{Name: "secret-ecdsa"}}
+			},
+		},
+		{
+			name: "additional secrets repeating a secret",
+			mutate: func(spec *v1beta1.CertificatePolicySpec) {
+				spec.AdditionalSecretRefs = []v1beta1.SecretReference{{Name: "secret-ecdsa"}, {Name: "secret-ecdsa"}, {Name: spec.SecretRef.Name}}
+			},
+			wantFields: []string{"spec.additionalSecretRefs[1].name", "spec.additionalSecretRefs[2].name"},
+		},
+		{
+			name: "additional secrets with filename delivery",
+			mutate: func(spec *v1beta1.CertificatePolicySpec) {
+				spec.AdditionalSecretRefs = []v1beta1.SecretReference{{Name: "secret-ecdsa"}}
+				spec.Delivery = &v1beta1.CertificateDelivery{Mode: v1beta1.DeliveryFilename, MountPath: "/etc/envoy/certs"}
+			},
+			wantFields: []string{"spec.additionalSecretRefs"},
+		},
+		{
+			name: "HTTP/1.1 only",
+			mutate: func(spec *v1beta1.CertificatePolicySpec) {
+				spec.ALPNProtocols = []v1beta1.ALPNProtocol{v1beta1.ALPNHTTP11}
+			},
+		},
+		{
+			name: "unsupported and repeated ALPN protocols",
+			mutate: func(spec *v1beta1.CertificatePolicySpec) {
+				spec.ALPNProtocols = []v1beta1.ALPNProtocol{v1beta1.ALPNHTTP2, "h3", v1beta1.ALPNHTTP2}
+			},
+			wantFields: []string{"spec.alpnProtocols[1]", "spec.alpnProtocols[2]"},
+		},
+		{
+			name: "invalid session ticket keys secret name",
+			mutate: func(spec *v1beta1.CertificatePolicySpec) {
+				spec.SessionTicketKeysSecretRef = &v1beta1.SecretReference{Name: "Ticket_Keys"}
+			},
+			wantFields: []string{"spec.sessionTicketKeysSecretRef.name"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := createPolicy()
+			tt.mutate(&policy.Spec)
+
+			errs := ValidateCertificatePolicySpec(&policy.Spec, field.NewPath("spec"))
+			if len(errs) != len(tt.wantFields) {
+				t.Fatalf("got errors %v, want errors for %v", errs, tt.wantFields)
+			}
+			for i, wantField := range tt.wantFields {
+				if errs[i].Field != wantField {
+					t.Errorf("error[%d].Field = %q, want %q", i, errs[i].Field, wantField)
+				}
+			}
+		})
+	}
+}
+
+func TestValidatorRejectsInvalidPolicies(t *testing.T) {
+	validator := NewCertificatePolicyValidator(newFakeClient(t))
+	policy := createPolicy()
+	policy.Spec.SecretRef.Name = ""
+
+	if _, err := validator.ValidateCreate(context.Background(), policy); err == nil {
+		t.Error("ValidateCreate() expected an error")
+	}
+	if _, err := validator.ValidateUpdate(context.Background(), createPolicy(), policy); err == nil {
+		t.Error("ValidateUpdate() expected an error")
+	}
+	if _, err := validator.ValidateDelete(context.Background(), policy); err != nil {
+		t.Errorf("ValidateDelete() error = %v", err)
+	}
+	if _, err := validator.ValidateCreate(context.Background(), &corev1.Secret{}); err == nil {
+		t.Error("ValidateCreate() expected an error for another kind")
+	}
+}
+
+func TestValidatorWarnsAboutMissingSecrets(t *testing.T) {
+	tests := []struct {
+		name         string
+		objs         []client.Object
+		fallback     string
+		wantWarnings []string
+	}{
+		{
+			name: "secret exists",
+			objs: []client.Object{createSecret("secret-1")},
+		},
+		{
+			name:         "secret missing",
+			wantWarnings: []string{"Secret default/secret-1 does not exist yet"},
+		},
+		{
+			name:         "fallback secret missing",
+			objs:         []client.Object{createSecret("secret-1")},
+			fallback:     "fallback",
+			wantWarnings: []string{"Secret default/fallback does not exist yet"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := NewCertificatePolicyValidator(newFakeClient(t, tt.objs...))
+			policy := createPolicy()
+			if tt.fallback != "" {
+				policy.Spec.FallbackSecretRef = &v1beta1.SecretReference{Name: tt.fallback}
+			}
+
+			warnings, err := validator.ValidateCreate(context.Background(), policy)
+			if err != nil {
+				t.Fatalf("ValidateCreate() error = %v", err)
+			}
+			if len(warnings) != len(tt.wantWarnings) {
+				t.Fatalf("got warnings %v, want %v", warnings, tt.wantWarnings)
+			}
+			for i, want := range tt.wantWarnings {
+				if warnings[i] != want {
+					t.Errorf("warning[%d] = %q, want %q", i, warnings[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewServerRegistersValidator(t *testing.T) {
+	server := NewServer(Options{Port: 9443}, newScheme(t), newFakeClient(t))
+
+	body := `{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","request":{"uid":"1","operation":"CREATE",` +
+		`"object":{"apiVersion":"gateway.giantswarm.io/v1beta1","kind":"CertificatePolicy","metadata":{"name":"p","namespace":"default"},"spec":{"secretRef":{"name":""},"targetRefs":[]}}}}`
+	req := httptest.NewRequest("POST", ValidateCertificatePolicyPath, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	server.WebhookMux().ServeHTTP(rec, req)
+
+	if !strings.Contains(rec.Body.String(), `"allowed":false`) {
+		t.Errorf("webhook response = %s, want the policy to be rejected", rec.Body.String())
+	}
+}
+
+func newScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
+	utilruntime.Must(v1alpha1.AddToScheme(scheme))
+	utilruntime.Must(v1beta1.AddToScheme(scheme))
+	return scheme
+}
+
+func newFakeClient(t *testing.T, objs ...client.Object) client.Client {
+	t.Helper()
+	return fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(objs...).Build()
+}
+
+func createPolicy() *v1beta1.CertificatePolicy {
+	return &v1beta1.CertificatePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy-1", Namespace: "default"},
+		Spec: v1beta1.CertificatePolicySpec{
+			SecretRef: v1beta1.SecretReference{Name: "secret-1"},
+			TargetRefs: []gwapiv1.LocalPolicyTargetReferenceWithSectionName{
+				targetRef("gateway-1", "https"),
+				targetRef("gateway-2", ""),
+			},
+		},
+	}
+}
+
+func createSecret(name string) *corev1.Secret {
+	return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+}
+
+func targetRef(name, sectionName string) gwapiv1.LocalPolicyTargetReferenceWithSectionName {
+	ref := gwapiv1.LocalPolicyTargetReferenceWithSectionName{
+		LocalPolicyTargetReference: gwapiv1.LocalPolicyTargetReference{
+			Group: gwapiv1.GroupName,
+			Kind:  "Gateway",
+			Name:  gwapiv1.ObjectName(name),
+		},
+	}
+	if sectionName != "" {
+		section := gwapiv1.SectionName(sectionName)
+		ref.SectionName = &section
+	}
+	return ref
+}